@@ -0,0 +1,111 @@
+package irtrx
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Alarm is a one-shot hardware timer with microsecond resolution. TxDevice
+// can use one in place of time.Sleep to time marks and spaces, so that
+// transmission doesn't depend on the Go scheduler and the CPU can sit in WFI
+// between edges.
+type Alarm interface {
+	// Now returns the current value of the alarm's free-running microsecond
+	// counter.
+	Now() uint32
+	// Set arranges for fn to be called, from interrupt context, when the
+	// counter reaches at. If at has already passed, fn is called right away.
+	Set(at uint32, fn func())
+}
+
+type alarmState struct {
+	alarm    Alarm
+	edge     func()
+	pairs    []TimePair
+	idx      int
+	inMark   bool
+	deadline uint32
+	done     func()
+	busy     atomic.Bool
+}
+
+// SetAlarm makes the TxDevice time transmissions with alarm rather than
+// sleeping. Pass nil to go back to sleeping. On the RP2040, NewRP2040Alarm
+// provides a suitable Alarm.
+func (tx *TxDevice) SetAlarm(alarm Alarm) {
+	tx.alarm.alarm = alarm
+	tx.alarm.edge = tx.alarmEdge
+}
+
+// StartPairs begins transmitting pairs in the background and returns
+// immediately. SetAlarm must have been called first. When the last pair has
+// been sent, done (which may be nil) is called from interrupt context. pairs
+// must not be modified until then.
+func (tx *TxDevice) StartPairs(pairs []TimePair, done func()) {
+	if tx.closed || len(pairs) == 0 {
+		if done != nil {
+			done()
+		}
+		return
+	}
+	a := &tx.alarm
+	a.pairs = pairs
+	a.idx = 0
+	a.inMark = false
+	a.done = done
+	a.busy.Store(true)
+	a.deadline = a.alarm.Now()
+	tx.alarmEdge()
+}
+
+// Busy reports whether a transmission started with StartPairs is still in
+// progress.
+func (tx *TxDevice) Busy() bool {
+	return tx.alarm.busy.Load()
+}
+
+// alarmEdge is called from the alarm interrupt at every mark/space boundary.
+// Edges whose deadline has already passed, because the interrupt ran late,
+// are taken in a loop rather than by recursing through Alarm.Set.
+func (tx *TxDevice) alarmEdge() {
+	a := &tx.alarm
+	for {
+		if a.inMark {
+			tx.carrier(false)
+			a.deadline += uint32(a.pairs[a.idx][1] / time.Microsecond)
+			a.idx++
+			a.inMark = false
+		} else {
+			if a.idx == len(a.pairs) {
+				a.pairs = nil
+				a.busy.Store(false)
+				tx.frameDone()
+				if a.done != nil {
+					a.done()
+				}
+				return
+			}
+			tx.carrier(true)
+			a.deadline += uint32(a.pairs[a.idx][0] / time.Microsecond)
+			a.inMark = true
+		}
+		if int32(a.deadline-a.alarm.Now()) > 0 {
+			break
+		}
+	}
+	a.alarm.Set(a.deadline, a.edge)
+}
+
+// sendPairsAlarm is SendPairs for alarm driven transmission. It blocks until
+// the pairs are sent, sleeping for most of that time.
+func (tx *TxDevice) sendPairsAlarm(pairs []TimePair) {
+	var total time.Duration
+	for _, p := range pairs {
+		total += p[0] + p[1]
+	}
+	tx.StartPairs(pairs, nil)
+	time.Sleep(total)
+	for tx.Busy() {
+		time.Sleep(100 * time.Microsecond)
+	}
+}
@@ -0,0 +1,68 @@
+//go:build rp2040
+
+package irtrx
+
+import (
+	"device/rp"
+	"runtime/interrupt"
+)
+
+// The TinyGo runtime uses alarm 0 of the RP2040 timer for sleeping; we use
+// alarm 3.
+const rp2040AlarmNum = 3
+
+type rp2040Alarm struct {
+	fn func()
+}
+
+var theRP2040Alarm *rp2040Alarm
+
+// NewRP2040Alarm returns an Alarm backed by alarm 3 of the RP2040's
+// microsecond timer. There is only one such alarm, so repeated calls return
+// the same Alarm.
+func NewRP2040Alarm() Alarm {
+	if theRP2040Alarm != nil {
+		return theRP2040Alarm
+	}
+	theRP2040Alarm = &rp2040Alarm{}
+	intr := interrupt.New(rp.IRQ_TIMER_IRQ_3, rp2040AlarmHandler)
+	rp.TIMER.INTE.SetBits(1 << rp2040AlarmNum)
+	intr.Enable()
+	return theRP2040Alarm
+}
+
+func rp2040AlarmHandler(interrupt.Interrupt) {
+	rp.TIMER.INTR.Set(1 << rp2040AlarmNum)
+	fn := theRP2040Alarm.fn
+	theRP2040Alarm.fn = nil
+	if fn != nil {
+		fn()
+	}
+}
+
+func (a *rp2040Alarm) Now() uint32 {
+	return rp.TIMER.TIMERAWL.Get()
+}
+
+func (a *rp2040Alarm) Set(at uint32, fn func()) {
+	// the alarm only fires on an exact match, so anything in the past (or
+	// too close to arm in time) is handled immediately
+	if int32(at-a.Now()) < 2 {
+		fn()
+		return
+	}
+	mask := interrupt.Disable()
+	a.fn = fn
+	rp.TIMER.ALARM3.Set(at)
+	// if at passed while arming, the match was missed and the alarm would
+	// only fire once the counter wraps
+	missed := int32(at-a.Now()) <= 0 && rp.TIMER.ARMED.Get()&(1<<rp2040AlarmNum) != 0
+	if missed {
+		rp.TIMER.ARMED.Set(1 << rp2040AlarmNum)
+		a.fn = nil
+	}
+	interrupt.Restore(mask)
+	if missed {
+		fn()
+	}
+}
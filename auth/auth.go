@@ -0,0 +1,168 @@
+// auth authenticates commands with a rolling code, so safety relevant
+// commands such as arming a weapon or releasing an e-stop can't be
+// triggered by spoofed frames or by replaying a captured one.
+//
+// Both ends share a secret Key. Each message is sent as a stream frame
+// whose payload is
+//
+//	counter (4 bytes, little endian), message, MAC (4 bytes)
+//
+// where the MAC is SipHash-2-4 of the counter and message, truncated to 32
+// bits. The sender increments the counter for every message, and the
+// receiver only accepts counters above the last it accepted, up to Window
+// ahead, so a captured frame is worthless once a newer one has been
+// received.
+//
+//	s := auth.NewSigner(key, counter)
+//	tx.SendFrame(s.Seal([]byte{CmdArm}))
+//
+//	v := auth.NewVerifier(key, last)
+//	sm := stream.NewStateMachine(v.Filter(handleCmd))
+//	rx := irtrx.NewRxDevice(rxPin, sm)
+//	rx.StartInverted()
+//
+// Seal's output can equally be sent with a stream.Link, for messages of up to
+// MaxMessage-1 bytes, with the receiving Link's MessageHandler set to the
+// Filter.
+//
+// Both counters must survive power cycles, or a receiver that reboots will
+// accept replays until it sees a fresh frame, and a sender that reboots
+// will be rejected. See Signer.Counter and Verifier.Last.
+package auth
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/sparques/irtrx/stream"
+)
+
+// Key is a shared secret. Generate it randomly, once per pair of devices.
+type Key [16]byte
+
+const (
+	// CounterSize and MACSize are the bytes added to every message.
+	CounterSize = 4
+	MACSize     = 4
+	// MaxMessage is the largest message that fits in a frame.
+	MaxMessage = stream.MaxPayload - CounterSize - MACSize
+)
+
+// DefaultWindow is how far ahead of the last accepted counter a Verifier
+// accepts by default, so the receiver keeps up when it misses frames.
+const DefaultWindow = 1024
+
+var (
+	// ErrShort is returned for payloads too short to hold a counter and MAC.
+	ErrShort = errors.New("auth: frame too short")
+	// ErrMAC is returned when the MAC doesn't match; the frame wasn't sent
+	// with the same key, or was corrupted.
+	ErrMAC = errors.New("auth: bad MAC")
+	// ErrReplay is returned for a counter at or below the last accepted.
+	ErrReplay = errors.New("auth: replayed counter")
+	// ErrWindow is returned for a counter more than Window ahead of the last
+	// accepted.
+	ErrWindow = errors.New("auth: counter out of window")
+)
+
+// mac returns the truncated MAC of signed, the counter and message.
+func mac(key *Key, signed []byte) uint32 {
+	return uint32(sipHash(key, signed))
+}
+
+// Signer seals messages for a Verifier with the same key.
+type Signer struct {
+	key     Key
+	counter uint32
+}
+
+// NewSigner returns a Signer whose first message uses counter+1. counter
+// should be the value of Counter saved before the last power off, or zero
+// the first time.
+func NewSigner(key Key, counter uint32) *Signer {
+	return &Signer{key: key, counter: counter}
+}
+
+// Counter returns the counter of the last sealed message. Save it to flash
+// now and then; to limit wear, save it ahead of time, e.g. every 100
+// messages save Counter()+100 and pass that to NewSigner at startup. The
+// skipped counters are within the Verifier's window.
+func (s *Signer) Counter() uint32 {
+	return s.counter
+}
+
+// Seal returns a frame carrying msg, authenticated with the next counter.
+// msg is truncated to MaxMessage bytes.
+func (s *Signer) Seal(msg []byte) stream.Frame {
+	if len(msg) > MaxMessage {
+		msg = msg[:MaxMessage]
+	}
+	s.counter++
+	f := make(stream.Frame, CounterSize, CounterSize+len(msg)+MACSize)
+	binary.LittleEndian.PutUint32(f, s.counter)
+	f = append(f, msg...)
+	return binary.LittleEndian.AppendUint32(f, mac(&s.key, f))
+}
+
+// Verifier checks frames sealed by a Signer with the same key.
+type Verifier struct {
+	// Window is how far ahead of the last accepted counter a counter may
+	// be.
+	Window uint32
+	// ErrorHandler, if set, is called by Filter with the reason for every
+	// rejected frame, e.g. to count spoofing attempts.
+	ErrorHandler func(error)
+
+	key  Key
+	last uint32
+}
+
+// NewVerifier returns a Verifier accepting counters above last, which should
+// be the value of Last saved before the last power off, or zero the first
+// time.
+func NewVerifier(key Key, last uint32) *Verifier {
+	return &Verifier{Window: DefaultWindow, key: key, last: last}
+}
+
+// Last returns the last accepted counter. Save it whenever it changes, as
+// every counter up to it can be replayed after a reboot if it isn't.
+func (v *Verifier) Last() uint32 {
+	return v.last
+}
+
+// Open checks payload and returns the message it carries. The message
+// shares payload's memory. Only an accepted frame advances the counter.
+func (v *Verifier) Open(payload []byte) ([]byte, error) {
+	if len(payload) < CounterSize+MACSize {
+		return nil, ErrShort
+	}
+	signed := payload[:len(payload)-MACSize]
+	if mac(&v.key, signed) != binary.LittleEndian.Uint32(payload[len(signed):]) {
+		return nil, ErrMAC
+	}
+	counter := binary.LittleEndian.Uint32(payload)
+	switch {
+	case counter-v.last == 0 || counter-v.last > 1<<31:
+		return nil, ErrReplay
+	case counter-v.last > v.Window:
+		return nil, ErrWindow
+	}
+	v.last = counter
+	return signed[CounterSize:], nil
+}
+
+// Filter returns a handler for stream payloads passing the messages of
+// accepted frames on to handler, for use as a stream.StateMachine's
+// PayloadHandler or a stream.Link's MessageHandler.
+func (v *Verifier) Filter(handler func(msg []byte)) func(payload []byte) {
+	return func(payload []byte) {
+		msg, err := v.Open(payload)
+		if err != nil {
+			if v.ErrorHandler != nil {
+				v.ErrorHandler(err)
+			}
+			return
+		}
+		handler(msg)
+	}
+}
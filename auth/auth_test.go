@@ -0,0 +1,159 @@
+package auth_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/sparques/irtrx/auth"
+)
+
+var key = auth.Key{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+func TestSeal(t *testing.T) {
+	s := auth.NewSigner(key, 41)
+	f := s.Seal([]byte{0x01, 0x02})
+	// counter 42, the message and SipHash-2-4 of the two, truncated
+	want := []byte{0x2A, 0x00, 0x00, 0x00, 0x01, 0x02, 0x32, 0xA9, 0xF1, 0x67}
+	if !bytes.Equal(f, want) {
+		t.Errorf("Seal() = % x, want % x", []byte(f), want)
+	}
+	if s.Counter() != 42 {
+		t.Errorf("Counter() = %d, want 42", s.Counter())
+	}
+
+	// too long a message is cut to fit
+	f = s.Seal(make([]byte, auth.MaxMessage+10))
+	if len(f) != auth.CounterSize+auth.MaxMessage+auth.MACSize {
+		t.Errorf("sealed %d bytes, want %d", len(f), auth.CounterSize+auth.MaxMessage+auth.MACSize)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	s := auth.NewSigner(key, 0)
+	v := auth.NewVerifier(key, 0)
+	for i, msg := range [][]byte{{0x01}, {}, []byte("arm")} {
+		got, err := v.Open(s.Seal(msg))
+		if err != nil || !bytes.Equal(got, msg) {
+			t.Errorf("message %d: got %q, %v; want %q", i, got, err, msg)
+		}
+	}
+	if v.Last() != 3 {
+		t.Errorf("Last() = %d, want 3", v.Last())
+	}
+}
+
+func TestOpenBad(t *testing.T) {
+	s := auth.NewSigner(key, 0)
+	f := s.Seal([]byte("arm"))
+	flipped := append([]byte(nil), f...)
+	flipped[4] ^= 0x01
+	other := auth.NewSigner(auth.Key{1}, 0).Seal([]byte("arm"))
+
+	v := auth.NewVerifier(key, 0)
+	for _, tc := range []struct {
+		name    string
+		payload []byte
+		err     error
+	}{
+		{"Empty", nil, auth.ErrShort},
+		{"Short", f[:auth.CounterSize+auth.MACSize-1], auth.ErrShort},
+		{"Corrupt", flipped, auth.ErrMAC},
+		{"Key", other, auth.ErrMAC},
+		{"Truncated", f[:len(f)-1], auth.ErrMAC},
+	} {
+		if _, err := v.Open(tc.payload); err != tc.err {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.err)
+		}
+	}
+	if v.Last() != 0 {
+		t.Errorf("rejected frames moved Last to %d", v.Last())
+	}
+	if _, err := v.Open(f); err != nil {
+		t.Errorf("the genuine frame after the bad ones: %v", err)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	s := auth.NewSigner(key, 0)
+	v := auth.NewVerifier(key, 0)
+	first := s.Seal([]byte("arm"))
+	second := s.Seal([]byte("fire"))
+
+	if _, err := v.Open(second); err != nil {
+		t.Fatal(err)
+	}
+	// the same frame again, and an older one arriving late
+	for name, f := range map[string][]byte{"Same": second, "Older": first} {
+		if _, err := v.Open(f); err != auth.ErrReplay {
+			t.Errorf("%s: got %v, want %v", name, err, auth.ErrReplay)
+		}
+	}
+	if v.Last() != 2 {
+		t.Errorf("Last() = %d, want 2", v.Last())
+	}
+
+	// a verifier restored from a saved Last rejects what came before it
+	v = auth.NewVerifier(key, 2)
+	if _, err := v.Open(first); err != auth.ErrReplay {
+		t.Errorf("after restart: got %v, want %v", err, auth.ErrReplay)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	const last = 1000
+	for _, tc := range []struct {
+		name    string
+		counter uint32
+		err     error
+	}{
+		{"Next", last + 1, nil},
+		{"Edge", last + auth.DefaultWindow, nil},
+		{"Beyond", last + auth.DefaultWindow + 1, auth.ErrWindow},
+		{"HalfWay", last + 1<<31, auth.ErrWindow},
+		// more than half way round is behind, not ahead
+		{"Behind", last + 1<<31 + 1, auth.ErrReplay},
+	} {
+		v := auth.NewVerifier(key, last)
+		// Seal uses the counter after the one it is given
+		f := auth.NewSigner(key, tc.counter-1).Seal([]byte("arm"))
+		if _, err := v.Open(f); err != tc.err {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.err)
+		}
+	}
+
+	// a wider window, and a counter wrapping round to zero
+	v := auth.NewVerifier(key, 0xFFFFFFFF)
+	v.Window = 1 << 20
+	for _, counter := range []uint32{0, 1 << 19} {
+		f := auth.NewSigner(key, counter-1).Seal(nil)
+		if _, err := v.Open(f); err != nil {
+			t.Errorf("counter %d: %v", counter, err)
+		}
+	}
+	if v.Last() != 1<<19 {
+		t.Errorf("Last() = %d, want %d", v.Last(), 1<<19)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	var got [][]byte
+	var errs []error
+	v := auth.NewVerifier(key, 0)
+	v.ErrorHandler = func(err error) { errs = append(errs, err) }
+	h := v.Filter(func(msg []byte) { got = append(got, msg) })
+
+	s := auth.NewSigner(key, 0)
+	f := s.Seal([]byte("arm"))
+	h(f)
+	h(f)
+	h(f[:3])
+	h(s.Seal([]byte("fire")))
+
+	if want := [][]byte{[]byte("arm"), []byte("fire")}; !reflect.DeepEqual(got, want) {
+		t.Errorf("messages %q, want %q", got, want)
+	}
+	if want := []error{auth.ErrReplay, auth.ErrShort}; !reflect.DeepEqual(errs, want) {
+		t.Errorf("errors %v, want %v", errs, want)
+	}
+}
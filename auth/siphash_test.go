@@ -0,0 +1,34 @@
+package auth
+
+import "testing"
+
+// TestSipHash checks sipHash against the reference vectors from the SipHash
+// paper: key 00 01 .. 0f and messages 00 01 .. of each length, covering an
+// empty message, partial last blocks and whole ones.
+func TestSipHash(t *testing.T) {
+	var key Key
+	for i := range key {
+		key[i] = byte(i)
+	}
+	msg := make([]byte, 64)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	for _, tc := range []struct {
+		n    int
+		want uint64
+	}{
+		{0, 0x726fdb47dd0e0e31},
+		{1, 0x74f839c593dc67fd},
+		{2, 0x0d6c8009d9a94f5a},
+		{7, 0xab0200f58b01d137},
+		{8, 0x93f5f5799a932462},
+		{15, 0xa129ca6149be45e5},
+		{16, 0x3f2acc7f57c29bdb},
+		{63, 0x958a324ceb064572},
+	} {
+		if got := sipHash(&key, msg[:tc.n]); got != tc.want {
+			t.Errorf("%d bytes: got %#016x, want %#016x", tc.n, got, tc.want)
+		}
+	}
+}
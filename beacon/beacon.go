@@ -0,0 +1,262 @@
+// beacon implements IR beacons, the building block for dock-seeking robots,
+// following behaviors and virtual walls. A Beacon transmits short frames
+// carrying its ID, cycling through a few power levels; a Tracker on the
+// robot reports which beacons are visible, how close (the weakest level
+// heard) and, with several receivers facing different ways, roughly in
+// which direction.
+//
+// A frame is a 2ms mark and 2ms space followed by 24 bits, LSB first: the
+// ID, the level and a check byte, ID^Level^0xA5; see Spec. This requires
+// StartInverted() and not Start().
+package beacon
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/codec"
+)
+
+// MaxLevels is the most power levels a Beacon cycles through.
+const MaxLevels = 8
+
+// FrameBits is the number of bits in a frame.
+const FrameBits = 24
+
+var (
+	HeaderPair = irtrx.TimePair{2000 * time.Microsecond, 2000 * time.Microsecond}
+	ZeroPair   = irtrx.TimePair{400 * time.Microsecond, 400 * time.Microsecond}
+	OnePair    = irtrx.TimePair{400 * time.Microsecond, 1200 * time.Microsecond}
+	// StopPair ends the last bit's space.
+	StopPair = irtrx.TimePair{400 * time.Microsecond, 400 * time.Microsecond}
+)
+
+// Spec describes a frame's 24 bits as sent. It is generous with tolerance
+// since frames sent at low power arrive with distorted marks.
+var Spec = codec.Spec{
+	Name:      "beacon",
+	Header:    HeaderPair,
+	One:       OnePair,
+	Zero:      ZeroPair,
+	Trailer:   StopPair[0],
+	Gap:       StopPair[1],
+	Bits:      FrameBits,
+	Tolerance: 40,
+}
+
+var (
+	// ErrNoFrame is returned when unmarshalling TimePairs that hold no
+	// complete frame.
+	ErrNoFrame = errors.New("beacon: no frame found")
+	// ErrCheck is returned when a frame's check byte doesn't match.
+	ErrCheck = errors.New("beacon: bad check byte")
+)
+
+// Frame is a single beacon transmission.
+type Frame struct {
+	ID uint8
+	// Level is the index of the power level the frame was sent at in the
+	// Beacon's Levels, 0 being the weakest.
+	Level uint8
+}
+
+func init() {
+	irtrx.RegisterProtocol(irtrx.Protocol{
+		Name:  "beacon",
+		Usage: "id [level]",
+		Encode: func(args ...uint64) (irtrx.FrameMarshaller, error) {
+			switch {
+			case len(args) == 1 && args[0] <= 0xFF:
+				return &Frame{ID: uint8(args[0])}, nil
+			case len(args) == 2 && args[0] <= 0xFF && args[1] < MaxLevels:
+				return &Frame{ID: uint8(args[0]), Level: uint8(args[1])}, nil
+			}
+			return nil, irtrx.ErrArgs
+		},
+	})
+}
+
+// Raw returns the frame's 24 bits as sent.
+func (f Frame) Raw() uint32 {
+	return uint32(f.ID) | uint32(f.Level)<<8 | uint32(f.ID^f.Level^0xA5)<<16
+}
+
+// UnmarshalFrame sets f from 24 raw bits, checking the check byte.
+func (f *Frame) UnmarshalFrame(raw uint32) error {
+	id, level := uint8(raw), uint8(raw>>8)
+	if uint8(raw>>16) != id^level^0xA5 || level >= MaxLevels {
+		return ErrCheck
+	}
+	f.ID, f.Level = id, level
+	return nil
+}
+
+// MarshalFrame implements irtrx.FrameMarshaller.
+func (f *Frame) MarshalFrame() []irtrx.TimePair {
+	return Spec.Encode(uint64(f.Raw()))
+}
+
+// UnmarshalTimePairs implements irtrx.FrameUnmarshaller.
+func (f *Frame) UnmarshalTimePairs(pairs []irtrx.TimePair) error {
+	err := ErrNoFrame
+	var got bool
+	sm := NewStateMachine(func(fr Frame) {
+		*f, got = fr, true
+	})
+	sm.ErrorHandler = func(e error) {
+		err = e
+	}
+	for _, p := range pairs {
+		sm.HandleTimePair(p)
+		if got {
+			return nil
+		}
+	}
+	return err
+}
+
+// Protocol implements irtrx.Frame.
+func (f Frame) Protocol() string { return "beacon" }
+
+// Bits implements irtrx.Frame.
+func (f Frame) Bits() []byte {
+	raw := f.Raw()
+	return []byte{byte(raw), byte(raw >> 8), byte(raw >> 16)}
+}
+
+func (f Frame) String() string {
+	return fmt.Sprintf("beacon %d level %d", f.ID, f.Level)
+}
+
+// StateMachine implements irtrx.RxStateMachine, decoding beacon frames.
+type StateMachine struct {
+	FrameHandler func(Frame)
+	// ErrorHandler, if set, is called for every frame failing its check.
+	ErrorHandler func(error)
+
+	dec *codec.Decoder
+}
+
+func NewStateMachine(frameHandler func(Frame)) *StateMachine {
+	sm := &StateMachine{FrameHandler: frameHandler}
+	sm.dec = codec.NewDecoder(&Spec, sm.raw)
+	return sm
+}
+
+func (sm *StateMachine) HandleTimePair(pair irtrx.TimePair) {
+	sm.dec.HandleTimePair(pair)
+}
+
+// raw checks and delivers the bits of a frame.
+func (sm *StateMachine) raw(v uint64) {
+	var f Frame
+	if err := f.UnmarshalFrame(uint32(v)); err != nil {
+		if sm.ErrorHandler != nil {
+			sm.ErrorHandler(err)
+		}
+		return
+	}
+	if sm.FrameHandler != nil {
+		sm.FrameHandler(f)
+	}
+}
+
+// DefaultLevels are the power levels, in percent, a Beacon cycles through by
+// default: close, mid and full range.
+var DefaultLevels = []uint8{5, 20, 100}
+
+// DefaultPeriod is the time from the start of one beacon frame to the start
+// of the next.
+const DefaultPeriod = 50 * time.Millisecond
+
+// Beacon transmits its ID periodically, each frame at the next of Levels.
+// Levels need a transmitter with a SetPower method, such as irtrx.TxDevice;
+// with any other, every frame goes out at full power. For a dock, put two
+// beacons with different IDs behind a divider so a robot can tell which
+// side of the center line it is on; for a virtual wall, use a single level
+// and a narrow emitter.
+//
+//	b := beacon.NewBeacon(tx, 1)
+//	b.Start()
+type Beacon struct {
+	ID uint8
+	// Levels are transmit powers in percent, weakest first. At most
+	// MaxLevels are used.
+	Levels []uint8
+	// Period is the time from the start of one frame to the start of the
+	// next. Stagger the Periods of beacons in range of one another, e.g.
+	// 50ms and 53ms, so their frames don't keep colliding.
+	Period time.Duration
+
+	tx      irtrx.Transmitter
+	running atomic.Bool
+	clock   irtrx.Clock
+}
+
+// NewBeacon returns a Beacon sending id with tx.
+func NewBeacon(tx irtrx.Transmitter, id uint8) *Beacon {
+	return &Beacon{
+		ID:     id,
+		Levels: DefaultLevels,
+		Period: DefaultPeriod,
+		tx:     tx,
+		clock:  irtrx.RealClock,
+	}
+}
+
+// SetClock replaces the clock used to time frames. Set it before Start.
+func (b *Beacon) SetClock(c irtrx.Clock) {
+	b.clock = c
+}
+
+// powered is implemented by irtrx.TxDevice.
+type powered interface {
+	SetPower(percent uint8)
+	Power() uint8
+}
+
+// Start starts transmitting from a goroutine.
+func (b *Beacon) Start() {
+	if b.running.Swap(true) {
+		return
+	}
+	go b.run()
+}
+
+// Stop stops transmitting after the current frame.
+func (b *Beacon) Stop() {
+	b.running.Store(false)
+}
+
+func (b *Beacon) run() {
+	next := b.clock.Now()
+	for level := 0; b.running.Load(); level++ {
+		if level >= len(b.Levels) || level >= MaxLevels {
+			level = 0
+		}
+		b.Send(uint8(level))
+		next = next.Add(b.Period)
+		if wait := next.Sub(b.clock.Now()); wait > 0 {
+			b.clock.Sleep(wait)
+		}
+	}
+}
+
+// Send sends a single frame at Levels[level], restoring the transmitter's
+// power afterwards.
+func (b *Beacon) Send(level uint8) {
+	if pt, ok := b.tx.(powered); ok && int(level) < len(b.Levels) {
+		defer pt.SetPower(pt.Power())
+		pt.SetPower(b.Levels[level])
+	}
+	b.tx.SendFrame(&Frame{ID: b.ID, Level: level})
+}
+
+var (
+	_ irtrx.Frame             = Frame{}
+	_ irtrx.FrameUnmarshaller = (*Frame)(nil)
+	_ irtrx.RxStateMachine    = (*StateMachine)(nil)
+)
@@ -0,0 +1,131 @@
+package beacon_test
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/beacon"
+	"github.com/sparques/irtrx/irtest"
+)
+
+// protocol round trips beacon.Frames at every level.
+var protocol = irtest.Protocol[beacon.Frame]{
+	Name: "beacon",
+	Random: func(r *rand.Rand) beacon.Frame {
+		return beacon.Frame{ID: uint8(r.Uint32()), Level: uint8(r.Intn(beacon.MaxLevels))}
+	},
+	Marshal: func(f beacon.Frame) irtrx.FrameMarshaller { return &f },
+	NewDecoder: func(h func(beacon.Frame)) irtrx.RxStateMachine {
+		return beacon.NewStateMachine(h)
+	},
+	Inverted: true,
+}
+
+// pairs returns the header, a pair for each of bits, '0' or '1', and the
+// stop pair.
+func pairs(bits string) []irtrx.TimePair {
+	out := []irtrx.TimePair{beacon.HeaderPair}
+	for _, b := range bits {
+		if b == '1' {
+			out = append(out, beacon.OnePair)
+		} else {
+			out = append(out, beacon.ZeroPair)
+		}
+	}
+	return append(out, beacon.StopPair)
+}
+
+// known is beacon 0x12 at level 2: 0x12, 0x02 and the check byte
+// 0x12^0x02^0xA5 = 0xB5, LSB first.
+var known = beacon.Frame{ID: 0x12, Level: 2}
+
+const knownBits = "01001000" + "01000000" + "10101101"
+
+func TestRoundTrip(t *testing.T) {
+	irtest.RoundTrip(t, protocol, 1000, 1)
+}
+
+func TestMarshalFrame(t *testing.T) {
+	if raw := known.Raw(); raw != 0xB50212 {
+		t.Errorf("Raw() = %#x, want 0xb50212", raw)
+	}
+	if got, want := known.MarshalFrame(), pairs(knownBits); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := known.Bits(), []byte{0x12, 0x02, 0xB5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Bits() = %x, want %x", got, want)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	// what every decoder must do, on random frames
+	irtest.Conform(t, protocol)
+
+	for _, want := range []beacon.Frame{
+		known,
+		{},
+		{ID: 0xFF, Level: beacon.MaxLevels - 1},
+	} {
+		var got []beacon.Frame
+		w := irtest.NewWire(beacon.NewStateMachine(func(f beacon.Frame) { got = append(got, f) }), true)
+		w.SendFrame(&want)
+		if len(got) != 1 || got[0] != want {
+			t.Errorf("sent %v, decoded %v", want, got)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		bits string
+	}{
+		// the last bit of the check byte flipped
+		{"CheckByte", knownBits[:23] + "0"},
+		// level 8 with a matching check byte: 0x12^0x08^0xA5 = 0xBF
+		{"Level", "01001000" + "00010000" + "11111101"},
+	} {
+		var got []beacon.Frame
+		var errs []error
+		sm := beacon.NewStateMachine(func(f beacon.Frame) { got = append(got, f) })
+		sm.ErrorHandler = func(err error) { errs = append(errs, err) }
+		irtest.NewWire(sm, true).SendPairs(pairs(tc.bits)...)
+		if len(got) != 0 || len(errs) != 1 || errs[0] != beacon.ErrCheck {
+			t.Errorf("%s: decoded %v, errors %v; want [%v]", tc.name, got, errs, beacon.ErrCheck)
+		}
+
+		var f beacon.Frame
+		if err := f.UnmarshalTimePairs(pairs(tc.bits)); err != beacon.ErrCheck {
+			t.Errorf("%s: UnmarshalTimePairs: got %v, want %v", tc.name, err, beacon.ErrCheck)
+		}
+	}
+}
+
+func TestUnmarshalTimePairs(t *testing.T) {
+	var f beacon.Frame
+	noise := []irtrx.TimePair{beacon.OnePair, beacon.ZeroPair}
+	if err := f.UnmarshalTimePairs(append(noise, pairs(knownBits)...)); err != nil || f != known {
+		t.Errorf("got %v, %v; want %v", f, err, known)
+	}
+	for name, p := range map[string][]irtrx.TimePair{
+		"Empty":    nil,
+		"NoHeader": pairs(knownBits)[1:],
+		"Short":    pairs(knownBits)[:20],
+	} {
+		if err := f.UnmarshalTimePairs(p); err != beacon.ErrNoFrame {
+			t.Errorf("%s: got %v, want %v", name, err, beacon.ErrNoFrame)
+		}
+	}
+}
+
+// TestCorpus replays the captures in testdata, each of which holds the
+// value it should decode to.
+func TestCorpus(t *testing.T) {
+	caps, err := irtest.LoadCorpus("testdata/captures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	irtest.ReplayCorpus(t, caps, "beacon", protocol.NewDecoder)
+}
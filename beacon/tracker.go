@@ -0,0 +1,217 @@
+package beacon
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/sparques/irtrx"
+)
+
+// MaxBeacons is the number of distinct beacon IDs a Tracker keeps track of.
+// Once full, further IDs are ignored.
+const MaxBeacons = 16
+
+// DefaultTimeout is how long after its last frame a beacon stays visible.
+const DefaultTimeout = 300 * time.Millisecond
+
+// Sighting describes a visible beacon.
+type Sighting struct {
+	ID uint8
+	// Receivers has bit i set if receiver i sees the beacon.
+	Receivers uint32
+	// Level is the weakest level any receiver heard; the lower, the
+	// closer the beacon.
+	Level uint8
+	// LastSeen is when the beacon was last heard.
+	LastSeen time.Time
+}
+
+// entry tracks one beacon ID on one receiver. id is only written before
+// used is set, so the interrupt handler and readers need no lock.
+type entry struct {
+	id   uint8
+	used atomic.Bool
+	// when each level was last heard, in Unix nanoseconds
+	seen [MaxLevels]atomic.Int64
+}
+
+// Tracker reports which beacons are visible to a set of receivers. Give each
+// RxDevice the StateMachine returned by Receiver:
+//
+//	t := beacon.NewTracker(2)
+//	left := irtrx.NewRxDevice(leftPin, t.Receiver(0))
+//	right := irtrx.NewRxDevice(rightPin, t.Receiver(1))
+//	left.StartInverted()
+//	right.StartInverted()
+//
+// and then poll it from the main loop:
+//
+//	if s, ok := t.Sighting(dockID); ok && s.Receivers == 0b01 {
+//	    turnLeft()
+//	}
+type Tracker struct {
+	// Timeout is how long after its last frame a beacon stays visible. It
+	// should cover a few of the Beacon's Periods times its levels, so a
+	// dropped frame doesn't make it blink out.
+	Timeout time.Duration
+
+	receivers [][MaxBeacons]entry
+	clock     irtrx.Clock
+}
+
+// NewTracker returns a Tracker for n receivers, at most 32.
+func NewTracker(n int) *Tracker {
+	if n > 32 {
+		n = 32
+	}
+	return &Tracker{
+		Timeout:   DefaultTimeout,
+		receivers: make([][MaxBeacons]entry, n),
+		clock:     irtrx.RealClock,
+	}
+}
+
+// SetClock replaces the clock used to time sightings.
+func (t *Tracker) SetClock(c irtrx.Clock) {
+	t.clock = c
+}
+
+// Receiver returns the decoder for receiver i.
+func (t *Tracker) Receiver(i int) *StateMachine {
+	return NewStateMachine(func(f Frame) {
+		t.saw(i, f)
+	})
+}
+
+// saw records f, heard by receiver i, from the interrupt handler.
+func (t *Tracker) saw(i int, f Frame) {
+	entries := &t.receivers[i]
+	for j := range entries {
+		e := &entries[j]
+		if !e.used.Load() {
+			e.id = f.ID
+			e.used.Store(true)
+		} else if e.id != f.ID {
+			continue
+		}
+		e.seen[f.Level].Store(t.clock.Now().UnixNano())
+		return
+	}
+}
+
+// lookup returns receiver i's entry for id, or nil.
+func (t *Tracker) lookup(i int, id uint8) *entry {
+	entries := &t.receivers[i]
+	for j := range entries {
+		e := &entries[j]
+		if !e.used.Load() {
+			break
+		}
+		if e.id == id {
+			return e
+		}
+	}
+	return nil
+}
+
+// heard returns the weakest level e was heard at within Timeout of now, and
+// when e was last heard at any level.
+func (t *Tracker) heard(e *entry, now int64) (level int, last int64, ok bool) {
+	level = MaxLevels
+	for l := range e.seen {
+		at := e.seen[l].Load()
+		if at == 0 || now-at > int64(t.Timeout) {
+			continue
+		}
+		level = min(level, l)
+		last = max(last, at)
+		ok = true
+	}
+	return level, last, ok
+}
+
+// Sighting returns the sighting of beacon id, if it is visible.
+func (t *Tracker) Sighting(id uint8) (Sighting, bool) {
+	now := t.clock.Now().UnixNano()
+	s := Sighting{ID: id, Level: MaxLevels}
+	var last int64
+	for i := range t.receivers {
+		e := t.lookup(i, id)
+		if e == nil {
+			continue
+		}
+		if level, at, ok := t.heard(e, now); ok {
+			s.Receivers |= 1 << i
+			s.Level = min(s.Level, uint8(level))
+			last = max(last, at)
+		}
+	}
+	if s.Receivers == 0 {
+		return Sighting{}, false
+	}
+	s.LastSeen = time.Unix(0, last)
+	return s, true
+}
+
+// Visible returns the sightings of every visible beacon.
+func (t *Tracker) Visible() []Sighting {
+	var ids [256]bool
+	var out []Sighting
+	for i := range t.receivers {
+		entries := &t.receivers[i]
+		for j := range entries {
+			e := &entries[j]
+			if !e.used.Load() {
+				break
+			}
+			if ids[e.id] {
+				continue
+			}
+			ids[e.id] = true
+			if s, ok := t.Sighting(e.id); ok {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+// Bearing estimates the direction of beacon id from which receivers see
+// it: the average of their headings, in degrees, given by angles, with
+// receivers hearing weaker levels counting for more. ok is false if the
+// beacon isn't visible.
+//
+// With receivers at the front, left, back and right, for example:
+//
+//	heading, ok := t.Bearing(dockID, []float64{0, 90, 180, 270})
+func (t *Tracker) Bearing(id uint8, angles []float64) (heading float64, ok bool) {
+	now := t.clock.Now().UnixNano()
+	var x, y float64
+	for i := range t.receivers {
+		if i >= len(angles) {
+			break
+		}
+		e := t.lookup(i, id)
+		if e == nil {
+			continue
+		}
+		level, _, heard := t.heard(e, now)
+		if !heard {
+			continue
+		}
+		w := float64(MaxLevels - level)
+		rad := angles[i] * math.Pi / 180
+		x += w * math.Cos(rad)
+		y += w * math.Sin(rad)
+		ok = true
+	}
+	if !ok {
+		return 0, false
+	}
+	heading = math.Atan2(y, x) * 180 / math.Pi
+	if heading < 0 {
+		heading += 360
+	}
+	return heading, true
+}
@@ -0,0 +1,199 @@
+// blaster sends codes of any registered protocol given by name and named
+// parameters, so gateway firmware can relay commands such as
+//
+//	{"protocol": "samsung", "addr": 7, "cmd": 2}
+//
+// without knowing at compile time which protocols it will be asked for:
+//
+//	b := blaster.NewBlaster(irtrx.NewTxDevice(txPin))
+//	err := b.Send("samsung", blaster.Params{"addr": 0x07, "cmd": 0x02})
+//	err = b.SendPronto("0000 006D 0022 0002 0155 00AA ...")
+//
+// Parameter names are those of the protocol's Usage; see irtrx.Protocol.
+// Importing blaster registers every protocol in this module.
+package blaster
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sparques/irtrx"
+	_ "github.com/sparques/irtrx/beacon"
+	_ "github.com/sparques/irtrx/cheapo"
+	_ "github.com/sparques/irtrx/codec"
+	_ "github.com/sparques/irtrx/hexbug"
+	"github.com/sparques/irtrx/pronto"
+	_ "github.com/sparques/irtrx/samsung"
+)
+
+// ErrParams is returned when parameters match none of the forms in a
+// protocol's Usage.
+var ErrParams = errors.New("blaster: parameters don't match protocol")
+
+// Params are the named parameters of a code, e.g. {"addr": 7, "cmd": 2}.
+type Params map[string]uint64
+
+// Args returns params as the arguments of the first form in usage, e.g.
+// "addr cmd | code", they match. A form matches if params has each of its
+// names, bar those in brackets, and no others.
+func Args(usage string, params Params) ([]uint64, error) {
+	for _, form := range strings.Split(usage, "|") {
+		if args, ok := match(strings.Fields(form), params); ok {
+			return args, nil
+		}
+	}
+	return nil, ErrParams
+}
+
+func match(names []string, params Params) ([]uint64, bool) {
+	var args []uint64
+	for _, name := range names {
+		optional := strings.HasPrefix(name, "[") && strings.HasSuffix(name, "]")
+		name = strings.Trim(name, "[]")
+		v, ok := params[name]
+		switch {
+		case ok:
+			args = append(args, v)
+		case !optional:
+			return nil, false
+		}
+	}
+	return args, len(args) == len(params)
+}
+
+// Encode returns the frame of the registered protocol for params.
+func Encode(protocol string, params Params) (irtrx.FrameMarshaller, error) {
+	p, ok := irtrx.LookupProtocol(protocol)
+	if !ok {
+		return nil, irtrx.ErrProtocol
+	}
+	args, err := Args(p.Usage, params)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s takes %s", err, protocol, p.Usage)
+	}
+	return p.Encode(args...)
+}
+
+// Blaster sends codes with a Transmitter, serializing sends from different
+// goroutines. With an irtrx.TxDevice, frames of protocols with their own
+// carrier are sent on it.
+type Blaster struct {
+	// Repeats is how many repeats follow each frame sent by Send,
+	// SendArgs and SendPronto, for devices that ignore a lone frame.
+	Repeats int
+
+	tx irtrx.Transmitter
+	mu sync.Mutex
+}
+
+// NewBlaster returns a Blaster sending with tx.
+func NewBlaster(tx irtrx.Transmitter) *Blaster {
+	return &Blaster{tx: tx}
+}
+
+// Send sends the code of protocol given by params.
+func (b *Blaster) Send(protocol string, params Params) error {
+	fm, err := Encode(protocol, params)
+	if err != nil {
+		return err
+	}
+	b.SendFrame(fm)
+	return nil
+}
+
+// SendArgs sends the code of protocol given by positional args, as for
+// irtrx.Encode.
+func (b *Blaster) SendArgs(protocol string, args ...uint64) error {
+	fm, err := irtrx.Encode(protocol, args...)
+	if err != nil {
+		return err
+	}
+	b.SendFrame(fm)
+	return nil
+}
+
+// SendPronto sends a Pronto Hex code: its once sequence, or its repeat
+// sequence if it has none.
+func (b *Blaster) SendPronto(s string) error {
+	c, err := pronto.Parse(s)
+	if err != nil {
+		return err
+	}
+	b.SendFrame(&c)
+	return nil
+}
+
+// Hold sends the code of protocol given by params as if its button were
+// held for d.
+func (b *Blaster) Hold(protocol string, params Params, d time.Duration) error {
+	fm, err := Encode(protocol, params)
+	if err != nil {
+		return err
+	}
+	b.HoldFrame(fm, d)
+	return nil
+}
+
+// SendFrame sends fm followed by Repeats repeats.
+func (b *Blaster) SendFrame(fm irtrx.FrameMarshaller) {
+	b.send(fm, b.Repeats, 0)
+}
+
+// HoldFrame sends fm followed by repeats until d has passed.
+func (b *Blaster) HoldFrame(fm irtrx.FrameMarshaller, d time.Duration) {
+	b.send(fm, -1, d)
+}
+
+// repeatFrame is a repeat, carrying the carrier of its frame.
+type repeatFrame struct {
+	pairs   []irtrx.TimePair
+	carrier uint32
+}
+
+func (r *repeatFrame) MarshalFrame() []irtrx.TimePair { return r.pairs }
+func (r *repeatFrame) Carrier() uint32                { return r.carrier }
+
+// send sends fm, then repeats until n have been sent or, if n is negative,
+// until d has passed. Repeats follow the protocol's convention if fm is an
+// irtrx.RepeatMarshaller; otherwise the whole frame is resent every
+// irtrx.DefaultHoldGap, as TxDevice.Hold does.
+func (b *Blaster) send(fm irtrx.FrameMarshaller, n int, d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	start := time.Now()
+	b.tx.SendFrame(fm)
+	if n == 0 {
+		return
+	}
+
+	rep := &repeatFrame{}
+	if ch, ok := fm.(irtrx.CarrierHinter); ok {
+		rep.carrier = ch.Carrier()
+	}
+	var period time.Duration
+	if rm, ok := fm.(irtrx.RepeatMarshaller); ok {
+		rep.pairs = rm.MarshalRepeat()
+		period = rm.RepeatPeriod()
+	} else {
+		rep.pairs = fm.MarshalFrame()
+		for _, p := range rep.pairs {
+			period += p[0] + p[1]
+		}
+		period += irtrx.DefaultHoldGap
+	}
+
+	next := start
+	for i := 0; n < 0 || i < n; i++ {
+		next = next.Add(period)
+		if n < 0 && next.Sub(start) >= d {
+			return
+		}
+		time.Sleep(time.Until(next))
+		b.tx.SendFrame(rep)
+	}
+}
+
+var _ irtrx.CarrierHinter = (*repeatFrame)(nil)
@@ -0,0 +1,154 @@
+// broadlink encodes and decodes the packet format used by Broadlink RM
+// devices, so codes harvested from the Broadlink ecosystem (e.g. SmartIR's
+// JSON files, which store them base64 encoded) can be replayed.
+//
+// A packet is a type byte (0x26 for IR), a repeat count, a little endian
+// length of the data that follows, and the data: alternating mark and space
+// durations in units of 269/8192ms (about 32.84µs). Durations that don't fit
+// in a byte are written as a zero byte followed by two bytes, big endian.
+// Packets are usually padded with zeros to a multiple of 16 bytes.
+package broadlink
+
+import (
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/sparques/irtrx"
+)
+
+const (
+	// TypeIR is the packet type of infrared codes.
+	TypeIR = 0x26
+	// TypeRF433 and TypeRF315 are the packet types of RF codes, which can't
+	// be sent with a TxDevice.
+	TypeRF433 = 0xB2
+	TypeRF315 = 0xD7
+
+	headerLen = 4
+)
+
+// TrailingSpace is the space given to a final mark without one.
+const TrailingSpace = 100 * time.Millisecond
+
+var (
+	// ErrShort is returned for a packet shorter than its header says.
+	ErrShort = errors.New("broadlink: packet too short")
+	// ErrType is returned for packets that aren't IR.
+	ErrType = errors.New("broadlink: not an IR packet")
+)
+
+// Packet is a Broadlink IR code.
+type Packet struct {
+	// Repeat is the number of times the code is sent after the first.
+	Repeat uint8
+	Pairs  []irtrx.TimePair
+}
+
+// toTicks converts d to Broadlink ticks, rounding to the nearest.
+func toTicks(d time.Duration) uint16 {
+	t := (d*8192 + 269*time.Millisecond/2) / (269 * time.Millisecond)
+	if t > 0xFFFF {
+		t = 0xFFFF
+	}
+	return uint16(t)
+}
+
+func fromTicks(t uint16) time.Duration {
+	return time.Duration(t) * 269 * time.Millisecond / 8192
+}
+
+// Decode parses a packet.
+func Decode(b []byte) (Packet, error) {
+	if len(b) < headerLen {
+		return Packet{}, ErrShort
+	}
+	if b[0] != TypeIR {
+		return Packet{}, ErrType
+	}
+	p := Packet{Repeat: b[1]}
+	n := int(b[2]) | int(b[3])<<8
+	if len(b) < headerLen+n {
+		return Packet{}, ErrShort
+	}
+	data := b[headerLen : headerLen+n]
+
+	var mark time.Duration
+	haveMark := false
+	for i := 0; i < len(data); i++ {
+		t := uint16(data[i])
+		if t == 0 {
+			if i+2 >= len(data) {
+				break
+			}
+			t = uint16(data[i+1])<<8 | uint16(data[i+2])
+			i += 2
+		}
+		d := fromTicks(t)
+		if !haveMark {
+			mark, haveMark = d, true
+			continue
+		}
+		p.Pairs = append(p.Pairs, irtrx.TimePair{mark, d})
+		haveMark = false
+	}
+	if haveMark {
+		p.Pairs = append(p.Pairs, irtrx.TimePair{mark, TrailingSpace})
+	}
+	return p, nil
+}
+
+// DecodeBase64 parses a base64 encoded packet, as stored by most Broadlink
+// integrations.
+func DecodeBase64(s string) (Packet, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return Packet{}, err
+	}
+	return Decode(b)
+}
+
+// Encode returns p as a packet, padded to a multiple of 16 bytes.
+func (p *Packet) Encode() []byte {
+	b := make([]byte, headerLen, headerLen+2*len(p.Pairs)+16)
+	b[0] = TypeIR
+	b[1] = p.Repeat
+	put := func(d time.Duration) {
+		t := toTicks(d)
+		if t > 0xFF || t == 0 {
+			b = append(b, 0, byte(t>>8), byte(t))
+			return
+		}
+		b = append(b, byte(t))
+	}
+	for _, pair := range p.Pairs {
+		put(pair[0])
+		put(pair[1])
+	}
+	n := len(b) - headerLen
+	b[2], b[3] = byte(n), byte(n>>8)
+	for len(b)%16 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// EncodeBase64 returns p as a base64 encoded packet.
+func (p *Packet) EncodeBase64() string {
+	return base64.StdEncoding.EncodeToString(p.Encode())
+}
+
+// MarshalFrame implements irtrx.FrameMarshaller. The pairs are sent
+// Repeat+1 times, as a Broadlink device would.
+func (p *Packet) MarshalFrame() []irtrx.TimePair {
+	out := make([]irtrx.TimePair, 0, len(p.Pairs)*(int(p.Repeat)+1))
+	for i := 0; i <= int(p.Repeat); i++ {
+		out = append(out, p.Pairs...)
+	}
+	return out
+}
+
+// Recording returns the pairs of a single send as a Recording.
+func (p *Packet) Recording() irtrx.Recording {
+	return irtrx.Recording{Freq: irtrx.Freq38Khz, Pairs: p.Pairs}
+}
@@ -0,0 +1,175 @@
+package broadlink_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/broadlink"
+)
+
+func us(n int) time.Duration { return time.Duration(n) * time.Microsecond }
+
+// known is the start of an NEC frame as a Broadlink device stores it: type
+// 0x26, no repeats, 12 bytes of data and, in ticks of 269/8192ms, a 274
+// tick header mark escaped as 00 01 12, a 137 tick space, 17 tick marks,
+// 17 and 51 tick spaces and the usual 00 0D 05 (3333 tick) gap.
+var known = []byte{
+	0x26, 0x00, 0x0C, 0x00,
+	0x00, 0x01, 0x12, 0x89,
+	0x11, 0x11,
+	0x11, 0x33,
+	0x11, 0x00, 0x0D, 0x05,
+}
+
+// knownPairs are the durations of known's ticks, truncated to the
+// nanosecond.
+var knownPairs = []irtrx.TimePair{
+	{8997314, 4498657},
+	{558227, 558227},
+	{558227, 1674682},
+	{558227, 109445434},
+}
+
+func TestDecode(t *testing.T) {
+	p, err := broadlink.Decode(known)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Repeat != 0 || !reflect.DeepEqual(p.Pairs, knownPairs) {
+		t.Errorf("got %d, %v; want 0, %v", p.Repeat, p.Pairs, knownPairs)
+	}
+
+	p, err = broadlink.DecodeBase64("JgAMAAABEokREREzEQANBQ==")
+	if err != nil || !reflect.DeepEqual(p.Pairs, knownPairs) {
+		t.Errorf("DecodeBase64: got %v, %v; want %v", p.Pairs, err, knownPairs)
+	}
+}
+
+func TestDecodeHeader(t *testing.T) {
+	// the repeat byte, padding after the data and a final mark without a
+	// space
+	b := []byte{0x26, 0x02, 0x03, 0x00, 0x11, 0x33, 0x11, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+	p, err := broadlink.Decode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []irtrx.TimePair{{558227, 1674682}, {558227, broadlink.TrailingSpace}}
+	if p.Repeat != 2 || !reflect.DeepEqual(p.Pairs, want) {
+		t.Errorf("got %d, %v; want 2, %v", p.Repeat, p.Pairs, want)
+	}
+	if got := p.MarshalFrame(); len(got) != 3*len(want) || !reflect.DeepEqual(got[4:], want) {
+		t.Errorf("MarshalFrame() = %v, want %v three times", got, want)
+	}
+	if r := p.Recording(); r.Freq != irtrx.Freq38Khz || !reflect.DeepEqual(r.Pairs, want) {
+		t.Errorf("Recording() = %v", r)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		b    []byte
+		err  error
+	}{
+		{"Empty", nil, broadlink.ErrShort},
+		{"Header", []byte{0x26, 0x00, 0x02}, broadlink.ErrShort},
+		// says 12 bytes of data, has 11
+		{"Data", known[:15], broadlink.ErrShort},
+		{"RF", []byte{broadlink.TypeRF433, 0x00, 0x00, 0x00}, broadlink.ErrType},
+	} {
+		if _, err := broadlink.Decode(tc.b); err != tc.err {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.err)
+		}
+	}
+	if _, err := broadlink.DecodeBase64("not base64!"); err == nil {
+		t.Error("bad base64: no error")
+	}
+}
+
+func TestEncode(t *testing.T) {
+	p := broadlink.Packet{Pairs: []irtrx.TimePair{
+		{us(9000), us(4500)},
+		{us(560), us(560)},
+		{us(560), us(1690)},
+		{us(560), us(109460)},
+	}}
+	if got := p.Encode(); !bytes.Equal(got, known) {
+		t.Errorf("Encode() = % x, want % x", got, known)
+	}
+	if got, want := p.EncodeBase64(), "JgAMAAABEokREREzEQANBQ=="; got != want {
+		t.Errorf("EncodeBase64() = %s, want %s", got, want)
+	}
+}
+
+func TestEncodeTicks(t *testing.T) {
+	for _, tc := range []struct {
+		d    time.Duration
+		want []byte
+	}{
+		// rounded to the nearest tick of about 32.84µs
+		{us(17), []byte{0x01}},
+		{us(49), []byte{0x01}},
+		{us(50), []byte{0x02}},
+		// the largest duration that fits in a byte, and the smallest
+		// that doesn't
+		{8373413, []byte{0xFF}},
+		{8406250, []byte{0x00, 0x01, 0x00}},
+		// a zero tick can't be a lone zero byte, which is the escape
+		{us(10), []byte{0x00, 0x00, 0x00}},
+		// clamped to the longest duration there is
+		{3 * time.Second, []byte{0x00, 0xFF, 0xFF}},
+	} {
+		p := broadlink.Packet{Pairs: []irtrx.TimePair{{tc.d, us(560)}}}
+		b := p.Encode()
+		want := append(append([]byte{}, tc.want...), 0x11)
+		if n := int(b[2]) | int(b[3])<<8; n != len(want) || !bytes.Equal(b[4:4+n], want) {
+			t.Errorf("%v: encoded % x, want % x", tc.d, b[4:], want)
+		}
+	}
+}
+
+func TestEncodeLength(t *testing.T) {
+	// 200 pairs of one byte ticks: 400 bytes of data, so the length needs
+	// both bytes, padded to 416
+	p := broadlink.Packet{Repeat: 5, Pairs: make([]irtrx.TimePair, 200)}
+	for i := range p.Pairs {
+		p.Pairs[i] = irtrx.TimePair{us(560), us(1690)}
+	}
+	b := p.Encode()
+	if len(b) != 416 {
+		t.Errorf("%d bytes, want 416", len(b))
+	}
+	if b[0] != broadlink.TypeIR || b[1] != 5 || b[2] != 0x90 || b[3] != 0x01 {
+		t.Errorf("header % x, want 26 05 90 01", b[:4])
+	}
+	got, err := broadlink.Decode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Repeat != 5 || len(got.Pairs) != 200 || got.Pairs[199] != (irtrx.TimePair{558227, 1674682}) {
+		t.Errorf("decoded %d pairs, repeat %d", len(got.Pairs), got.Repeat)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	// whole ticks survive a round trip exactly, however they are written
+	var pairs []irtrx.TimePair
+	for _, ticks := range [][2]uint16{{1, 17}, {255, 256}, {274, 137}, {3333, 0xFFFF}} {
+		pairs = append(pairs, irtrx.TimePair{fromTicks(ticks[0]), fromTicks(ticks[1])})
+	}
+	p := broadlink.Packet{Repeat: 1, Pairs: pairs}
+	got, err := broadlink.DecodeBase64(p.EncodeBase64())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, p) {
+		t.Errorf("got %v, want %v", got, p)
+	}
+}
+
+func fromTicks(t uint16) time.Duration {
+	return time.Duration(t) * 269 * time.Millisecond / 8192
+}
@@ -0,0 +1,84 @@
+// capture moves IR timings between this module and logic analyzers, so
+// received timings can be overlaid on Saleae or sigrok captures, and real
+// captures can be replayed through decoders on a desktop.
+package capture
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/sparques/irtrx"
+)
+
+// Options controls how pairs are turned into a waveform.
+type Options struct {
+	// ActiveLow writes marks as 0, as seen on the output pin of a
+	// demodulating receiver. Otherwise marks are 1, as on the LED.
+	ActiveLow bool
+	// Name is the name of the signal; "ir" if empty.
+	Name string
+}
+
+func (o Options) name() string {
+	if o.Name == "" {
+		return "ir"
+	}
+	return o.Name
+}
+
+// levels returns the mark and space levels.
+func (o Options) levels() (mark, space int) {
+	if o.ActiveLow {
+		return 0, 1
+	}
+	return 1, 0
+}
+
+// edges calls fn with the time and new level of every edge in pairs. A
+// zero length mark or space gives two edges at the same time.
+func edges(pairs []irtrx.TimePair, opt Options, fn func(t time.Duration, level int)) {
+	mark, space := opt.levels()
+	var t time.Duration
+	for _, p := range pairs {
+		fn(t, mark)
+		t += p[0]
+		fn(t, space)
+		t += p[1]
+	}
+}
+
+// WriteCSV writes pairs as a CSV of level transitions, with the time in
+// seconds, in the same layout as a Saleae digital export. The first pair's
+// mark starts at time zero.
+func WriteCSV(w io.Writer, pairs []irtrx.TimePair, opt Options) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "Time [s],%s\n", opt.name())
+	last := -1
+	edges(pairs, opt, func(t time.Duration, level int) {
+		if level == last {
+			return
+		}
+		last = level
+		fmt.Fprintf(bw, "%.9f,%d\n", t.Seconds(), level)
+	})
+	return bw.Flush()
+}
+
+// WriteVCD writes pairs as a Value Change Dump with a 1µs timescale, which
+// PulseView, GTKWave and most other waveform viewers open.
+func WriteVCD(w io.Writer, pairs []irtrx.TimePair, opt Options) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "$timescale 1 us $end\n$scope module irtrx $end\n")
+	fmt.Fprintf(bw, "$var wire 1 ! %s $end\n$upscope $end\n$enddefinitions $end\n", opt.name())
+	last := -1
+	edges(pairs, opt, func(t time.Duration, level int) {
+		if level == last {
+			return
+		}
+		last = level
+		fmt.Fprintf(bw, "#%d\n%d!\n", t.Microseconds(), level)
+	})
+	return bw.Flush()
+}
@@ -0,0 +1,212 @@
+package capture
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sparques/irtrx"
+)
+
+// TrailingSpace is the space given to the last mark of a capture, long
+// enough for any decoder to see the end of the frame.
+const TrailingSpace = 100 * time.Millisecond
+
+var (
+	// ErrNoSignal is returned when the signal named in Options isn't in
+	// the capture.
+	ErrNoSignal = errors.New("capture: signal not found")
+	// ErrFormat is returned for malformed captures.
+	ErrFormat = errors.New("capture: malformed capture")
+)
+
+// pairer builds mark-space pairs from level changes.
+type pairer struct {
+	mark    int
+	level   int
+	started bool
+	start   time.Duration
+	markEnd time.Duration
+	pairs   []irtrx.TimePair
+}
+
+func newPairer(opt Options) *pairer {
+	mark, _ := opt.levels()
+	return &pairer{mark: mark, level: -1}
+}
+
+func (p *pairer) edge(t time.Duration, level int) {
+	if level == p.level {
+		return
+	}
+	p.level = level
+	switch {
+	case level == p.mark && p.started:
+		p.pairs = append(p.pairs, irtrx.TimePair{p.markEnd - p.start, t - p.markEnd})
+		p.start = t
+	case level == p.mark:
+		p.started = true
+		p.start = t
+	case p.started:
+		p.markEnd = t
+	}
+}
+
+func (p *pairer) finish() []irtrx.TimePair {
+	if p.started && p.level != p.mark {
+		p.pairs = append(p.pairs, irtrx.TimePair{p.markEnd - p.start, TrailingSpace})
+	}
+	return p.pairs
+}
+
+// ReadCSV reads a CSV of levels against time in seconds, as exported by
+// Saleae Logic (transitions only) or sigrok-cli -O csv:time=true (one row
+// per sample), or written by WriteCSV, and returns the mark-space pairs. The
+// signal is the column whose header is opt.Name, or the first after the
+// time. Lines starting with ; or # are skipped. opt.ActiveLow must match
+// the capture: it is true for the output pin of a demodulating receiver.
+func ReadCSV(r io.Reader, opt Options) ([]irtrx.TimePair, error) {
+	p := newPairer(opt)
+	col := 1
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+		f := strings.Split(line, ",")
+		secs, err := strconv.ParseFloat(strings.TrimSpace(f[0]), 64)
+		if err != nil {
+			// a header
+			if opt.Name == "" {
+				continue
+			}
+			col = -1
+			for i, h := range f {
+				if strings.TrimSpace(h) == opt.Name {
+					col = i
+				}
+			}
+			if col < 0 {
+				return nil, ErrNoSignal
+			}
+			continue
+		}
+		if col >= len(f) {
+			return nil, ErrFormat
+		}
+		level, err := strconv.Atoi(strings.TrimSpace(f[col]))
+		if err != nil {
+			return nil, ErrFormat
+		}
+		p.edge(time.Duration(secs*float64(time.Second)+0.5), level)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return p.finish(), nil
+}
+
+// ReadVCD reads a Value Change Dump, as exported by PulseView or written by
+// WriteVCD, and returns the mark-space pairs of the signal named opt.Name,
+// or of the first signal if it is empty.
+func ReadVCD(r io.Reader, opt Options) ([]irtrx.TimePair, error) {
+	p := newPairer(opt)
+	scale := time.Microsecond
+	var id string
+	var t time.Duration
+
+	sc := bufio.NewScanner(r)
+	sc.Split(bufio.ScanWords)
+	var words []string
+	for sc.Scan() {
+		words = append(words, sc.Text())
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		switch {
+		case w == "$timescale":
+			spec := ""
+			for i++; i < len(words) && words[i] != "$end"; i++ {
+				spec += words[i]
+			}
+			var err error
+			if scale, err = parseTimescale(spec); err != nil {
+				return nil, err
+			}
+		case w == "$var":
+			// $var type size id name $end
+			if i+4 >= len(words) {
+				return nil, ErrFormat
+			}
+			if id == "" && (opt.Name == "" || words[i+4] == opt.Name) {
+				id = words[i+3]
+			}
+			for ; i < len(words) && words[i] != "$end"; i++ {
+			}
+		case w[0] == '$':
+			// other sections; $dumpvars and friends wrap value changes
+			// we read anyway
+			if w == "$dumpvars" || w == "$dumpall" || w == "$dumpon" || w == "$dumpoff" || w == "$end" {
+				continue
+			}
+			for ; i < len(words) && words[i] != "$end"; i++ {
+			}
+		case w[0] == '#':
+			n, err := strconv.ParseInt(w[1:], 10, 64)
+			if err != nil {
+				return nil, ErrFormat
+			}
+			t = time.Duration(n) * scale
+		case w[0] == '0' || w[0] == '1':
+			if w[1:] == id {
+				p.edge(t, int(w[0]-'0'))
+			}
+		case w[0] == 'b' && i+1 < len(words):
+			// a vector value; the id is the next word
+			if words[i+1] == id {
+				p.edge(t, int(w[len(w)-1]-'0'))
+			}
+			i++
+		}
+	}
+	if id == "" {
+		return nil, ErrNoSignal
+	}
+	return p.finish(), nil
+}
+
+// parseTimescale parses e.g. "1us" or "10ns".
+func parseTimescale(s string) (time.Duration, error) {
+	units := []struct {
+		suffix string
+		d      time.Duration
+	}{{"fs", 0}, {"ps", 0}, {"ns", time.Nanosecond}, {"us", time.Microsecond}, {"ms", time.Millisecond}, {"s", time.Second}}
+	for _, u := range units {
+		if !strings.HasSuffix(s, u.suffix) {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(s, u.suffix))
+		if err != nil || u.d == 0 {
+			// sub-nanosecond timescales aren't useful for IR
+			return 0, ErrFormat
+		}
+		return time.Duration(n) * u.d, nil
+	}
+	return 0, ErrFormat
+}
+
+// Replay feeds pairs through sm, as an RxDevice would after
+// StartInverted().
+func Replay(pairs []irtrx.TimePair, sm irtrx.RxStateMachine) {
+	for _, p := range pairs {
+		sm.HandleTimePair(p)
+	}
+}
@@ -0,0 +1,146 @@
+package cheapo
+
+import (
+	"time"
+
+	"github.com/sparques/irtrx"
+)
+
+// Thresholds are the timings the StateMachine uses to tell pairs apart.
+type Thresholds struct {
+	// StartMark is the shortest mark taken as the start of a frame or a
+	// repeat burst.
+	StartMark time.Duration
+	// RepeatSpace is the longest space after a start mark taken as a repeat
+	// burst rather than a frame.
+	RepeatSpace time.Duration
+	// OneSpace is the shortest space taken as a one bit.
+	OneSpace time.Duration
+}
+
+// DefaultThresholds suit most remotes.
+var DefaultThresholds = Thresholds{
+	StartMark:   7 * time.Millisecond,
+	RepeatSpace: 3 * time.Millisecond,
+	OneSpace:    time.Millisecond,
+}
+
+// CalibrationPairs is how many pairs, about four frames' worth, Calibrate
+// collects before deriving thresholds.
+const CalibrationPairs = 4 * (FrameBits + 2)
+
+type calibration struct {
+	pairs [CalibrationPairs]irtrx.TimePair
+	n     int
+	done  func(Thresholds)
+}
+
+// SetThresholds replaces the timing thresholds.
+func (c *StateMachine) SetThresholds(th Thresholds) {
+	c.th = th
+}
+
+// Thresholds returns the timing thresholds in use.
+func (c *StateMachine) Thresholds() Thresholds {
+	return c.th
+}
+
+// Calibrate derives the thresholds from the remote in use rather than
+// relying on DefaultThresholds; these remotes vary a lot between batches.
+// The next CalibrationPairs pairs are collected and their marks and spaces
+// clustered; frames keep being decoded with the old thresholds meanwhile.
+// done, if not nil, is called with the new thresholds. It is called from the
+// interrupt handler. If the pairs can't be clustered (e.g. they were all
+// noise) the thresholds are left alone and done isn't called.
+func (c *StateMachine) Calibrate(done func(Thresholds)) {
+	c.cal = &calibration{done: done}
+}
+
+func (c *StateMachine) calibrate(pair irtrx.TimePair) {
+	cal := c.cal
+	cal.pairs[cal.n] = pair
+	cal.n++
+	if cal.n < CalibrationPairs {
+		return
+	}
+	c.cal = nil
+
+	var marks, spaces [CalibrationPairs]time.Duration
+	for i, p := range cal.pairs {
+		marks[i] = p[0]
+	}
+	// start marks are about 16 times as long as bit marks
+	startMark := split(marks[:])
+	if startMark == 0 {
+		return
+	}
+
+	// the spaces following start marks: frame starts and repeat bursts
+	var longest time.Duration
+	n := 0
+	for _, p := range cal.pairs {
+		if p[0] > startMark {
+			spaces[n] = p[1]
+			n++
+			if p[1] > longest {
+				longest = p[1]
+			}
+		}
+	}
+	repeatSpace := split(spaces[:n])
+	if repeatSpace == 0 {
+		// only frame starts were seen; repeats are half as long
+		repeatSpace = longest * 3 / 4
+	}
+
+	// bit spaces, leaving out the gaps between frames
+	n = 0
+	for _, p := range cal.pairs {
+		if p[0] <= startMark && p[1] < longest {
+			spaces[n] = p[1]
+			n++
+		}
+	}
+	oneSpace := split(spaces[:n])
+	if oneSpace == 0 {
+		return
+	}
+
+	c.th = Thresholds{StartMark: startMark, RepeatSpace: repeatSpace, OneSpace: oneSpace}
+	if cal.done != nil {
+		cal.done(c.th)
+	}
+}
+
+// split clusters vals into a short and a long group and returns the midpoint
+// between them, or 0 if they can't be split.
+func split(vals []time.Duration) time.Duration {
+	if len(vals) < 2 {
+		return 0
+	}
+	lo, hi := vals[0], vals[0]
+	for _, v := range vals {
+		lo = min(lo, v)
+		hi = max(hi, v)
+	}
+	// the groups must be well apart to be told apart reliably
+	if hi < lo*3/2 {
+		return 0
+	}
+	for i := 0; i < 8; i++ {
+		mid := (lo + hi) / 2
+		var sumLo, sumHi time.Duration
+		var nLo, nHi int
+		for _, v := range vals {
+			if v <= mid {
+				sumLo += v
+				nLo++
+			} else {
+				sumHi += v
+				nHi++
+			}
+		}
+		lo, hi = sumLo/time.Duration(nLo), sumHi/time.Duration(nHi)
+	}
+	return (lo + hi) / 2
+}
@@ -1,8 +1,15 @@
-package cheapo
-
 // cheapo package implements an irtrx.RxStatemachine for cheap, unknown brand IR remote controls.
 // I have a stack of these things; they come with LED light strips.
 // The button codes are usually in order, starting from zero and increasing, left to right, top to bottom.
+//
+// The remotes speak something very close to NEC: a 9ms mark and 4.5ms space
+// start the frame, followed by 32 bits, LSB first: an address byte, its
+// inverse, a command byte and its inverse. This requires StartInverted() and
+// not Start().
+//
+// Encoding "cheapo" by name with irtrx.Encode, a lone cmd is sent to
+// DefaultAddr.
+package cheapo
 
 import (
 	"time"
@@ -10,35 +17,175 @@ import (
 	"github.com/sparques/irtrx"
 )
 
-// Cheapo implements an RX statemachine for an cheap, uknown brand IR remote
+// FrameBits is the number of bits in a frame.
+const FrameBits = 32
+
+// BitOrder is the order in which a frame's bits are sent.
+type BitOrder uint8
+
+const (
+	// LSBFirst frames are sent least significant bit first, like NEC.
+	LSBFirst BitOrder = iota
+	// MSBFirst frames are sent most significant bit first.
+	MSBFirst
+)
+
+// RepeatTimeout is how long after a frame or repeat burst a repeat burst is
+// still taken to mean the same key is held. The remotes send one every
+// 108ms.
+const RepeatTimeout = 150 * time.Millisecond
+
+// StateMachine implements an RX statemachine for an cheap, uknown brand IR remote
 type StateMachine struct {
+	// CmdHandler is called with the raw bits of every frame. 32 bit frames
+	// must pass the inverse byte checks; see Addr and Cmd.
 	CmdHandler func(uint32)
-	buf        uint32
-	bitcount   int
+	// RepeatHandler, if set, is called for each repeat burst sent while a
+	// key is held, with the raw frame of the held key and the number of
+	// repeats so far, starting at 1.
+	RepeatHandler func(raw uint32, n int)
+	buf           uint32
+	bitcount      int
+	bits          int
+	order         BitOrder
+	// inFrame is set by a start of frame and cleared once a frame is
+	// delivered, so stray pairs between frames are ignored.
+	inFrame bool
+
+	// last delivered frame and when it, or its last repeat, was seen
+	last    uint32
+	lastAt  time.Time
+	repeats int
+	clock   irtrx.Clock
+
+	// timing thresholds and their calibration; see calibrate.go
+	th  Thresholds
+	cal *calibration
+
+	// decode error accounting; see errors.go
+	errors       Errors
+	errorHandler func(DecodeError, uint32, int)
 }
 
 func NewStateMachine(cmdHandler func(uint32)) *StateMachine {
-	return &Cheapo{CmdHandler: cmdHandler}
+	return NewStateMachineBits(cmdHandler, FrameBits, LSBFirst)
+}
+
+// NewStateMachineBits returns a StateMachine for remotes that send frames of
+// bits bits (at most 32) in the given order, so the whole family of no-name
+// remotes can be covered. Frames are delivered as the number their bits
+// form; only 32 bit frames can be checked for inverse bytes, and Addr and
+// Cmd only make sense for 32 bit LSBFirst frames.
+func NewStateMachineBits(cmdHandler func(uint32), bits int, order BitOrder) *StateMachine {
+	if bits < 1 || bits > FrameBits {
+		bits = FrameBits
+	}
+	return &StateMachine{
+		CmdHandler: cmdHandler,
+		bits:       bits,
+		order:      order,
+		th:         DefaultThresholds,
+		clock:      irtrx.RealClock,
+	}
 }
 
-func (c *Cheapo) HandleTimePair(pair irtrx.TimePair) {
+func (c *StateMachine) HandleTimePair(pair irtrx.TimePair) {
 	on, off := pair[0], pair[1]
+	if c.cal != nil {
+		c.calibrate(pair)
+	}
 	switch {
-	case on > 7*time.Millisecond: // 9ms start of frame
+	case on > c.th.StartMark && off < c.th.RepeatSpace: // 9ms, 2.25ms repeat
+		c.reset()
+		c.repeat()
+		return
+	case on > c.th.StartMark: // 9ms start of frame
+		if c.inFrame && c.bitcount != 0 {
+			c.decodeError(ErrBitCount, c.buf, c.bitcount)
+		}
 		c.buf = 0
 		c.bitcount = 0
+		c.inFrame = true
+		return
+	case !c.inFrame:
+		return
+	case off > 3*c.th.OneSpace:
+		// a gap; the frame ended early
+		c.decodeError(ErrBitCount, c.buf, c.bitcount)
+		c.reset()
+		return
+	case off < minBitSpace || on > c.th.StartMark/4:
+		c.decodeError(ErrTiming, c.buf, c.bitcount)
+		c.reset()
 		return
-	case off > time.Millisecond:
+	case c.order == MSBFirst:
+		c.buf <<= 1
+		if off > c.th.OneSpace {
+			c.buf |= 1
+		}
+		c.bitcount++
+	case off > c.th.OneSpace:
 		// one
 		c.buf |= 1 << c.bitcount
 		fallthrough
-	case off < time.Millisecond:
+	default:
 		// zero
 		c.bitcount++
 	}
 
-	if c.bitcount < 10 {
+	if c.bitcount < c.bits {
+		return
+	}
+	buf := c.buf
+	c.reset()
+	if c.bits == FrameBits && !Valid(buf) {
+		c.decodeError(ErrInverse, buf, c.bits)
+		return
+	}
+	c.last = buf
+	c.lastAt = c.clock.Now()
+	c.repeats = 0
+	if c.CmdHandler != nil {
+		c.CmdHandler(buf)
+	}
+}
+
+// SetClock replaces the clock used to time out repeats.
+func (c *StateMachine) SetClock(clock irtrx.Clock) {
+	c.clock = clock
+}
+
+// repeat handles a repeat burst.
+func (c *StateMachine) repeat() {
+	now := c.clock.Now()
+	if c.lastAt.IsZero() || now.Sub(c.lastAt) > RepeatTimeout {
 		return
 	}
-	c.CmdHandler(c.buf)
+	c.lastAt = now
+	c.repeats++
+	if c.RepeatHandler != nil {
+		c.RepeatHandler(c.last, c.repeats)
+	}
+}
+
+func (c *StateMachine) reset() {
+	c.buf = 0
+	c.bitcount = 0
+	c.inFrame = false
+}
+
+// Valid reports whether the second and fourth bytes of raw are the inverse
+// of the first and third.
+func Valid(raw uint32) bool {
+	return byte(raw) == ^byte(raw>>8) && byte(raw>>16) == ^byte(raw>>24)
+}
+
+// Addr returns the address byte of a raw frame.
+func Addr(raw uint32) uint8 {
+	return uint8(raw)
+}
+
+// Cmd returns the command byte of a raw frame; this identifies the button.
+func Cmd(raw uint32) uint8 {
+	return uint8(raw >> 16)
 }
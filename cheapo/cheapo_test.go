@@ -0,0 +1,251 @@
+package cheapo_test
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/cheapo"
+	"github.com/sparques/irtrx/irtest"
+)
+
+// protocol round trips cheapo.Frames, decoded as raw frames.
+var protocol = irtest.Protocol[uint32]{
+	Name: "cheapo",
+	Random: func(r *rand.Rand) uint32 {
+		f := cheapo.Frame{Addr: uint8(r.Uint32()), Cmd: uint8(r.Uint32())}
+		return f.Raw()
+	},
+	Marshal: func(raw uint32) irtrx.FrameMarshaller {
+		return &cheapo.Frame{Addr: cheapo.Addr(raw), Cmd: cheapo.Cmd(raw)}
+	},
+	NewDecoder: func(h func(uint32)) irtrx.RxStateMachine {
+		return cheapo.NewStateMachine(h)
+	},
+	Inverted: true,
+}
+
+// pairs returns a start of frame, a pair for each of bits, '0' or '1', and
+// the stop bit.
+func pairs(bits string) []irtrx.TimePair {
+	out := []irtrx.TimePair{cheapo.StartPair}
+	for _, b := range bits {
+		if b == '1' {
+			out = append(out, cheapo.OnePair)
+		} else {
+			out = append(out, cheapo.ZeroPair)
+		}
+	}
+	return append(out, cheapo.ZeroPair)
+}
+
+// red is KeyRed sent to DefaultAddr: 0x00, 0xFF, 0x04, 0xFB, LSB first.
+const (
+	red    = "00000000" + "11111111" + "00100000" + "11011111"
+	redRaw = 0xFB04FF00
+)
+
+func TestRoundTrip(t *testing.T) {
+	irtest.RoundTrip(t, protocol, 1000, 1)
+}
+
+func TestMarshalFrame(t *testing.T) {
+	if got, want := cheapo.KeyRed.MarshalFrame(), pairs(red); !reflect.DeepEqual(got, want) {
+		t.Errorf("KeyRed: got %v, want %v", got, want)
+	}
+	f := cheapo.Frame{Addr: 0xFF, Cmd: 0xFF}
+	if got, want := f.MarshalFrame(), pairs("11111111000000001111111100000000"); !reflect.DeepEqual(got, want) {
+		t.Errorf("%v: got %v, want %v", f, got, want)
+	}
+	if got, want := f.MarshalRepeat(), []irtrx.TimePair{cheapo.RepeatPair, cheapo.ZeroPair}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MarshalRepeat() = %v, want %v", got, want)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	// what every decoder must do, on random frames
+	irtest.Conform(t, protocol)
+
+	var got []uint32
+	w := irtest.NewWire(cheapo.NewStateMachine(func(raw uint32) { got = append(got, raw) }), true)
+	w.SendPairs(pairs(red)...)
+	if len(got) != 1 || got[0] != redRaw {
+		t.Fatalf("decoded %x, want [%x]", got, redRaw)
+	}
+	if k := cheapo.KeyOf(got[0]); k != cheapo.KeyRed {
+		t.Errorf("KeyOf(%x) = %v, want %v", got[0], k, cheapo.KeyRed)
+	}
+	if a, c := cheapo.Addr(got[0]), cheapo.Cmd(got[0]); a != cheapo.DefaultAddr || c != 0x04 {
+		t.Errorf("Addr, Cmd = %#x, %#x; want %#x, 0x04", a, c, cheapo.DefaultAddr)
+	}
+
+	for _, f := range []cheapo.Frame{{}, {Addr: 0xFF, Cmd: 0xFF}, cheapo.Key44Red.Frame()} {
+		got = got[:0]
+		w.SendFrame(&f)
+		if len(got) != 1 || got[0] != f.Raw() {
+			t.Errorf("sent %v, decoded %x, want [%x]", f, got, f.Raw())
+		}
+	}
+}
+
+func TestValid(t *testing.T) {
+	for raw, want := range map[uint32]bool{
+		redRaw:     true,
+		0xFF00FF00: true,
+		0x00FF00FF: true,
+		0xFB04FE00: false,
+		0xFA04FF00: false,
+		0:          false,
+	} {
+		if got := cheapo.Valid(raw); got != want {
+			t.Errorf("Valid(%#x) = %v, want %v", raw, got, want)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	var got []uint32
+	type dropped struct {
+		de   cheapo.DecodeError
+		raw  uint32
+		bits int
+	}
+	var errs []dropped
+	sm := cheapo.NewStateMachine(func(raw uint32) { got = append(got, raw) })
+	sm.SetErrorHandler(func(de cheapo.DecodeError, raw uint32, bits int) {
+		errs = append(errs, dropped{de, raw, bits})
+	})
+	w := irtest.NewWire(sm, true)
+
+	// the last byte isn't the inverse of the third
+	w.SendPairs(pairs(red[:24] + "11011110")...)
+	// cut short by the next start of frame
+	w.SendPairs(pairs(red)[:9]...)
+	w.SendPairs(pairs(red)...)
+	// a glitch in place of a bit
+	glitch := pairs(red)
+	glitch[3][1] = 100 * time.Microsecond
+	w.SendPairs(glitch...)
+
+	if len(got) != 1 || got[0] != redRaw {
+		t.Errorf("decoded %x, want [%x]", got, redRaw)
+	}
+	want := []dropped{
+		{cheapo.ErrInverse, 0x7B04FF00, 32},
+		{cheapo.ErrBitCount, 0, 8},
+		{cheapo.ErrTiming, 0, 2},
+	}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("errors %+v, want %+v", errs, want)
+	}
+	if got, want := sm.Errors(), (cheapo.Errors{BitCount: 1, Inverse: 1, Timing: 1}); got != want {
+		t.Errorf("Errors() = %+v, want %+v", got, want)
+	}
+	sm.ResetErrors()
+	if got := sm.Errors(); got != (cheapo.Errors{}) {
+		t.Errorf("after ResetErrors: %+v", got)
+	}
+}
+
+func TestRepeat(t *testing.T) {
+	type repeat struct {
+		raw uint32
+		n   int
+	}
+	var got []repeat
+	clock := irtest.NewClock()
+	sm := cheapo.NewStateMachine(nil)
+	sm.SetClock(clock)
+	sm.RepeatHandler = func(raw uint32, n int) { got = append(got, repeat{raw, n}) }
+	w := irtest.NewWire(sm, true)
+	f := cheapo.KeyRed.Frame()
+
+	// a repeat with nothing to repeat is ignored
+	w.SendPairs(f.MarshalRepeat()...)
+	w.SendFrame(&f)
+	for i := 0; i < 2; i++ {
+		clock.Advance(cheapo.RepeatPeriod)
+		w.SendPairs(f.MarshalRepeat()...)
+	}
+	// the key was released and a repeat arrives too late
+	clock.Advance(cheapo.RepeatTimeout + time.Millisecond)
+	w.SendPairs(f.MarshalRepeat()...)
+	// the stop bit ending the last burst
+	w.SendPair(cheapo.ZeroPair)
+
+	want := []repeat{{redRaw, 1}, {redRaw, 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestStateMachineBits(t *testing.T) {
+	var got []uint32
+	sm := cheapo.NewStateMachineBits(func(raw uint32) { got = append(got, raw) }, 12, cheapo.MSBFirst)
+	w := irtest.NewWire(sm, true)
+	// 12 bits aren't checked for inverse bytes
+	w.SendPairs(pairs("101100000001")...)
+	if len(got) != 1 || got[0] != 0xB01 {
+		t.Errorf("decoded %x, want [b01]", got)
+	}
+
+	// out of range bit counts fall back to FrameBits
+	got = got[:0]
+	sm = cheapo.NewStateMachineBits(func(raw uint32) { got = append(got, raw) }, 40, cheapo.LSBFirst)
+	irtest.NewWire(sm, true).SendPairs(pairs(red)...)
+	if len(got) != 1 || got[0] != redRaw {
+		t.Errorf("decoded %x, want [%x]", got, redRaw)
+	}
+}
+
+func TestUnmarshalTimePairs(t *testing.T) {
+	var f cheapo.Frame
+	if err := f.UnmarshalTimePairs(pairs(red)); err != nil || f != cheapo.KeyRed.Frame() {
+		t.Errorf("got %v, %v; want %v", f, err, cheapo.KeyRed.Frame())
+	}
+	repeat := cheapo.KeyRed.Frame()
+	for _, tc := range []struct {
+		name  string
+		pairs []irtrx.TimePair
+		err   error
+	}{
+		{"Empty", nil, cheapo.ErrBitCount},
+		{"Short", pairs(red)[:20], cheapo.ErrBitCount},
+		{"Repeat", repeat.MarshalRepeat(), cheapo.ErrBitCount},
+		{"Inverse", pairs(red[:31] + "0"), cheapo.ErrInverse},
+	} {
+		if err := f.UnmarshalTimePairs(tc.pairs); err != tc.err {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.err)
+		}
+	}
+}
+
+func TestBinary(t *testing.T) {
+	f := cheapo.KeyRed.Frame()
+	b, _ := f.MarshalBinary()
+	if want := []byte{0x00, 0x04}; !reflect.DeepEqual(b, want) {
+		t.Errorf("MarshalBinary() = %x, want %x", b, want)
+	}
+	if got, want := f.Bits(), []byte{0x00, 0xFF, 0x04, 0xFB}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Bits() = %x, want %x", got, want)
+	}
+	var got cheapo.Frame
+	if err := got.UnmarshalBinary(b); err != nil || got != f {
+		t.Errorf("UnmarshalBinary(%x) = %v, %v", b, got, err)
+	}
+	if err := got.UnmarshalBinary(f.Bits()); err != irtrx.ErrBinary {
+		t.Errorf("UnmarshalBinary(%x) = %v, want %v", f.Bits(), err, irtrx.ErrBinary)
+	}
+}
+
+// TestCorpus replays the captures in testdata, each of which holds the
+// value it should decode to.
+func TestCorpus(t *testing.T) {
+	caps, err := irtest.LoadCorpus("testdata/captures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	irtest.ReplayCorpus(t, caps, "cheapo", protocol.NewDecoder)
+}
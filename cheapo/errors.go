@@ -0,0 +1,70 @@
+package cheapo
+
+import "time"
+
+// bit spaces shorter than this are glitches
+const minBitSpace = 150 * time.Microsecond
+
+// DecodeError identifies why a frame was dropped.
+type DecodeError uint8
+
+const (
+	// ErrBitCount means the frame ended, with a gap or a new start, before
+	// the expected number of bits arrived.
+	ErrBitCount DecodeError = iota + 1
+	// ErrInverse means a 32 bit frame failed the inverse byte checks.
+	ErrInverse
+	// ErrTiming means a bit's mark or space was neither a zero nor a one.
+	ErrTiming
+)
+
+func (de DecodeError) Error() string {
+	switch de {
+	case ErrBitCount:
+		return "cheapo: wrong bit count"
+	case ErrInverse:
+		return "cheapo: inverse byte mismatch"
+	case ErrTiming:
+		return "cheapo: bit timing out of range"
+	}
+	return "cheapo: unknown error"
+}
+
+// Errors counts the frames dropped by a StateMachine, by reason.
+type Errors struct {
+	BitCount int
+	Inverse  int
+	Timing   int
+}
+
+// Errors returns the decode error counts.
+func (c *StateMachine) Errors() Errors {
+	return c.errors
+}
+
+// ResetErrors zeroes the decode error counts.
+func (c *StateMachine) ResetErrors() {
+	c.errors = Errors{}
+}
+
+// SetErrorHandler sets a callback that is called, from interrupt context,
+// for every dropped frame with the reason, the bits received so far and how
+// many there were. This is the quickest way to find out which dialect an
+// unknown remote speaks. Pass nil to remove it.
+func (c *StateMachine) SetErrorHandler(errorHandler func(de DecodeError, raw uint32, bits int)) {
+	c.errorHandler = errorHandler
+}
+
+func (c *StateMachine) decodeError(de DecodeError, raw uint32, bits int) {
+	switch de {
+	case ErrBitCount:
+		c.errors.BitCount++
+	case ErrInverse:
+		c.errors.Inverse++
+	case ErrTiming:
+		c.errors.Timing++
+	}
+	if c.errorHandler != nil {
+		c.errorHandler(de, raw, bits)
+	}
+}
@@ -0,0 +1,162 @@
+package cheapo
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sparques/irtrx"
+)
+
+// DefaultAddr is the address sent by the 24-key and 44-key LED remotes.
+const DefaultAddr = 0x00
+
+var (
+	StartPair  = irtrx.TimePair{9 * time.Millisecond, 4500 * time.Microsecond}
+	RepeatPair = irtrx.TimePair{9 * time.Millisecond, 2250 * time.Microsecond}
+	ZeroPair   = irtrx.TimePair{562 * time.Microsecond, 562 * time.Microsecond}
+	OnePair    = irtrx.TimePair{562 * time.Microsecond, 1687 * time.Microsecond}
+)
+
+// RepeatPeriod is the time from the start of one frame or repeat burst to
+// the start of the next while a button is held.
+const RepeatPeriod = 108 * time.Millisecond
+
+// Frame is a decoded frame. On the wire each byte is followed by its
+// inverse.
+type Frame struct {
+	Addr uint8
+	Cmd  uint8
+}
+
+func init() {
+	irtrx.RegisterProtocol(irtrx.Protocol{
+		Name:  "cheapo",
+		Usage: "[addr] cmd",
+		Encode: func(args ...uint64) (irtrx.FrameMarshaller, error) {
+			f := Frame{Addr: DefaultAddr}
+			switch {
+			case len(args) == 1 && args[0] <= 0xFF:
+				f.Cmd = uint8(args[0])
+			case len(args) == 2 && args[0] <= 0xFF && args[1] <= 0xFF:
+				f.Addr, f.Cmd = uint8(args[0]), uint8(args[1])
+			default:
+				return nil, irtrx.ErrArgs
+			}
+			return &f, nil
+		},
+	})
+}
+
+// Raw returns the 32 bits sent for f.
+func (f *Frame) Raw() uint32 {
+	return uint32(^f.Cmd)<<24 | uint32(f.Cmd)<<16 | uint32(^f.Addr)<<8 | uint32(f.Addr)
+}
+
+// UnmarshalFrame sets f from the raw 32 bits of a frame, as passed to
+// CmdHandler.
+func (f *Frame) UnmarshalFrame(raw uint32) {
+	f.Addr = Addr(raw)
+	f.Cmd = Cmd(raw)
+}
+
+// UnmarshalTimePairs implements irtrx.FrameUnmarshaller, decoding a 32 bit
+// LSBFirst frame. Anything before the start of frame is skipped; a repeat
+// burst is not a frame.
+func (f *Frame) UnmarshalTimePairs(pairs []irtrx.TimePair) error {
+	var raw uint32
+	var got bool
+	err := error(ErrBitCount)
+	sm := NewStateMachine(func(r uint32) {
+		raw, got = r, true
+	})
+	sm.SetErrorHandler(func(de DecodeError, _ uint32, _ int) {
+		err = de
+	})
+	for _, p := range pairs {
+		sm.HandleTimePair(p)
+		if got {
+			f.UnmarshalFrame(raw)
+			return nil
+		}
+	}
+	return err
+}
+
+// MarshalFrame implements irtrx.FrameMarshaller.
+func (f *Frame) MarshalFrame() []irtrx.TimePair {
+	out := make([]irtrx.TimePair, FrameBits+2)
+	out[0] = StartPair
+	raw := f.Raw()
+	for bit := 0; bit < FrameBits; bit++ {
+		if (raw>>bit)&1 == 1 {
+			out[bit+1] = OnePair
+		} else {
+			out[bit+1] = ZeroPair
+		}
+	}
+	// stop bit
+	out[FrameBits+1] = ZeroPair
+	return out
+}
+
+// MarshalRepeat implements irtrx.RepeatMarshaller. While a button is held
+// the remotes send a short repeat burst rather than the whole frame.
+func (f *Frame) MarshalRepeat() []irtrx.TimePair {
+	return []irtrx.TimePair{RepeatPair, ZeroPair}
+}
+
+// RepeatPeriod implements irtrx.RepeatMarshaller.
+func (f *Frame) RepeatPeriod() time.Duration {
+	return RepeatPeriod
+}
+
+func (f Frame) String() string {
+	return fmt.Sprintf("{Addr: %02X, Cmd: %02X}", f.Addr, f.Cmd)
+}
+
+// Protocol implements irtrx.Frame.
+func (f Frame) Protocol() string { return "cheapo" }
+
+// Bits implements irtrx.Frame, returning all 32 bits of the frame, inverse
+// bytes included.
+func (f Frame) Bits() []byte {
+	return []byte{f.Addr, ^f.Addr, f.Cmd, ^f.Cmd}
+}
+
+// Frame returns a Frame sending k to DefaultAddr.
+func (k Key) Frame() Frame {
+	return Frame{Addr: DefaultAddr, Cmd: uint8(k)}
+}
+
+// MarshalFrame implements irtrx.FrameMarshaller, so you can do
+// tx.SendFrame(cheapo.KeyRed).
+func (k Key) MarshalFrame() []irtrx.TimePair {
+	f := k.Frame()
+	return f.MarshalFrame()
+}
+
+// Frame returns a Frame sending k to DefaultAddr.
+func (k Key44) Frame() Frame {
+	return Frame{Addr: DefaultAddr, Cmd: uint8(k)}
+}
+
+// MarshalFrame implements irtrx.FrameMarshaller.
+func (k Key44) MarshalFrame() []irtrx.TimePair {
+	f := k.Frame()
+	return f.MarshalFrame()
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler as the address byte
+// followed by the command byte; the inverse bytes are implied.
+func (f Frame) MarshalBinary() ([]byte, error) {
+	return []byte{f.Addr, f.Cmd}, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (f *Frame) UnmarshalBinary(b []byte) error {
+	if len(b) != 2 {
+		return irtrx.ErrBinary
+	}
+	f.Addr, f.Cmd = b[0], b[1]
+	return nil
+}
@@ -0,0 +1,172 @@
+package cheapo
+
+// Key is a button on the common 24-key LED strip remote. Its value is the
+// command byte sent, see Cmd. The codes run from 0 left to right, top to
+// bottom:
+//
+//	BrightUp  BrightDown Off    On
+//	Red       Green      Blue   White
+//	RedOrange LightGreen Indigo Flash
+//	Orange    Cyan       Purple Strobe
+//	Amber     SkyBlue    Violet Fade
+//	Yellow    Teal       Pink   Smooth
+type Key uint8
+
+const (
+	KeyBrightUp Key = iota
+	KeyBrightDown
+	KeyOff
+	KeyOn
+	KeyRed
+	KeyGreen
+	KeyBlue
+	KeyWhite
+	KeyRedOrange
+	KeyLightGreen
+	KeyIndigo
+	KeyFlash
+	KeyOrange
+	KeyCyan
+	KeyPurple
+	KeyStrobe
+	KeyAmber
+	KeySkyBlue
+	KeyViolet
+	KeyFade
+	KeyYellow
+	KeyTeal
+	KeyPink
+	KeySmooth
+)
+
+var keyNames = [...]string{
+	"BrightUp", "BrightDown", "Off", "On",
+	"Red", "Green", "Blue", "White",
+	"RedOrange", "LightGreen", "Indigo", "Flash",
+	"Orange", "Cyan", "Purple", "Strobe",
+	"Amber", "SkyBlue", "Violet", "Fade",
+	"Yellow", "Teal", "Pink", "Smooth",
+}
+
+func (k Key) String() string {
+	if int(k) < len(keyNames) {
+		return keyNames[k]
+	}
+	return "Unknown"
+}
+
+// KeyOf returns the 24-key remote Key carried by a raw frame.
+func KeyOf(raw uint32) Key {
+	return Key(Cmd(raw))
+}
+
+// Key44 is a button on the common 44-key LED strip remote. Its value is the
+// command byte sent, see Cmd. Unlike the 24-key remote the codes aren't in
+// order, and they overlap with Key, so the two need separate types.
+type Key44 uint8
+
+const (
+	Key44BrightUp   Key44 = 0x5C
+	Key44BrightDown Key44 = 0x5D
+	Key44Play       Key44 = 0x41
+	Key44Power      Key44 = 0x40
+	Key44Red        Key44 = 0x58
+	Key44Green      Key44 = 0x59
+	Key44Blue       Key44 = 0x45
+	Key44White      Key44 = 0x44
+	Key44RedOrange  Key44 = 0x54
+	Key44LightGreen Key44 = 0x55
+	Key44Indigo     Key44 = 0x49
+	Key44WarmWhite  Key44 = 0x48
+	Key44Orange     Key44 = 0x50
+	Key44Cyan       Key44 = 0x51
+	Key44Purple     Key44 = 0x4D
+	Key44Pink       Key44 = 0x4C
+	Key44Amber      Key44 = 0x1C
+	Key44SkyBlue    Key44 = 0x1D
+	Key44Violet     Key44 = 0x1E
+	Key44ColdWhite  Key44 = 0x1F
+	Key44Yellow     Key44 = 0x18
+	Key44Teal       Key44 = 0x19
+	Key44Magenta    Key44 = 0x1A
+	Key44IceBlue    Key44 = 0x1B
+	Key44RedUp      Key44 = 0x14
+	Key44GreenUp    Key44 = 0x15
+	Key44BlueUp     Key44 = 0x16
+	Key44Quick      Key44 = 0x17
+	Key44RedDown    Key44 = 0x10
+	Key44GreenDown  Key44 = 0x11
+	Key44BlueDown   Key44 = 0x12
+	Key44Slow       Key44 = 0x13
+	Key44DIY1       Key44 = 0x0C
+	Key44DIY2       Key44 = 0x0D
+	Key44DIY3       Key44 = 0x0E
+	Key44Auto       Key44 = 0x0F
+	Key44DIY4       Key44 = 0x08
+	Key44DIY5       Key44 = 0x09
+	Key44DIY6       Key44 = 0x0A
+	Key44Flash      Key44 = 0x0B
+	Key44Jump3      Key44 = 0x04
+	Key44Jump7      Key44 = 0x05
+	Key44Fade3      Key44 = 0x06
+	Key44Fade7      Key44 = 0x07
+)
+
+var key44Names = map[Key44]string{
+	Key44BrightUp:   "BrightUp",
+	Key44BrightDown: "BrightDown",
+	Key44Play:       "Play",
+	Key44Power:      "Power",
+	Key44Red:        "Red",
+	Key44Green:      "Green",
+	Key44Blue:       "Blue",
+	Key44White:      "White",
+	Key44RedOrange:  "RedOrange",
+	Key44LightGreen: "LightGreen",
+	Key44Indigo:     "Indigo",
+	Key44WarmWhite:  "WarmWhite",
+	Key44Orange:     "Orange",
+	Key44Cyan:       "Cyan",
+	Key44Purple:     "Purple",
+	Key44Pink:       "Pink",
+	Key44Amber:      "Amber",
+	Key44SkyBlue:    "SkyBlue",
+	Key44Violet:     "Violet",
+	Key44ColdWhite:  "ColdWhite",
+	Key44Yellow:     "Yellow",
+	Key44Teal:       "Teal",
+	Key44Magenta:    "Magenta",
+	Key44IceBlue:    "IceBlue",
+	Key44RedUp:      "RedUp",
+	Key44GreenUp:    "GreenUp",
+	Key44BlueUp:     "BlueUp",
+	Key44Quick:      "Quick",
+	Key44RedDown:    "RedDown",
+	Key44GreenDown:  "GreenDown",
+	Key44BlueDown:   "BlueDown",
+	Key44Slow:       "Slow",
+	Key44DIY1:       "DIY1",
+	Key44DIY2:       "DIY2",
+	Key44DIY3:       "DIY3",
+	Key44Auto:       "Auto",
+	Key44DIY4:       "DIY4",
+	Key44DIY5:       "DIY5",
+	Key44DIY6:       "DIY6",
+	Key44Flash:      "Flash",
+	Key44Jump3:      "Jump3",
+	Key44Jump7:      "Jump7",
+	Key44Fade3:      "Fade3",
+	Key44Fade7:      "Fade7",
+}
+
+func (k Key44) String() string {
+	if name, ok := key44Names[k]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// Key44Of returns the 44-key remote Key44 carried by a raw frame.
+func Key44Of(raw uint32) Key44 {
+	return Key44(Cmd(raw))
+}
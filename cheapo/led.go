@@ -0,0 +1,184 @@
+package cheapo
+
+// Intent is what a key on an LED remote asks the controller to do.
+type Intent uint8
+
+const (
+	IntentNone Intent = iota
+	// IntentColor sets the color given by the Action's R, G and B.
+	IntentColor
+	IntentBrightnessUp
+	IntentBrightnessDown
+	IntentModeNext
+	IntentPowerOn
+	IntentPowerOff
+	IntentPowerToggle
+)
+
+// Action is the Intent bound to a key.
+type Action struct {
+	Intent  Intent
+	R, G, B uint8
+}
+
+// Keymap binds command bytes to Actions.
+type Keymap map[uint8]Action
+
+func color(r, g, b uint8) Action {
+	return Action{Intent: IntentColor, R: r, G: g, B: b}
+}
+
+// Keymap24 is the Keymap for the 24-key remote. The flash, strobe, fade and
+// smooth keys all step through modes.
+var Keymap24 = Keymap{
+	uint8(KeyBrightUp):   {Intent: IntentBrightnessUp},
+	uint8(KeyBrightDown): {Intent: IntentBrightnessDown},
+	uint8(KeyOff):        {Intent: IntentPowerOff},
+	uint8(KeyOn):         {Intent: IntentPowerOn},
+	uint8(KeyRed):        color(255, 0, 0),
+	uint8(KeyGreen):      color(0, 255, 0),
+	uint8(KeyBlue):       color(0, 0, 255),
+	uint8(KeyWhite):      color(255, 255, 255),
+	uint8(KeyRedOrange):  color(255, 64, 0),
+	uint8(KeyLightGreen): color(64, 255, 64),
+	uint8(KeyIndigo):     color(32, 0, 255),
+	uint8(KeyFlash):      {Intent: IntentModeNext},
+	uint8(KeyOrange):     color(255, 128, 0),
+	uint8(KeyCyan):       color(0, 255, 255),
+	uint8(KeyPurple):     color(128, 0, 255),
+	uint8(KeyStrobe):     {Intent: IntentModeNext},
+	uint8(KeyAmber):      color(255, 176, 0),
+	uint8(KeySkyBlue):    color(0, 160, 255),
+	uint8(KeyViolet):     color(176, 0, 255),
+	uint8(KeyFade):       {Intent: IntentModeNext},
+	uint8(KeyYellow):     color(255, 255, 0),
+	uint8(KeyTeal):       color(0, 128, 128),
+	uint8(KeyPink):       color(255, 0, 128),
+	uint8(KeySmooth):     {Intent: IntentModeNext},
+}
+
+// Keymap44 is the Keymap for the 44-key remote. Play steps through modes;
+// the per-channel, speed and DIY keys are left unbound.
+var Keymap44 = Keymap{
+	uint8(Key44BrightUp):   {Intent: IntentBrightnessUp},
+	uint8(Key44BrightDown): {Intent: IntentBrightnessDown},
+	uint8(Key44Play):       {Intent: IntentModeNext},
+	uint8(Key44Power):      {Intent: IntentPowerToggle},
+	uint8(Key44Red):        color(255, 0, 0),
+	uint8(Key44Green):      color(0, 255, 0),
+	uint8(Key44Blue):       color(0, 0, 255),
+	uint8(Key44White):      color(255, 255, 255),
+	uint8(Key44RedOrange):  color(255, 64, 0),
+	uint8(Key44LightGreen): color(64, 255, 64),
+	uint8(Key44Indigo):     color(32, 0, 255),
+	uint8(Key44WarmWhite):  color(255, 214, 170),
+	uint8(Key44Orange):     color(255, 128, 0),
+	uint8(Key44Cyan):       color(0, 255, 255),
+	uint8(Key44Purple):     color(128, 0, 255),
+	uint8(Key44Pink):       color(255, 96, 160),
+	uint8(Key44Amber):      color(255, 176, 0),
+	uint8(Key44SkyBlue):    color(0, 160, 255),
+	uint8(Key44Violet):     color(176, 0, 255),
+	uint8(Key44ColdWhite):  color(200, 220, 255),
+	uint8(Key44Yellow):     color(255, 255, 0),
+	uint8(Key44Teal):       color(0, 128, 128),
+	uint8(Key44Magenta):    color(255, 0, 255),
+	uint8(Key44IceBlue):    color(160, 220, 255),
+	uint8(Key44Fade3):      {Intent: IntentModeNext},
+	uint8(Key44Fade7):      {Intent: IntentModeNext},
+	uint8(Key44Jump3):      {Intent: IntentModeNext},
+	uint8(Key44Jump7):      {Intent: IntentModeNext},
+	uint8(Key44Flash):      {Intent: IntentModeNext},
+	uint8(Key44Auto):       {Intent: IntentModeNext},
+}
+
+// LEDAdapter turns keys from an LED remote into what LED strip firmware
+// cares about. Set the callbacks for the intents you handle; the others are
+// ignored. Holding a brightness key repeats it, so brightness ramps the way
+// it does on the stock controllers.
+//
+//	a := cheapo.NewLEDAdapter(cheapo.NewStateMachine(nil), cheapo.Keymap24)
+//	a.SetColor = strip.SetColor
+//	rx := irtrx.NewRxDevice(rxPin, a.StateMachine())
+//	rx.StartInverted()
+//
+// The callbacks are called from the interrupt handler.
+type LEDAdapter struct {
+	Keymap Keymap
+
+	SetColor       func(r, g, b uint8)
+	BrightnessUp   func()
+	BrightnessDown func()
+	ModeNext       func()
+	// Power is called with the new power state.
+	Power func(on bool)
+
+	sm *StateMachine
+	on bool
+}
+
+// NewLEDAdapter returns an LEDAdapter for sm using keymap. It takes over
+// sm's CmdHandler and RepeatHandler.
+func NewLEDAdapter(sm *StateMachine, keymap Keymap) *LEDAdapter {
+	a := &LEDAdapter{Keymap: keymap, sm: sm, on: true}
+	sm.CmdHandler = a.HandleCmd
+	sm.RepeatHandler = a.HandleRepeat
+	return a
+}
+
+// StateMachine returns the StateMachine the LEDAdapter is attached to.
+func (a *LEDAdapter) StateMachine() *StateMachine {
+	return a.sm
+}
+
+// On reports the power state as last set by the remote.
+func (a *LEDAdapter) On() bool {
+	return a.on
+}
+
+// HandleCmd acts on the key in a raw frame.
+func (a *LEDAdapter) HandleCmd(raw uint32) {
+	act, ok := a.Keymap[Cmd(raw)]
+	if !ok {
+		return
+	}
+	switch act.Intent {
+	case IntentColor:
+		if a.SetColor != nil {
+			a.SetColor(act.R, act.G, act.B)
+		}
+	case IntentBrightnessUp:
+		if a.BrightnessUp != nil {
+			a.BrightnessUp()
+		}
+	case IntentBrightnessDown:
+		if a.BrightnessDown != nil {
+			a.BrightnessDown()
+		}
+	case IntentModeNext:
+		if a.ModeNext != nil {
+			a.ModeNext()
+		}
+	case IntentPowerOn:
+		a.setPower(true)
+	case IntentPowerOff:
+		a.setPower(false)
+	case IntentPowerToggle:
+		a.setPower(!a.on)
+	}
+}
+
+// HandleRepeat repeats held brightness keys; other keys aren't repeated.
+func (a *LEDAdapter) HandleRepeat(raw uint32, n int) {
+	switch a.Keymap[Cmd(raw)].Intent {
+	case IntentBrightnessUp, IntentBrightnessDown:
+		a.HandleCmd(raw)
+	}
+}
+
+func (a *LEDAdapter) setPower(on bool) {
+	a.on = on
+	if a.Power != nil {
+		a.Power(on)
+	}
+}
@@ -0,0 +1,33 @@
+package irtrx
+
+import "time"
+
+// Clock is the source of time for timeouts, repeat windows and failsafes.
+// Decoders use RealClock unless given another with their SetClock method,
+// which lets tests step time forward deterministically; see irtest.Clock.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time        { return time.Now() }
+func (realClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// RealClock is the system clock.
+var RealClock Clock = realClock{}
+
+// After is time.After on c: it returns a channel that receives c's time
+// once c has slept for d.
+func After(c Clock, d time.Duration) <-chan time.Time {
+	if c == RealClock {
+		return time.After(d)
+	}
+	ch := make(chan time.Time, 1)
+	go func() {
+		c.Sleep(d)
+		ch <- c.Now()
+	}()
+	return ch
+}
@@ -0,0 +1,99 @@
+// irbridge is reference firmware that turns a board into an IR transceiver
+// dongle for desktop software, speaking the framed protocol of package
+// dongle over the serial console: send messages from the host are
+// transmitted and answered with an ack or an error, and every code received
+// is sent up raw.
+//
+//	tinygo flash -target pico ./cmd/irbridge
+//
+// Connect a demodulating receiver's output to rxPin and an IR LED to txPin;
+// see pin_tinygo.go.
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sparques/irtrx"
+	_ "github.com/sparques/irtrx/cheapo"
+	_ "github.com/sparques/irtrx/codec"
+	"github.com/sparques/irtrx/dongle"
+	_ "github.com/sparques/irtrx/hexbug"
+	_ "github.com/sparques/irtrx/samsung"
+)
+
+// maxPairs is the longest code that can be received.
+const maxPairs = 160
+
+// capture is a copy of a Recording, so it can be passed out of the interrupt
+// handler without allocating.
+type capture struct {
+	n     int
+	pairs [maxPairs]irtrx.TimePair
+}
+
+var captures = make(chan capture, 2)
+
+// writes to the link come from both the receive and the send side
+var linkMu sync.Mutex
+
+func write(m dongle.Message) {
+	linkMu.Lock()
+	dongle.WriteMessage(link, m)
+	linkMu.Unlock()
+}
+
+func main() {
+	rec := irtrx.NewRecorder(maxPairs, func(r irtrx.Recording) {
+		var c capture
+		c.n = copy(c.pairs[:], r.Pairs)
+		select {
+		case captures <- c:
+		default:
+		}
+	})
+	rx := irtrx.NewRxDevice(rxPin, rec)
+	rx.StartInverted()
+	tx := irtrx.NewTxDevice(txPin)
+
+	go forward(rx, rec)
+
+	rd := dongle.NewReader(link)
+	for {
+		m, err := rd.ReadMessage()
+		switch err {
+		case nil:
+		case dongle.ErrChecksum, dongle.ErrTooLong:
+			write(dongle.Error(err))
+			continue
+		default:
+			return
+		}
+		fm, err := m.Frame()
+		if err != nil {
+			write(dongle.Error(err))
+			continue
+		}
+		rx.Mute()
+		tx.SendFrame(fm)
+		rx.Unmute(10 * time.Millisecond)
+		write(dongle.Ack())
+	}
+}
+
+// forward sends captures up the link. The final pair of a code is only
+// seen at the next edge, so once the line has been quiet for a while, the
+// RxDevice is flushed, passing that pair to the Recorder and ending its
+// capture with the interrupt held off.
+func forward(rx *irtrx.RxDevice, rec *irtrx.Recorder) {
+	for {
+		select {
+		case c := <-captures:
+			write(dongle.Received(irtrx.Recording{Freq: rec.Freq, Pairs: c.pairs[:c.n]}))
+		case <-time.After(50 * time.Millisecond):
+			if time.Since(rx.LastActivity()) > 2*rec.Gap {
+				rx.Flush()
+			}
+		}
+	}
+}
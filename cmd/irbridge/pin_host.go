@@ -0,0 +1,22 @@
+//go:build !tinygo
+
+package main
+
+import (
+	"bufio"
+	"os"
+
+	"github.com/sparques/irtrx/internal/hal"
+)
+
+// rxPin and txPin are simulated pins off-device, where the link is stdin
+// and stdout; nothing is received unless something drives rxPin.
+const (
+	rxPin hal.Pin = 15
+	txPin hal.Pin = 16
+)
+
+var link = struct {
+	*bufio.Reader
+	*os.File
+}{bufio.NewReader(os.Stdin), os.Stdout}
@@ -0,0 +1,31 @@
+//go:build tinygo
+
+package main
+
+import (
+	"machine"
+	"time"
+)
+
+// rxPin is the pin the receiver's output is connected to and txPin, which
+// must be PWM capable, drives the IR LED. Change them to suit your board.
+const (
+	rxPin = machine.GP15
+	txPin = machine.GP16
+)
+
+// serial is the USB serial console, with a ReadByte that waits for data.
+type serial struct{}
+
+func (serial) ReadByte() (byte, error) {
+	for machine.Serial.Buffered() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	return machine.Serial.ReadByte()
+}
+
+func (serial) Write(b []byte) (int, error) {
+	return machine.Serial.Write(b)
+}
+
+var link serial
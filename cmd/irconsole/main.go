@@ -0,0 +1,25 @@
+// irconsole is reference firmware for the desk end of an IR serial console:
+// it receives the log lines a robot writes to a console.Logger and forwards
+// them to the serial console, so they show up in any terminal.
+//
+//	tinygo flash -target pico -monitor ./cmd/irconsole
+//
+// Connect a demodulating receiver's output to rxPin; see pin_tinygo.go.
+package main
+
+import (
+	"os"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/console"
+)
+
+func main() {
+	r := console.NewReceiver()
+	rx := irtrx.NewRxDevice(rxPin, r)
+	rx.StartInverted()
+	println("irconsole: listening")
+	if err := r.Forward(os.Stdout); err != nil {
+		println("irconsole:", err.Error())
+	}
+}
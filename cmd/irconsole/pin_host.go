@@ -0,0 +1,9 @@
+//go:build !tinygo
+
+package main
+
+import "github.com/sparques/irtrx/internal/hal"
+
+// rxPin is a simulated pin off-device, where nothing is received unless
+// another simulated pin is connected to it; see irtest.Connect.
+const rxPin hal.Pin = 15
@@ -0,0 +1,9 @@
+//go:build tinygo
+
+package main
+
+import "machine"
+
+// rxPin is the pin the receiver's output is connected to. Change it to
+// suit your board.
+const rxPin = machine.GP15
@@ -0,0 +1,66 @@
+// irdbgen turns an IRDB CSV file into a Go table of named commands.
+//
+//	irdbgen -pkg mytv -var TV -o tv_codes.go Samsung/TV/7,7.csv
+//
+// or, from a go:generate directive,
+//
+//	//go:generate go run github.com/sparques/irtrx/cmd/irdbgen -pkg mytv -var TV -o tv_codes.go 7,7.csv
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/sparques/irtrx/irdb"
+)
+
+func main() {
+	pkg := flag.String("pkg", "main", "package of the generated file")
+	name := flag.String("var", "Commands", "name of the generated variable")
+	out := flag.String("o", "", "output file (default stdout)")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: irdbgen [flags] file.csv")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	if err := run(flag.Arg(0), *pkg, *name, *out); err != nil {
+		fmt.Fprintln(os.Stderr, "irdbgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(in, pkg, name, out string) error {
+	f, err := os.Open(in)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	cmds, err := irdb.Parse(f)
+	if err != nil {
+		return err
+	}
+	for _, c := range cmds {
+		if !irdb.Supported(c.Protocol) {
+			fmt.Fprintf(os.Stderr, "irdbgen: %s: unsupported protocol %s\n", c.Name, c.Protocol)
+		}
+	}
+	for _, c := range irdb.Duplicates(cmds) {
+		fmt.Fprintf(os.Stderr, "irdbgen: %s: duplicate name, keeping the first\n", c.Name)
+	}
+
+	var w io.Writer = os.Stdout
+	if out != "" {
+		o, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer o.Close()
+		w = o
+	}
+	return irdb.Generate(w, pkg, name, filepath.Base(in), cmds)
+}
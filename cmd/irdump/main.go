@@ -0,0 +1,152 @@
+// irdump is reference firmware for checking an IR receiver and identifying
+// remotes. Every decoder in irtrx listens at once; each frame decoded is
+// printed to the serial console with its protocol and bits, followed by the
+// raw timings it was decoded from. Signals no decoder recognises are still
+// dumped raw.
+//
+//	tinygo flash -target pico -monitor ./cmd/irdump
+//
+// Connect a demodulating receiver's output to rxPin; see pin_tinygo.go.
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/cheapo"
+	"github.com/sparques/irtrx/codec"
+	"github.com/sparques/irtrx/hexbug"
+	"github.com/sparques/irtrx/samsung"
+	"github.com/sparques/irtrx/samsungac"
+)
+
+// maxPairs is the longest capture dumped; Samsung AC frames are the longest
+// supported at 117 pairs.
+const maxPairs = 160
+
+// capture is a copy of a Recording, so it can be passed out of the interrupt
+// handler without allocating.
+type capture struct {
+	n     int
+	pairs [maxPairs]irtrx.TimePair
+}
+
+// Decoders run in interrupt context, so they just post what they decode to
+// these channels, dropping it if the main loop is behind.
+var (
+	hexbugs    = make(chan hexbug.Cmd, 4)
+	samsungs   = make(chan samsung.Frame, 4)
+	samsung48s = make(chan samsung.ExtFrame, 4)
+	cheapos    = make(chan uint32, 4)
+	necs       = make(chan uint64, 4)
+	acs        = make(chan samsungac.State, 2)
+	captures   = make(chan capture, 2)
+)
+
+func post[T any](ch chan T) func(T) {
+	return func(v T) {
+		select {
+		case ch <- v:
+		default:
+		}
+	}
+}
+
+// spaceMark turns the mark-space pairs of StartInverted into the space-mark
+// pairs expected by decoders written for Start, such as hexbug's.
+type spaceMark struct {
+	sm    irtrx.RxStateMachine
+	space time.Duration
+}
+
+func (s *spaceMark) HandleTimePair(p irtrx.TimePair) {
+	s.sm.HandleTimePair(irtrx.TimePair{s.space, p[0]})
+	s.space = p[1]
+}
+
+func main() {
+	ssm := samsung.NewStateMachine(post(samsungs))
+	ssm.Ext48Handler = post(samsung48s)
+	ac := samsungac.NewStateMachine(nil)
+	ac.StateHandler = post(acs)
+	rec := irtrx.NewRecorder(maxPairs, func(r irtrx.Recording) {
+		var c capture
+		c.n = copy(c.pairs[:], r.Pairs)
+		post(captures)(c)
+	})
+
+	rx := irtrx.NewRxDevice(rxPin, irtrx.MultiRxStateMachine(
+		&spaceMark{sm: hexbug.NewStateMachine(post(hexbugs))},
+		ssm,
+		cheapo.NewStateMachine(post(cheapos)),
+		codec.NewDecoder(&codec.NEC, post(necs)),
+		ac,
+		rec,
+	))
+	rx.StartInverted()
+	// deliver 32 bit samsung frames, and captures, without waiting for the
+	// next signal; no decoder here has a longer space within a frame
+	rx.SetAutoFlush(rec.Gap)
+	println("irdump: listening")
+
+	decoded := false
+	for {
+		var f irtrx.Frame
+		select {
+		case v := <-hexbugs:
+			f = v
+		case v := <-samsungs:
+			f = v
+		case v := <-samsung48s:
+			f = v
+		case raw := <-cheapos:
+			f = cheapo.Frame{Addr: cheapo.Addr(raw), Cmd: cheapo.Cmd(raw)}
+		case v := <-necs:
+			f = codec.NEC.Code(v)
+		case st := <-acs:
+			printFrame(st)
+			if s, err := st.Settings(); err != nil {
+				fmt.Printf("%-10s %v\n", "", err)
+			} else {
+				fmt.Printf("%-10s %v\n", "", s)
+			}
+			decoded = true
+			continue
+		case c := <-captures:
+			if !decoded {
+				fmt.Printf("%-10s\n", "unknown")
+			}
+			printCapture(c.pairs[:c.n])
+			decoded = false
+			continue
+		}
+		printFrame(f)
+		decoded = true
+	}
+}
+
+func printFrame(f irtrx.Frame) {
+	fmt.Printf("%-10s %v  bits %x\n", f.Protocol(), f, f.Bits())
+}
+
+// printCapture prints the pair count, total length and header of pairs,
+// then every mark (+) and space (-) in microseconds.
+func printCapture(pairs []irtrx.TimePair) {
+	var total time.Duration
+	for _, p := range pairs {
+		total += p[0] + p[1]
+	}
+	fmt.Printf("%-10s %d pairs, %v, header +%d -%d\n", "raw", len(pairs), total,
+		pairs[0][0].Microseconds(), pairs[0][1].Microseconds())
+	for i, p := range pairs {
+		if i%8 == 0 {
+			if i != 0 {
+				fmt.Println()
+			}
+			fmt.Printf("%-10s", "")
+		}
+		fmt.Printf(" +%d -%d", p[0].Microseconds(), p[1].Microseconds())
+	}
+	fmt.Println()
+}
@@ -0,0 +1,10 @@
+//go:build !tinygo
+
+package main
+
+import "github.com/sparques/irtrx/internal/hal"
+
+// rxPin is a simulated pin off-device, where nothing is ever received; the
+// host build only exists to keep irdump compiling with the rest of the
+// module.
+const rxPin hal.Pin = 15
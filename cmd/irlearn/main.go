@@ -0,0 +1,110 @@
+// irlearn is reference firmware for learning remotes over the serial
+// console. Give a code a name, point the remote at the receiver and press
+// the button; the capture is identified if it is of a known protocol,
+// stored, and printed as a Go literal to paste into your own firmware.
+//
+//	learn power   capture the next code received and store it as power
+//	send power    transmit a learned code
+//	list          list the learned codes
+//	dump          print every learned code as JSON
+//
+// Codes are kept in RAM, so dump them before resetting the board.
+//
+//	tinygo flash -target pico -monitor ./cmd/irlearn
+//
+// Connect a demodulating receiver's output to rxPin and an IR LED to txPin;
+// see pin_tinygo.go.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/universal"
+)
+
+var errCommand = errors.New("unknown command; try learn, send, list or dump")
+
+func main() {
+	r := universal.NewRemote(irtrx.NewTxDevice(txPin), universal.NewMemStore())
+	rx := irtrx.NewRxDevice(rxPin, r)
+	rx.StartInverted()
+	r.Muter = rx
+	r.Flusher = rx
+	println("irlearn: ready")
+	for {
+		line, err := readLine()
+		if err != nil {
+			println("irlearn:", err.Error())
+			return
+		}
+		if err := run(r, strings.Fields(line)); err != nil {
+			fmt.Println("error:", err)
+		}
+	}
+}
+
+func run(r *universal.Remote, args []string) error {
+	switch {
+	case len(args) == 2 && args[0] == "learn":
+		fmt.Printf("learning %s: press the button on the remote\n", args[1])
+		code, err := r.Learn(args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("// %s: %v\n", code.Name, code)
+		fmt.Printf("var %s = %#v\n", code.Name, code.Recording)
+		return nil
+	case len(args) == 2 && args[0] == "send":
+		if err := r.Send(args[1]); err != nil {
+			return err
+		}
+		fmt.Println("ok")
+		return nil
+	case len(args) == 1 && args[0] == "list":
+		codes, err := codes(r)
+		if err != nil {
+			return err
+		}
+		for _, code := range codes {
+			fmt.Printf("%-16s %v\n", code.Name, code)
+		}
+		return nil
+	case len(args) == 1 && args[0] == "dump":
+		codes, err := codes(r)
+		if err != nil {
+			return err
+		}
+		all := make(map[string]irtrx.Recording, len(codes))
+		for _, code := range codes {
+			all[code.Name] = code.Recording
+		}
+		b, err := json.Marshal(all)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+	return errCommand
+}
+
+// codes returns every learned code.
+func codes(r *universal.Remote) ([]*universal.Code, error) {
+	names, err := r.Names()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*universal.Code, 0, len(names))
+	for _, name := range names {
+		code, err := r.Code(name)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, code)
+	}
+	return out, nil
+}
@@ -0,0 +1,31 @@
+//go:build !tinygo
+
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+
+	"github.com/sparques/irtrx/internal/hal"
+)
+
+// rxPin and txPin are simulated pins off-device, where commands are read
+// from stdin; nothing is received unless something drives rxPin.
+const (
+	rxPin hal.Pin = 15
+	txPin hal.Pin = 16
+)
+
+var stdin = bufio.NewScanner(os.Stdin)
+
+// readLine returns the next line of stdin.
+func readLine() (string, error) {
+	if !stdin.Scan() {
+		if err := stdin.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return stdin.Text(), nil
+}
@@ -0,0 +1,40 @@
+//go:build tinygo
+
+package main
+
+import (
+	"machine"
+	"time"
+)
+
+// rxPin is the pin the receiver's output is connected to and txPin, which
+// must be PWM capable, drives the IR LED. Change them to suit your board.
+const (
+	rxPin = machine.GP15
+	txPin = machine.GP16
+)
+
+var line []byte
+
+// readLine returns the next line typed on the serial console.
+func readLine() (string, error) {
+	line = line[:0]
+	for {
+		if machine.Serial.Buffered() == 0 {
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+		b, err := machine.Serial.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		switch b {
+		case '\r', '\n':
+			if len(line) != 0 {
+				return string(line), nil
+			}
+		default:
+			line = append(line, b)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// irsend is reference firmware that turns a board into an IR blaster
+// scripted over its serial console. It reads one command per line and
+// answers each with "ok" or "error: ..." :
+//
+//	send nec 0x04 0x08        send a frame of a registered protocol
+//	hold 500 samsung 7 0x07   send a frame, repeating it for 500ms
+//	pronto 0000 006D 0022 ... send a Pronto Hex code
+//	0000 006D 0022 ...        the same, for pasting codes as they come
+//	list                      print the registered protocols
+//
+// Numbers are decimal, or hex with a 0x prefix. Protocols are those of the
+// packages imported below; see irtrx.RegisterProtocol.
+//
+//	tinygo flash -target pico -monitor ./cmd/irsend
+//
+// Connect an IR LED, through a suitable resistor or driver, to txPin; see
+// pin_tinygo.go.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sparques/irtrx"
+	_ "github.com/sparques/irtrx/cheapo"
+	_ "github.com/sparques/irtrx/codec"
+	_ "github.com/sparques/irtrx/hexbug"
+	"github.com/sparques/irtrx/pronto"
+	_ "github.com/sparques/irtrx/samsung"
+)
+
+var errCommand = errors.New("unknown command; try list")
+
+func main() {
+	tx := irtrx.NewTxDevice(txPin)
+	println("irsend: ready")
+	for {
+		line, err := readLine()
+		if err != nil {
+			println("irsend:", err.Error())
+			return
+		}
+		if err := run(tx, line); err != nil {
+			fmt.Println("error:", err)
+			continue
+		}
+		fmt.Println("ok")
+	}
+}
+
+func run(tx *irtrx.TxDevice, line string) error {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return errCommand
+	}
+	switch fields[0] {
+	case "send":
+		if len(fields) < 2 {
+			return irtrx.ErrArgs
+		}
+		fm, err := encode(fields[1], fields[2:])
+		if err != nil {
+			return err
+		}
+		tx.SendFrame(fm)
+	case "hold":
+		if len(fields) < 3 {
+			return irtrx.ErrArgs
+		}
+		ms, err := strconv.ParseUint(fields[1], 0, 32)
+		if err != nil {
+			return err
+		}
+		fm, err := encode(fields[2], fields[3:])
+		if err != nil {
+			return err
+		}
+		tx.Hold(fm, time.Duration(ms)*time.Millisecond)
+	case "pronto":
+		return sendPronto(tx, strings.Join(fields[1:], " "))
+	case "0000":
+		return sendPronto(tx, line)
+	case "list":
+		for _, p := range irtrx.Protocols() {
+			fmt.Printf("%-10s %s\n", p.Name, p.Usage)
+		}
+	default:
+		return errCommand
+	}
+	return nil
+}
+
+func encode(protocol string, fields []string) (irtrx.FrameMarshaller, error) {
+	args := make([]uint64, len(fields))
+	for i, f := range fields {
+		v, err := strconv.ParseUint(f, 0, 64)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return irtrx.Encode(protocol, args...)
+}
+
+func sendPronto(tx *irtrx.TxDevice, s string) error {
+	c, err := pronto.Parse(s)
+	if err != nil {
+		return err
+	}
+	tx.SendFrame(&c)
+	return nil
+}
@@ -0,0 +1,28 @@
+//go:build !tinygo
+
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+
+	"github.com/sparques/irtrx/internal/hal"
+)
+
+// txPin is a simulated pin off-device, where commands are read from stdin
+// and nothing is emitted; the host build is handy for checking commands.
+const txPin hal.Pin = 16
+
+var stdin = bufio.NewScanner(os.Stdin)
+
+// readLine returns the next line of stdin.
+func readLine() (string, error) {
+	if !stdin.Scan() {
+		if err := stdin.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return stdin.Text(), nil
+}
@@ -0,0 +1,37 @@
+//go:build tinygo
+
+package main
+
+import (
+	"machine"
+	"time"
+)
+
+// txPin drives the IR LED. It must be PWM capable; change it to suit your
+// board.
+const txPin = machine.GP16
+
+var line []byte
+
+// readLine returns the next line typed on the serial console.
+func readLine() (string, error) {
+	line = line[:0]
+	for {
+		if machine.Serial.Buffered() == 0 {
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+		b, err := machine.Serial.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		switch b {
+		case '\r', '\n':
+			if len(line) != 0 {
+				return string(line), nil
+			}
+		default:
+			line = append(line, b)
+		}
+	}
+}
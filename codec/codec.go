@@ -0,0 +1,280 @@
+// codec builds encoders and decoders for the many IR protocols that differ
+// only in their timings and bit counts: a header, a fixed number of bits each
+// told apart by the length of their mark or space, and a trailing mark. NEC,
+// Samsung and most no-name remotes are of this kind, as is anything
+// described by a SPACE_ENC or PULSE_ENC lircd.conf.
+//
+// Decoding requires StartInverted() and not Start().
+//
+// NEC is registered with irtrx as "nec". Encoding it by name with
+// irtrx.Encode, an 8 bit addr is followed by its inverse, as standard NEC
+// addresses are.
+package codec
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sparques/irtrx"
+)
+
+// ErrNoFrame is returned by Code.UnmarshalTimePairs when pairs don't contain
+// a frame of the Spec.
+var ErrNoFrame = errors.New("codec: no frame found")
+
+// MaxBits is the longest frame a Spec can describe.
+const MaxBits = 64
+
+// DefaultTolerance is the Tolerance used when a Spec doesn't set one.
+const DefaultTolerance = 25
+
+// minTolerance is always allowed, however short the expected duration, to
+// cover receiver jitter.
+const minTolerance = 100 * time.Microsecond
+
+// Spec describes a protocol. All TimePairs are {mark, space}; a zero
+// TimePair or duration means the part is absent.
+type Spec struct {
+	// Name identifies the protocol, e.g. "nec"; see Code.Protocol.
+	Name string
+	// Freq is the carrier frequency in Hz; 0 means the default 38kHz.
+	Freq uint32
+	// Header starts a frame.
+	Header irtrx.TimePair
+	// One and Zero encode a bit.
+	One, Zero irtrx.TimePair
+	// Trailer is the mark ending a frame, followed by Gap.
+	Trailer time.Duration
+	Gap     time.Duration
+	// Bits is the number of bits in a frame, at most MaxBits.
+	Bits int
+	// MSBFirst sends the most significant bit first.
+	MSBFirst bool
+	// Repeat, followed by Trailer, is sent every RepeatPeriod while a
+	// button is held. If it is zero the whole frame is repeated instead.
+	Repeat       irtrx.TimePair
+	RepeatPeriod time.Duration
+	// Tolerance is how far, in percent, a received duration may be from
+	// the expected one.
+	Tolerance int
+}
+
+// Encode returns the pairs sent for v.
+func (s *Spec) Encode(v uint64) []irtrx.TimePair {
+	out := make([]irtrx.TimePair, 0, s.Bits+2)
+	if s.Header != (irtrx.TimePair{}) {
+		out = append(out, s.Header)
+	}
+	for i := 0; i < s.Bits; i++ {
+		bit := i
+		if s.MSBFirst {
+			bit = s.Bits - 1 - i
+		}
+		if (v>>bit)&1 == 1 {
+			out = append(out, s.One)
+		} else {
+			out = append(out, s.Zero)
+		}
+	}
+	return s.trail(out)
+}
+
+// trail ends a frame: the trailing mark and gap, or, without a trailer, the
+// gap stretching the last space.
+func (s *Spec) trail(out []irtrx.TimePair) []irtrx.TimePair {
+	switch {
+	case s.Trailer != 0:
+		out = append(out, irtrx.TimePair{s.Trailer, s.Gap})
+	case len(out) > 0 && s.Gap > out[len(out)-1][1]:
+		out[len(out)-1][1] = s.Gap
+	}
+	return out
+}
+
+// Code returns a FrameMarshaller sending v.
+func (s *Spec) Code(v uint64) *Code {
+	return &Code{Spec: s, Value: v}
+}
+
+// Code is a value of a Spec, ready to send. It implements
+// irtrx.RepeatMarshaller and irtrx.CarrierHinter.
+type Code struct {
+	Spec  *Spec
+	Value uint64
+}
+
+// MarshalFrame implements irtrx.FrameMarshaller.
+func (c *Code) MarshalFrame() []irtrx.TimePair {
+	return c.Spec.Encode(c.Value)
+}
+
+// MarshalRepeat implements irtrx.RepeatMarshaller.
+func (c *Code) MarshalRepeat() []irtrx.TimePair {
+	if c.Spec.Repeat == (irtrx.TimePair{}) {
+		return c.MarshalFrame()
+	}
+	return c.Spec.trail([]irtrx.TimePair{c.Spec.Repeat})
+}
+
+// RepeatPeriod implements irtrx.RepeatMarshaller. Without a RepeatPeriod in
+// the Spec, frames follow each other after Gap.
+func (c *Code) RepeatPeriod() time.Duration {
+	if c.Spec.RepeatPeriod != 0 {
+		return c.Spec.RepeatPeriod
+	}
+	var d time.Duration
+	for _, p := range c.MarshalFrame() {
+		d += p[0] + p[1]
+	}
+	return d
+}
+
+// UnmarshalTimePairs implements irtrx.FrameUnmarshaller, setting Value from
+// the first frame of Spec in pairs. Spec must be set.
+func (c *Code) UnmarshalTimePairs(pairs []irtrx.TimePair) error {
+	var got bool
+	d := NewDecoder(c.Spec, func(v uint64) {
+		c.Value, got = v, true
+	})
+	for _, p := range pairs {
+		d.HandleTimePair(p)
+		if got {
+			return nil
+		}
+	}
+	return ErrNoFrame
+}
+
+// Protocol implements irtrx.Frame, returning the Spec's Name or "codec" if
+// it has none.
+func (c *Code) Protocol() string {
+	if c.Spec.Name == "" {
+		return "codec"
+	}
+	return c.Spec.Name
+}
+
+// Bits implements irtrx.Frame, returning the Spec's Bits bits of Value in
+// the order they are sent.
+func (c *Code) Bits() []byte {
+	b := make([]byte, (c.Spec.Bits+7)/8)
+	for i := 0; i < c.Spec.Bits; i++ {
+		bit := i
+		if c.Spec.MSBFirst {
+			bit = c.Spec.Bits - 1 - i
+		}
+		if c.Value>>bit&1 == 1 {
+			b[i/8] |= 1 << (i % 8)
+		}
+	}
+	return b
+}
+
+func (c *Code) String() string {
+	return fmt.Sprintf("%s %#x", c.Protocol(), c.Value)
+}
+
+// Carrier implements irtrx.CarrierHinter.
+func (c *Code) Carrier() uint32 {
+	return c.Spec.Freq
+}
+
+// Within reports whether got is within tolerance percent of want.
+func Within(got, want time.Duration, tolerance int) bool {
+	diff := got - want
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= minTolerance || diff <= want*time.Duration(tolerance)/100
+}
+
+// Decoder implements irtrx.RxStateMachine for a Spec.
+type Decoder struct {
+	// Handler is called, from interrupt context, with the value of every
+	// frame received.
+	Handler func(uint64)
+	// RepeatHandler, if set, is called for every repeat burst.
+	RepeatHandler func()
+
+	spec     *Spec
+	buf      uint64
+	bitcount int
+	inFrame  bool
+}
+
+// NewDecoder returns a Decoder for s calling handler with each value
+// received.
+func NewDecoder(s *Spec, handler func(uint64)) *Decoder {
+	return &Decoder{Handler: handler, spec: s}
+}
+
+func (d *Decoder) tolerance() int {
+	if d.spec.Tolerance == 0 {
+		return DefaultTolerance
+	}
+	return d.spec.Tolerance
+}
+
+func (d *Decoder) match(got, want irtrx.TimePair) bool {
+	tol := d.tolerance()
+	return Within(got[0], want[0], tol) && Within(got[1], want[1], tol)
+}
+
+// HandleTimePair implements irtrx.RxStateMachine.
+func (d *Decoder) HandleTimePair(pair irtrx.TimePair) {
+	s := d.spec
+	noHeader := s.Header == (irtrx.TimePair{})
+	switch {
+	case !noHeader && d.match(pair, s.Header):
+		d.buf, d.bitcount, d.inFrame = 0, 0, true
+		return
+	case s.Repeat != (irtrx.TimePair{}) && d.match(pair, s.Repeat):
+		d.reset()
+		if d.RepeatHandler != nil {
+			d.RepeatHandler()
+		}
+		return
+	case !d.inFrame && noHeader:
+		d.buf, d.bitcount, d.inFrame = 0, 0, true
+	case !d.inFrame:
+		return
+	}
+
+	var one bool
+	tol := d.tolerance()
+	last := d.bitcount == s.Bits-1
+	switch {
+	case d.match(pair, s.One):
+		one = true
+	case d.match(pair, s.Zero):
+	// without a trailer the last bit's space runs into the gap, so only
+	// its mark can tell it apart
+	case last && s.Trailer == 0 && s.One[0] != s.Zero[0] && Within(pair[0], s.One[0], tol):
+		one = true
+	case last && s.Trailer == 0 && Within(pair[0], s.Zero[0], tol):
+	default:
+		d.reset()
+		return
+	}
+	if one {
+		if s.MSBFirst {
+			d.buf |= 1 << (s.Bits - 1 - d.bitcount)
+		} else {
+			d.buf |= 1 << d.bitcount
+		}
+	}
+	d.bitcount++
+	if d.bitcount < s.Bits {
+		return
+	}
+	v := d.buf
+	d.reset()
+	if d.Handler != nil {
+		d.Handler(v)
+	}
+}
+
+func (d *Decoder) reset() {
+	d.buf, d.bitcount, d.inFrame = 0, 0, false
+}
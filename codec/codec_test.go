@@ -0,0 +1,225 @@
+package codec_test
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/codec"
+	"github.com/sparques/irtrx/irtest"
+)
+
+// nec round trips codec.NEC codes of every address and command.
+var nec = irtest.Protocol[uint64]{
+	Name: "nec",
+	Random: func(r *rand.Rand) uint64 {
+		return codec.NECValue(uint16(r.Uint32()), uint8(r.Uint32()))
+	},
+	Marshal: func(v uint64) irtrx.FrameMarshaller { return codec.NEC.Code(v) },
+	NewDecoder: func(h func(uint64)) irtrx.RxStateMachine {
+		return codec.NewDecoder(&codec.NEC, h)
+	},
+	Inverted: true,
+}
+
+// necPairs returns the NEC header, a pair for each of bits, '0' or '1', and
+// the trailer.
+func necPairs(bits string) []irtrx.TimePair {
+	out := []irtrx.TimePair{codec.NEC.Header}
+	for _, b := range bits {
+		if b == '1' {
+			out = append(out, codec.NEC.One)
+		} else {
+			out = append(out, codec.NEC.Zero)
+		}
+	}
+	return append(out, irtrx.TimePair{codec.NEC.Trailer, codec.NEC.Gap})
+}
+
+// power is address 0x00 and command 0x45, each followed by its inverse,
+// LSB first: the power button of many cheap remotes.
+const (
+	power      = "00000000" + "11111111" + "10100010" + "01011101"
+	powerValue = 0xBA45FF00
+)
+
+func TestRoundTrip(t *testing.T) {
+	irtest.RoundTrip(t, nec, 1000, 1)
+}
+
+func TestNECValue(t *testing.T) {
+	if v := codec.NECValue(0xFF00, 0x45); v != powerValue {
+		t.Errorf("NECValue(0xFF00, 0x45) = %#x, want %#x", v, powerValue)
+	}
+	if v := codec.NECValue(0, 0); v != 0xFF000000 {
+		t.Errorf("NECValue(0, 0) = %#x, want 0xff000000", v)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	c := codec.NEC.Code(powerValue)
+	if got, want := c.MarshalFrame(), necPairs(power); !reflect.DeepEqual(got, want) {
+		t.Errorf("MarshalFrame() = %v, want %v", got, want)
+	}
+	wantRepeat := []irtrx.TimePair{codec.NEC.Repeat, {codec.NEC.Trailer, codec.NEC.Gap}}
+	if got := c.MarshalRepeat(); !reflect.DeepEqual(got, wantRepeat) {
+		t.Errorf("MarshalRepeat() = %v, want %v", got, wantRepeat)
+	}
+	if got, want := c.Bits(), []byte{0x00, 0xFF, 0x45, 0xBA}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Bits() = %x, want %x", got, want)
+	}
+	if c.RepeatPeriod() != codec.NEC.RepeatPeriod || c.Carrier() != irtrx.Freq38Khz {
+		t.Errorf("RepeatPeriod, Carrier = %v, %v", c.RepeatPeriod(), c.Carrier())
+	}
+	if s := c.String(); s != "nec 0xba45ff00" {
+		t.Errorf("String() = %q", s)
+	}
+}
+
+func TestDecodeNEC(t *testing.T) {
+	// what every decoder must do, on random frames
+	irtest.Conform(t, nec)
+
+	var got []uint64
+	var repeats int
+	d := codec.NewDecoder(&codec.NEC, func(v uint64) { got = append(got, v) })
+	d.RepeatHandler = func() { repeats++ }
+	w := irtest.NewWire(d, true)
+
+	w.SendPairs(necPairs(power)...)
+	w.SendPairs(codec.NEC.Code(powerValue).MarshalRepeat()...)
+	// a frame broken off by a gap, then the boundary values
+	short := necPairs(power)[:10]
+	short[9][1] = codec.NEC.Gap
+	w.SendPairs(short...)
+	w.SendFrame(codec.NEC.Code(0))
+	w.SendFrame(codec.NEC.Code(0xFFFFFFFF))
+	w.SendPair(irtrx.TimePair{codec.NEC.Trailer, codec.NEC.Gap})
+
+	if want := []uint64{powerValue, 0, 0xFFFFFFFF}; !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded %#x, want %#x", got, want)
+	}
+	if repeats != 1 {
+		t.Errorf("%d repeats, want 1", repeats)
+	}
+}
+
+// sirc is a 12 bit Sony code: pulse encoded and without a trailer, so the
+// last bit's space runs into the gap.
+var sirc = codec.Spec{
+	Name:   "sirc",
+	Header: irtrx.TimePair{us(2400), us(600)},
+	One:    irtrx.TimePair{us(1200), us(600)},
+	Zero:   irtrx.TimePair{us(600), us(600)},
+	Gap:    us(45000),
+	Bits:   12,
+}
+
+func TestDecodeNoTrailer(t *testing.T) {
+	var got []uint64
+	d := codec.NewDecoder(&sirc, func(v uint64) { got = append(got, v) })
+	w := irtest.NewWire(d, true)
+	// ending in a one, a zero, a one
+	want := []uint64{0x801, 0x095, 0xFFF, 0x000}
+	for _, v := range want {
+		w.SendFrame(sirc.Code(v))
+	}
+	// the mark ending the last frame's gap
+	w.SendPair(sirc.Header)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded %#x, want %#x", got, want)
+	}
+
+	// a last mark that is neither a one nor a zero
+	got = got[:0]
+	p := sirc.Encode(0x801)
+	p[len(p)-1][0] = us(1800)
+	w.SendPairs(p...)
+	w.SendPair(sirc.Header)
+	if len(got) != 0 {
+		t.Errorf("decoded %#x from a bad last mark", got)
+	}
+}
+
+func TestDecodeNoHeader(t *testing.T) {
+	s := sirc
+	s.Header = irtrx.TimePair{}
+	s.MSBFirst = true
+	var got []uint64
+	d := codec.NewDecoder(&s, func(v uint64) { got = append(got, v) })
+	w := irtest.NewWire(d, true)
+	// a frame starts with its first bit, and a glitch starts over
+	w.SendPair(irtrx.TimePair{us(100), us(100)})
+	want := []uint64{0xA90, 0x001}
+	for _, v := range want {
+		w.SendFrame(s.Code(v))
+	}
+	w.SendPair(s.Zero)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded %#x, want %#x", got, want)
+	}
+}
+
+func TestCodeUnmarshalTimePairs(t *testing.T) {
+	c := codec.NEC.Code(0)
+	if err := c.UnmarshalTimePairs(necPairs(power)); err != nil || c.Value != powerValue {
+		t.Errorf("got %#x, %v; want %#x", c.Value, err, powerValue)
+	}
+	for name, p := range map[string][]irtrx.TimePair{
+		"Empty":    nil,
+		"NoHeader": necPairs(power)[1:],
+		"Short":    necPairs(power)[:30],
+		"Repeat":   c.MarshalRepeat(),
+	} {
+		if err := c.UnmarshalTimePairs(p); err != codec.ErrNoFrame {
+			t.Errorf("%s: got %v, want %v", name, err, codec.ErrNoFrame)
+		}
+	}
+}
+
+func us(n int64) time.Duration { return time.Duration(n) * time.Microsecond }
+
+func TestWithin(t *testing.T) {
+	for _, tc := range []struct {
+		got, want int64
+		tolerance int
+		ok        bool
+	}{
+		{1000, 1000, 0, true},
+		// minTolerance covers short durations
+		{1100, 1000, 0, true},
+		{1101, 1000, 0, false},
+		{9000, 10000, 10, true},
+		{8999, 10000, 10, false},
+		{11000, 10000, 10, true},
+		{11001, 10000, 10, false},
+	} {
+		got, want := us(tc.got), us(tc.want)
+		if ok := codec.Within(got, want, tc.tolerance); ok != tc.ok {
+			t.Errorf("Within(%v, %v, %d) = %v, want %v", got, want, tc.tolerance, ok, tc.ok)
+		}
+	}
+}
+
+// BenchmarkDecoder covers every protocol decoded by a codec.Decoder, such as
+// beacon and telemetry.
+// TestCorpus replays the captures in testdata, each of which holds the
+// value it should decode to.
+func TestCorpus(t *testing.T) {
+	caps, err := irtest.LoadCorpus("testdata/captures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	irtest.ReplayCorpus(t, caps, "nec", nec.NewDecoder)
+}
+
+func BenchmarkDecoder(b *testing.B) {
+	irtest.BenchmarkDecoder(b, irtest.Bench{
+		Name:     "nec",
+		New:      func() irtrx.RxStateMachine { return codec.NewDecoder(&codec.NEC, func(uint64) {}) },
+		Frame:    codec.NEC.Code(codec.NECValue(0xFF00, 0x45)),
+		Inverted: true,
+	})
+}
@@ -0,0 +1,53 @@
+package codec
+
+import (
+	"time"
+
+	"github.com/sparques/irtrx"
+)
+
+// NEC is the Spec of the NEC protocol and its many clones: 32 bits, LSB
+// first, usually an address byte and its inverse (or a 16 bit address)
+// followed by a command byte and its inverse; see NECValue. Its Tolerance
+// is wider than the default, for receivers that stretch the short marks,
+// but narrow enough to tell a header from a repeat.
+var NEC = Spec{
+	Name:         "nec",
+	Freq:         irtrx.Freq38Khz,
+	Header:       irtrx.TimePair{9 * time.Millisecond, 4500 * time.Microsecond},
+	One:          irtrx.TimePair{562 * time.Microsecond, 1687 * time.Microsecond},
+	Zero:         irtrx.TimePair{562 * time.Microsecond, 562 * time.Microsecond},
+	Trailer:      562 * time.Microsecond,
+	Gap:          40 * time.Millisecond,
+	Bits:         32,
+	Repeat:       irtrx.TimePair{9 * time.Millisecond, 2250 * time.Microsecond},
+	RepeatPeriod: 108 * time.Millisecond,
+	Tolerance:    30,
+}
+
+func init() {
+	irtrx.RegisterProtocol(irtrx.Protocol{
+		Name:  "nec",
+		Usage: "addr cmd | code",
+		Encode: func(args ...uint64) (irtrx.FrameMarshaller, error) {
+			switch {
+			case len(args) == 1 && args[0] <= 0xFFFFFFFF:
+				return NEC.Code(args[0]), nil
+			case len(args) == 2 && args[0] <= 0xFFFF && args[1] <= 0xFF:
+				addr := uint16(args[0])
+				if addr <= 0xFF {
+					addr |= uint16(^uint8(addr)) << 8
+				}
+				return NEC.Code(NECValue(addr, uint8(args[1]))), nil
+			}
+			return nil, irtrx.ErrArgs
+		},
+	})
+}
+
+// NECValue returns the value of an NEC frame for the 16 bit address addr and
+// the command byte cmd. Standard NEC addresses are a byte followed by its
+// inverse: addr = uint16(^a)<<8 | uint16(a).
+func NECValue(addr uint16, cmd uint8) uint64 {
+	return uint64(^cmd)<<24 | uint64(cmd)<<16 | uint64(addr)
+}
@@ -0,0 +1,258 @@
+// console is a one-way serial console over IR, for robots with no UART to
+// spare or sealed inside armour. The robot writes log lines to a Logger,
+// which sends them as stream frames from its IR LED:
+//
+//	l := console.NewLogger(irtrx.NewTxDevice(txPin), 512)
+//	l.Start()
+//	fmt.Fprintf(l, "battery %dmV\n", mv)
+//
+// and a board on the desk, running cmd/irconsole, forwards them to its USB
+// serial port with a Receiver. Nothing is acknowledged; the Receiver marks
+// where frames were lost and where the robot restarted.
+package console
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/stream"
+)
+
+// MaxText is the most text a frame carries, after its header byte.
+const MaxText = stream.MaxPayload - 1
+
+// The header byte holds a 7 bit sequence number, with firstFlag set on the
+// first frame a Logger sends.
+const (
+	firstFlag = 0x80
+	seqMask   = 0x7F
+)
+
+// FlushDelay is how long a Logger holds a partial line before sending it
+// anyway.
+const FlushDelay = 100 * time.Millisecond
+
+// PollInterval is how often the Logger and Receiver check for work.
+const PollInterval = 5 * time.Millisecond
+
+// Logger is an io.Writer sending what is written to it over IR from a
+// goroutine, a line or MaxText bytes per frame, so writing never waits for
+// the slow transmission. What doesn't fit in its buffer is dropped.
+type Logger struct {
+	tx  irtrx.Transmitter
+	mu  sync.Mutex
+	buf []byte
+	// when the oldest unsent byte was written
+	since   time.Time
+	dropped atomic.Int64
+	seq     uint8
+	running atomic.Bool
+	frame   [stream.MaxPayload]byte
+	clock   irtrx.Clock
+}
+
+// NewLogger returns a Logger sending with tx and buffering up to size
+// bytes.
+func NewLogger(tx irtrx.Transmitter, size int) *Logger {
+	return &Logger{tx: tx, buf: make([]byte, 0, size), seq: firstFlag, clock: irtrx.RealClock}
+}
+
+// SetClock replaces the clock used to hold partial lines and poll. Set it
+// before Start.
+func (l *Logger) SetClock(c irtrx.Clock) {
+	l.clock = c
+}
+
+// Write implements io.Writer. It never blocks; bytes that don't fit are
+// dropped and counted, and the error is nil regardless.
+func (l *Logger) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.buf) == 0 {
+		l.since = l.clock.Now()
+	}
+	n := min(len(p), cap(l.buf)-len(l.buf))
+	l.buf = append(l.buf, p[:n]...)
+	l.dropped.Add(int64(len(p) - n))
+	return len(p), nil
+}
+
+// Dropped returns the number of bytes dropped so far for want of room.
+func (l *Logger) Dropped() int {
+	return int(l.dropped.Load())
+}
+
+// Buffered returns the number of bytes waiting to be sent.
+func (l *Logger) Buffered() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buf)
+}
+
+// Start starts sending from a goroutine.
+func (l *Logger) Start() {
+	if l.running.Swap(true) {
+		return
+	}
+	go func() {
+		for l.running.Load() {
+			if !l.send(false) {
+				l.clock.Sleep(PollInterval)
+			}
+		}
+	}()
+}
+
+// Stop stops the goroutine started by Start after its current frame.
+func (l *Logger) Stop() {
+	l.running.Store(false)
+}
+
+// Flush sends everything buffered, partial lines included, blocking until
+// it has been transmitted. Don't call it while the Logger is started.
+func (l *Logger) Flush() {
+	for l.send(true) {
+	}
+}
+
+// send sends the next frame's worth of buffered text, if a full line, a
+// full frame or a partial line held for FlushDelay is waiting, or anything
+// at all if force is set. It returns whether it sent anything.
+func (l *Logger) send(force bool) bool {
+	l.mu.Lock()
+	n := min(len(l.buf), MaxText)
+	for i, b := range l.buf[:n] {
+		if b == '\n' {
+			n = i + 1
+			force = true
+			break
+		}
+	}
+	if n == 0 || !force && n < MaxText && l.clock.Now().Sub(l.since) < FlushDelay {
+		l.mu.Unlock()
+		return false
+	}
+	l.frame[0] = l.seq
+	copy(l.frame[1:], l.buf[:n])
+	l.buf = l.buf[:copy(l.buf, l.buf[n:])]
+	l.since = l.clock.Now()
+	l.seq = (l.seq + 1) & seqMask
+	l.mu.Unlock()
+
+	l.tx.SendFrame(stream.Frame(l.frame[:n+1]))
+	return true
+}
+
+// queueSize is the number of frames a Receiver holds between Polls; it must
+// be a power of two.
+const queueSize = 8
+
+type frame struct {
+	n    int
+	data [stream.MaxPayload]byte
+}
+
+// Receiver implements irtrx.RxStateMachine, decoding a Logger's frames for
+// Poll or Forward to write out. Its receiver must be started with
+// StartInverted.
+type Receiver struct {
+	// ErrorHandler, if set, is called with every frame that fails to
+	// decode. It is called from the interrupt handler.
+	ErrorHandler func(error)
+
+	sm stream.StateMachine
+	// single producer, single consumer ring of frames
+	queue      [queueSize]frame
+	head, tail atomic.Uint32
+	// the last sequence number seen, or -1
+	last int
+	// whether the text written so far ends mid line
+	midLine bool
+	clock   irtrx.Clock
+}
+
+// NewReceiver returns a Receiver.
+func NewReceiver() *Receiver {
+	r := &Receiver{last: -1, clock: irtrx.RealClock}
+	r.sm.PayloadHandler = r.push
+	r.sm.ErrorHandler = func(err error) {
+		if r.ErrorHandler != nil {
+			r.ErrorHandler(err)
+		}
+	}
+	return r
+}
+
+// SetClock replaces the clock Forward polls with.
+func (r *Receiver) SetClock(c irtrx.Clock) {
+	r.clock = c
+}
+
+// HandleTimePair implements irtrx.RxStateMachine.
+func (r *Receiver) HandleTimePair(pair irtrx.TimePair) {
+	r.sm.HandleTimePair(pair)
+}
+
+// push queues a frame's payload, dropping it if Poll has fallen behind; the
+// gap in sequence numbers will show.
+func (r *Receiver) push(payload []byte) {
+	head := r.head.Load()
+	if len(payload) == 0 || head-r.tail.Load() == queueSize {
+		return
+	}
+	f := &r.queue[head&(queueSize-1)]
+	f.n = copy(f.data[:], payload)
+	r.head.Store(head + 1)
+}
+
+// Poll writes the text of the frames received since the last Poll to w,
+// with a note in brackets, on a line of its own, wherever frames were lost
+// or the Logger restarted. It returns the first error writing to w.
+func (r *Receiver) Poll(w io.Writer) error {
+	for {
+		tail := r.tail.Load()
+		if tail == r.head.Load() {
+			return nil
+		}
+		f := &r.queue[tail&(queueSize-1)]
+		hdr, text := f.data[0], f.data[1:f.n]
+		seq := int(hdr & seqMask)
+		var note string
+		switch {
+		case hdr&firstFlag != 0:
+			note = "[restarted]\n"
+		case r.last >= 0 && seq != (r.last+1)&seqMask:
+			note = fmt.Sprintf("[lost %d frames]\n", (seq-r.last-1)&seqMask)
+		}
+		if note != "" && r.midLine {
+			note = "\n" + note
+		}
+		_, err := io.WriteString(w, note)
+		if err == nil && len(text) > 0 {
+			_, err = w.Write(text)
+			r.midLine = text[len(text)-1] != '\n'
+		}
+		r.last = seq
+		r.tail.Store(tail + 1)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Forward writes what is received to w until writing fails, polling every
+// PollInterval.
+func (r *Receiver) Forward(w io.Writer) error {
+	for {
+		if err := r.Poll(w); err != nil {
+			return err
+		}
+		r.clock.Sleep(PollInterval)
+	}
+}
+
+var _ irtrx.RxStateMachine = (*Receiver)(nil)
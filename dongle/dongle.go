@@ -0,0 +1,240 @@
+// dongle is the framed serial protocol spoken by cmd/irbridge, which turns a
+// board into an IR transceiver for desktop software. The host sends codes to
+// transmit and the board answers each with an ack or an error; everything
+// the board receives is sent up as a raw capture, which the host can decode
+// with any protocol's UnmarshalTimePairs.
+//
+// Every message is framed as
+//
+//	0xA5, type, payload length (2 bytes, little endian), payload, CRC-8
+//
+// with the CRC (polynomial 0x07) taken over everything between the sync byte
+// and the CRC. A Reader skips anything that doesn't frame up, so either end
+// can start listening at any time.
+package dongle
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/sparques/irtrx"
+)
+
+// Sync starts every message.
+const Sync = 0xA5
+
+// MaxPayload is the largest payload a message may carry.
+const MaxPayload = 1024
+
+// Type identifies a message.
+type Type uint8
+
+const (
+	// TypeAck is sent by the board once a send has been transmitted. It
+	// has no payload.
+	TypeAck Type = iota + 1
+	// TypeError is sent by the board instead of TypeAck when a send fails.
+	// The payload is the error text.
+	TypeError
+	// TypeSendRaw asks the board to transmit a Recording, in the form of
+	// irtrx.Recording.MarshalBinary.
+	TypeSendRaw
+	// TypeSendCode asks the board to transmit a code of a registered
+	// protocol: the protocol name's length as a byte, the name, then each
+	// argument as a uvarint; see irtrx.Encode.
+	TypeSendCode
+	// TypeReceived carries a Recording the board received, in the form of
+	// irtrx.Recording.MarshalBinary.
+	TypeReceived
+)
+
+var (
+	// ErrChecksum is returned by Reader.ReadMessage for a message whose CRC
+	// doesn't match. The Reader resynchronises on the next message.
+	ErrChecksum = errors.New("dongle: checksum mismatch")
+	// ErrTooLong is returned for payloads longer than MaxPayload.
+	ErrTooLong = errors.New("dongle: payload too long")
+	// ErrPayload is returned when a payload doesn't parse as its type.
+	ErrPayload = errors.New("dongle: malformed payload")
+)
+
+// Message is a single framed message.
+type Message struct {
+	Type    Type
+	Payload []byte
+}
+
+// crc8 updates crc with b, polynomial 0x07.
+func crc8(crc byte, b []byte) byte {
+	for _, c := range b {
+		crc ^= c
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// Append appends m, framed, to b.
+func (m Message) Append(b []byte) ([]byte, error) {
+	if len(m.Payload) > MaxPayload {
+		return b, ErrTooLong
+	}
+	b = append(b, Sync)
+	start := len(b)
+	b = append(b, byte(m.Type))
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(m.Payload)))
+	b = append(b, m.Payload...)
+	return append(b, crc8(0, b[start:])), nil
+}
+
+// WriteMessage writes m, framed, to w in a single Write.
+func WriteMessage(w io.Writer, m Message) error {
+	b, err := m.Append(nil)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// Reader reads framed messages.
+type Reader struct {
+	r   io.ByteReader
+	buf []byte
+}
+
+// NewReader returns a Reader reading from r.
+func NewReader(r io.ByteReader) *Reader {
+	return &Reader{r: r}
+}
+
+// ReadMessage returns the next message. Bytes before a sync byte are
+// skipped. The payload is only valid until the next call.
+func (rd *Reader) ReadMessage() (Message, error) {
+	for {
+		b, err := rd.r.ReadByte()
+		if err != nil {
+			return Message{}, err
+		}
+		if b == Sync {
+			break
+		}
+	}
+	rd.buf = rd.buf[:0]
+	for len(rd.buf) < 3 {
+		if err := rd.readByte(); err != nil {
+			return Message{}, err
+		}
+	}
+	n := int(binary.LittleEndian.Uint16(rd.buf[1:]))
+	if n > MaxPayload {
+		return Message{}, ErrTooLong
+	}
+	for len(rd.buf) < 3+n+1 {
+		if err := rd.readByte(); err != nil {
+			return Message{}, err
+		}
+	}
+	if crc8(0, rd.buf[:3+n]) != rd.buf[3+n] {
+		return Message{}, ErrChecksum
+	}
+	return Message{Type: Type(rd.buf[0]), Payload: rd.buf[3 : 3+n]}, nil
+}
+
+func (rd *Reader) readByte() error {
+	b, err := rd.r.ReadByte()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	rd.buf = append(rd.buf, b)
+	return nil
+}
+
+// Ack returns a TypeAck message.
+func Ack() Message {
+	return Message{Type: TypeAck}
+}
+
+// Error returns a TypeError message for err.
+func Error(err error) Message {
+	return Message{Type: TypeError, Payload: []byte(err.Error())}
+}
+
+// SendRaw returns a TypeSendRaw message for rec.
+func SendRaw(rec irtrx.Recording) Message {
+	b, _ := rec.MarshalBinary()
+	return Message{Type: TypeSendRaw, Payload: b}
+}
+
+// Received returns a TypeReceived message for rec.
+func Received(rec irtrx.Recording) Message {
+	b, _ := rec.MarshalBinary()
+	return Message{Type: TypeReceived, Payload: b}
+}
+
+// SendCode returns a TypeSendCode message for a code of protocol.
+func SendCode(protocol string, args ...uint64) Message {
+	b := append([]byte{byte(len(protocol))}, protocol...)
+	for _, a := range args {
+		b = binary.AppendUvarint(b, a)
+	}
+	return Message{Type: TypeSendCode, Payload: b}
+}
+
+// Recording returns the Recording carried by a TypeSendRaw or TypeReceived
+// message.
+func (m Message) Recording() (irtrx.Recording, error) {
+	var rec irtrx.Recording
+	if m.Type != TypeSendRaw && m.Type != TypeReceived {
+		return rec, ErrPayload
+	}
+	err := rec.UnmarshalBinary(m.Payload)
+	return rec, err
+}
+
+// Code returns the protocol and arguments carried by a TypeSendCode
+// message.
+func (m Message) Code() (protocol string, args []uint64, err error) {
+	p := m.Payload
+	if m.Type != TypeSendCode || len(p) == 0 || len(p) < 1+int(p[0]) {
+		return "", nil, ErrPayload
+	}
+	protocol, p = string(p[1:1+p[0]]), p[1+p[0]:]
+	for len(p) > 0 {
+		v, n := binary.Uvarint(p)
+		if n <= 0 {
+			return "", nil, ErrPayload
+		}
+		args = append(args, v)
+		p = p[n:]
+	}
+	return protocol, args, nil
+}
+
+// Frame returns what a send message asks to transmit.
+func (m Message) Frame() (irtrx.FrameMarshaller, error) {
+	switch m.Type {
+	case TypeSendRaw:
+		rec, err := m.Recording()
+		if err != nil {
+			return nil, err
+		}
+		return rec, nil
+	case TypeSendCode:
+		protocol, args, err := m.Code()
+		if err != nil {
+			return nil, err
+		}
+		return irtrx.Encode(protocol, args...)
+	}
+	return nil, ErrPayload
+}
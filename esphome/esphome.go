@@ -0,0 +1,174 @@
+// esphome reads and writes signals in the syntax of ESPHome's
+// remote_receiver dumps and remote_transmitter actions, so codes can be
+// copied verbatim between ESPHome and this module in either direction:
+//
+//	Received Raw: 9024, -4512, 564, -564, 564, -1692, ...
+//	Received NEC: address=0xFB04, command=0xF708
+//	Received Samsung: data=0xE0E040BF, nbits=32
+//
+// Raw timings are in microseconds, positive for marks and negative for
+// spaces.
+package esphome
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/codec"
+	"github.com/sparques/irtrx/samsung"
+)
+
+// TrailingSpace is the space given to a final mark without one.
+const TrailingSpace = 40 * time.Millisecond
+
+var (
+	// ErrFormat is returned for lines that can't be parsed.
+	ErrFormat = errors.New("esphome: malformed dump")
+	// ErrProtocol is returned for dumps of protocols without a package
+	// here.
+	ErrProtocol = errors.New("esphome: unsupported protocol")
+)
+
+// FormatRaw returns pairs as the list of timings used by both raw dumps and
+// transmit_raw's code, e.g. "9000, -4500, 560, -560".
+func FormatRaw(pairs []irtrx.TimePair) string {
+	var sb strings.Builder
+	for i, p := range pairs {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		fmt.Fprintf(&sb, "%d, -%d", p[0].Microseconds(), p[1].Microseconds())
+	}
+	return sb.String()
+}
+
+// DumpRaw returns pairs as remote_receiver logs them.
+func DumpRaw(pairs []irtrx.TimePair) string {
+	return "Received Raw: " + FormatRaw(pairs)
+}
+
+// TransmitRaw returns a remote_transmitter.transmit_raw action sending r.
+func TransmitRaw(r irtrx.Recording) string {
+	freq := r.Freq
+	if freq == 0 {
+		freq = irtrx.Freq38Khz
+	}
+	return fmt.Sprintf("remote_transmitter.transmit_raw:\n  carrier_frequency: %dHz\n  code: [%s]\n", freq, FormatRaw(r.Pairs))
+}
+
+// ParseRaw parses a list of timings, with or without the "Received Raw:"
+// prefix or the brackets of a transmit_raw code. Consecutive marks or
+// spaces are merged.
+func ParseRaw(s string) ([]irtrx.TimePair, error) {
+	if i := strings.IndexByte(s, ':'); i >= 0 {
+		s = s[i+1:]
+	}
+	s = strings.Trim(strings.TrimSpace(s), "[]")
+	var pairs []irtrx.TimePair
+	var p irtrx.TimePair
+	haveMark := false
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		v, err := strconv.ParseInt(f, 10, 64)
+		if err != nil {
+			return nil, ErrFormat
+		}
+		d := time.Duration(v) * time.Microsecond
+		switch {
+		case v > 0 && haveMark && p[1] == 0:
+			p[0] += d
+		case v > 0:
+			if haveMark {
+				pairs = append(pairs, p)
+			}
+			p = irtrx.TimePair{d, 0}
+			haveMark = true
+		case haveMark:
+			p[1] -= d
+		}
+	}
+	if haveMark {
+		if p[1] == 0 {
+			p[1] = TrailingSpace
+		}
+		pairs = append(pairs, p)
+	}
+	return pairs, nil
+}
+
+// DumpNEC returns the dump of the NEC frame with value v, as sent with
+// codec.NEC.
+func DumpNEC(v uint64) string {
+	return fmt.Sprintf("Received NEC: address=0x%04X, command=0x%04X", uint16(v), uint16(v>>16))
+}
+
+// DumpSamsung returns the dump of f. ESPHome sends Samsung data MSB first
+// where this module uses LSB first, so the bits are reversed.
+func DumpSamsung(f samsung.Frame) string {
+	raw := uint32(f.Cmd)<<16 | uint32(f.Addr)
+	return fmt.Sprintf("Received Samsung: data=0x%08X, nbits=32", bits.Reverse32(raw))
+}
+
+// ParseDump parses a remote_receiver dump line into a frame ready to send.
+// Raw, NEC and Samsung dumps are supported.
+func ParseDump(line string) (irtrx.FrameMarshaller, error) {
+	line = strings.TrimSpace(line)
+	if i := strings.Index(line, "Received "); i >= 0 {
+		line = line[i+len("Received "):]
+	}
+	proto, args, ok := strings.Cut(line, ":")
+	if !ok {
+		return nil, ErrFormat
+	}
+	switch strings.TrimSpace(proto) {
+	case "Raw":
+		pairs, err := ParseRaw(args)
+		if err != nil {
+			return nil, err
+		}
+		return irtrx.Recording{Freq: irtrx.Freq38Khz, Pairs: pairs}, nil
+	case "NEC":
+		kv, err := fields(args)
+		if err != nil {
+			return nil, err
+		}
+		return codec.NEC.Code(kv["command"]<<16 | kv["address"]&0xFFFF), nil
+	case "Samsung":
+		kv, err := fields(args)
+		if err != nil {
+			return nil, err
+		}
+		if n, ok := kv["nbits"]; ok && n != 32 {
+			return nil, ErrProtocol
+		}
+		var f samsung.Frame
+		f.UnmarshalFrame(bits.Reverse32(uint32(kv["data"])))
+		return &f, nil
+	}
+	return nil, ErrProtocol
+}
+
+// fields parses "key=value, key=value" with numeric values.
+func fields(s string) (map[string]uint64, error) {
+	kv := map[string]uint64{}
+	for _, f := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(f), "=")
+		if !ok {
+			return nil, ErrFormat
+		}
+		n, err := strconv.ParseUint(v, 0, 64)
+		if err != nil {
+			return nil, ErrFormat
+		}
+		kv[k] = n
+	}
+	return kv, nil
+}
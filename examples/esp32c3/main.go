@@ -0,0 +1,60 @@
+//go:build esp32c3
+
+// A Samsung TV remote switching a lamp and a fan, through relay modules or
+// MOSFETs, on an ESP32-C3 board. Only frames addressed to a TV are
+// accepted, so other remotes in the room don't interfere.
+//
+//	IR receiver OUT  GPIO4
+//	lamp             GPIO2, toggled by Power
+//	fan              GPIO3, on with 1, off with 0
+//
+//	tinygo flash -target esp32c3 ./examples/esp32c3
+package main
+
+import (
+	"machine"
+	"time"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/samsung"
+)
+
+const (
+	rxPin   = machine.GPIO4
+	lampPin = machine.GPIO2
+	fanPin  = machine.GPIO3
+)
+
+func main() {
+	lampPin.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	fanPin.Configure(machine.PinConfig{Mode: machine.PinOutput})
+
+	// Samsung remotes repeat the frame while a key is held, so act on
+	// presses only
+	kt := samsung.NewKeyTracker(func(ev samsung.KeyEvent) {
+		if ev.Type != samsung.Pressed {
+			return
+		}
+		key, ok := ev.Frame.Key()
+		if !ok {
+			return
+		}
+		switch key {
+		case samsung.KeyPower:
+			lampPin.Set(!lampPin.Get())
+		case samsung.Key1:
+			fanPin.High()
+		case samsung.Key0:
+			fanPin.Low()
+		}
+	})
+	sm := samsung.NewStateMachine(kt.HandleFrame)
+	sm.SetAddressFilter(samsung.TVAddr)
+
+	rx := irtrx.NewRxDevice(rxPin, sm)
+	rx.StartInverted()
+	for {
+		kt.Poll()
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,50 @@
+//go:build pca10056
+
+// An IR RC receiver on the nRF52840 DK: PPM frames from an IR transmitter
+// (e.g. a TxDevice sending ppm.Frame) drive an ESC and a steering servo,
+// with LED1 lit while the link is up. When frames stop arriving the outputs
+// move to their failsafe values.
+//
+//	IR receiver OUT  P1.01
+//	ESC              P1.02, channel 1
+//	steering servo   P1.03, channel 2
+//
+//	tinygo flash -target pca10056 ./examples/nrf52840
+package main
+
+import (
+	"machine"
+	"time"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/ppm"
+)
+
+const rxPin = machine.P1_01
+
+func main() {
+	// LED1 is active low
+	led := machine.LED1
+	led.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	led.High()
+
+	psm := ppm.NewStateMachineChannels(2)
+	servos := ppm.NewServoOutputs(psm, machine.P1_02, machine.P1_03)
+	servos.Start()
+	psm.SetFailsafeHandler(func(failsafe bool) {
+		led.Set(failsafe)
+	})
+
+	// PPM takes space-mark pairs, so Start, not StartInverted
+	rx := irtrx.NewRxDevice(rxPin, psm)
+	rx.Start()
+
+	for {
+		// frames update the outputs as they arrive; this moves them to
+		// failsafe when they don't
+		if psm.IsSafe() {
+			servos.Update()
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
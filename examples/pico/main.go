@@ -0,0 +1,62 @@
+//go:build pico
+
+// A hexbug remote driven robot on a Raspberry Pi Pico: a differential drive
+// through a DRV8833 style H-bridge, two inputs per motor, with the onboard
+// LED lit while buttons are held. If the remote goes out of range the
+// failsafe stops the motors.
+//
+//	IR receiver OUT  GP15
+//	left motor       GP16 (IN1), GP17 (IN2)
+//	right motor      GP18 (IN3), GP19 (IN4)
+//
+//	tinygo flash -target pico ./examples/pico
+package main
+
+import (
+	"machine"
+	"time"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/hexbug"
+)
+
+const rxPin = machine.GP15
+
+// motor is one side of an H-bridge.
+type motor struct{ in1, in2 machine.Pin }
+
+func newMotor(in1, in2 machine.Pin) motor {
+	in1.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	in2.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	return motor{in1, in2}
+}
+
+// set runs the motor forward, back or stops it by the sign of v. Use PWM
+// on the inputs for proportional speed.
+func (m motor) set(v float32) {
+	m.in1.Set(v > 0)
+	m.in2.Set(v < 0)
+}
+
+func main() {
+	led := machine.LED
+	led.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	left := newMotor(machine.GP16, machine.GP17)
+	right := newMotor(machine.GP18, machine.GP19)
+
+	drive := hexbug.NewDrive()
+	hb := hexbug.NewStateMachine(drive.SetCmd)
+	hb.SetFailsafe(300 * time.Millisecond)
+
+	// hexbug's decoder takes space-mark pairs, so Start, not StartInverted
+	rx := irtrx.NewRxDevice(rxPin, hb)
+	rx.Start()
+
+	for {
+		l, r := drive.Update()
+		left.set(l)
+		right.set(r)
+		led.Set(l != 0 || r != 0)
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,49 @@
+//go:build xiao_rp2040
+
+// The 24 key LED strip remote driving the onboard RGB LED of a Seeed XIAO
+// RP2040. The XIAO's LED has no PWM-free dimming, so each color is on when
+// its component is at least half; the power keys switch it off and on.
+//
+//	IR receiver OUT  D7
+//
+//	tinygo flash -target xiao-rp2040 ./examples/xiao-rp2040
+package main
+
+import (
+	"machine"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/cheapo"
+)
+
+const rxPin = machine.D7
+
+// the RGB LED is common anode, so each color is lit by driving it low
+var rgb = [3]machine.Pin{machine.LED_RED, machine.LED_GREEN, machine.LED_BLUE}
+
+var color [3]uint8
+
+func show(on bool) {
+	for i, pin := range rgb {
+		pin.Set(!(on && color[i] >= 0x80))
+	}
+}
+
+func main() {
+	for _, pin := range rgb {
+		pin.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	}
+
+	a := cheapo.NewLEDAdapter(cheapo.NewStateMachine(nil), cheapo.Keymap24)
+	a.SetColor = func(r, g, b uint8) {
+		color = [3]uint8{r, g, b}
+		show(a.On())
+	}
+	a.Power = show
+	color = [3]uint8{0xFF, 0xFF, 0xFF}
+	show(true)
+
+	rx := irtrx.NewRxDevice(rxPin, a.StateMachine())
+	rx.StartInverted()
+	select {}
+}
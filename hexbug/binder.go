@@ -0,0 +1,87 @@
+package hexbug
+
+// Binder wraps a StateMachine and does what real hexbugs do: it takes the
+// first channel it hears as its own and ignores commands on any other
+// channel.
+//
+// To keep a binding across power cycles, set Save to write the channel to
+// flash and Load to read it back, then call Restore at startup.
+type Binder struct {
+	*StateMachine
+
+	handler func(Cmd)
+	channel Channel
+	bound   bool
+
+	// BindEnabled, if not nil, is checked before binding; binding only
+	// happens while it returns true, e.g. while a bind button is held. It is
+	// called from interrupt context.
+	BindEnabled func() bool
+	// Save, if not nil, is called with the channel once bound. It is called
+	// from interrupt context, so it should hand the work off rather than
+	// write to flash itself.
+	Save func(channel Channel)
+	// Load, if not nil, is used by Restore to read back a saved channel.
+	Load func() (channel Channel, ok bool)
+}
+
+// NewBinder returns an unbound Binder. cmdHandler is called with commands
+// on the bound channel only.
+func NewBinder(cmdHandler func(Cmd)) *Binder {
+	b := &Binder{handler: cmdHandler}
+	b.StateMachine = NewStateMachine(b.handleCmd)
+	return b
+}
+
+// SetCmdHandler lets you change the callback for when a cmd is received on
+// the bound channel.
+func (b *Binder) SetCmdHandler(cmdHandler func(Cmd)) {
+	b.handler = cmdHandler
+}
+
+func (b *Binder) handleCmd(cmd Cmd) {
+	ch := cmd.Channel()
+	if !b.bound {
+		if b.BindEnabled != nil && !b.BindEnabled() {
+			return
+		}
+		b.channel = ch
+		b.bound = true
+		if b.Save != nil {
+			b.Save(ch)
+		}
+	}
+	if ch != b.channel {
+		return
+	}
+	b.handler(cmd)
+}
+
+// Restore binds to the channel returned by Load, if any. It returns whether
+// a channel was restored.
+func (b *Binder) Restore() bool {
+	if b.Load == nil {
+		return false
+	}
+	ch, ok := b.Load()
+	if ok {
+		b.Bind(ch)
+	}
+	return ok
+}
+
+// Bind binds to channel without waiting to hear it.
+func (b *Binder) Bind(channel Channel) {
+	b.channel = channel
+	b.bound = true
+}
+
+// Unbind forgets the bound channel; the next channel heard is taken.
+func (b *Binder) Unbind() {
+	b.bound = false
+}
+
+// Channel returns the bound channel and whether there is one.
+func (b *Binder) Channel() (Channel, bool) {
+	return b.channel, b.bound
+}
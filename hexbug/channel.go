@@ -0,0 +1,65 @@
+package hexbug
+
+import (
+	"errors"
+	"strings"
+)
+
+// Channel is a hexbug channel as numbered on the remote, 1 to 4. Use Bits
+// and ChannelFromBits to convert to and from the channel bits on the wire,
+// where channels 3 and 4 are swapped.
+type Channel uint8
+
+const (
+	NoChannel Channel = iota
+	Channel1
+	Channel2
+	Channel3
+	Channel4
+)
+
+// ErrChannel is returned by ParseChannel for anything that isn't a channel.
+var ErrChannel = errors.New("hexbug: invalid channel")
+
+// wire bits for each channel, indexed by Channel-1
+var channelBits = [4]Cmd{CH1, CH2, CH3, CH4}
+
+// Bits returns the channel bits for c as they appear in a Cmd.
+func (c Channel) Bits() Cmd {
+	if c < Channel1 || c > Channel4 {
+		return CH1
+	}
+	return channelBits[c-1]
+}
+
+// index returns c as 0 to 3, for indexing per-channel arrays.
+func (c Channel) index() int {
+	return int(c.Bits() >> 6)
+}
+
+// ChannelFromBits returns the Channel for the channel bits of cmd.
+func ChannelFromBits(cmd Cmd) Channel {
+	for i, bits := range channelBits {
+		if cmd&CmdChannelMask == bits {
+			return Channel(i + 1)
+		}
+	}
+	return NoChannel
+}
+
+// ParseChannel parses a channel given as "1" to "4", optionally prefixed
+// with "CH" in either case, e.g. "ch3".
+func ParseChannel(s string) (Channel, error) {
+	s = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "CH")
+	if len(s) != 1 || s[0] < '1' || s[0] > '4' {
+		return NoChannel, ErrChannel
+	}
+	return Channel(s[0] - '0'), nil
+}
+
+func (c Channel) String() string {
+	if c < Channel1 || c > Channel4 {
+		return "CH?"
+	}
+	return "CH" + string(rune('0'+c))
+}
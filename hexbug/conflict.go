@@ -0,0 +1,87 @@
+package hexbug
+
+import (
+	"time"
+
+	"github.com/sparques/irtrx"
+)
+
+// ConflictDetector watches for two transmitters fighting over one channel:
+// commands that contradict each other (forward then back, left then right)
+// alternating faster than a person could press buttons. Arena software can
+// use it to flag jamming or two remotes set to the same channel.
+//
+// Like EventTracker, pass its HandleCmd method as (or call it from) the
+// StateMachine's command handler.
+type ConflictDetector struct {
+	// Window is how close together contradictory frames must be to count.
+	Window time.Duration
+	// Threshold is how many contradictions within Window trigger the
+	// handler.
+	Threshold int
+
+	handler func(Channel)
+	clock   irtrx.Clock
+
+	// per Channel.index()
+	last    [4]Cmd
+	lastAt  [4]time.Time
+	flips   [4]int
+	flagged [4]bool
+	lastHit [4]time.Time
+}
+
+// NewConflictDetector returns a ConflictDetector that calls handler once
+// each time a conflict starts on a channel. handler is called from the
+// command handler, so usually from interrupt context.
+func NewConflictDetector(handler func(Channel)) *ConflictDetector {
+	return &ConflictDetector{
+		Window:    200 * time.Millisecond,
+		Threshold: 3,
+		handler:   handler,
+		clock:     irtrx.RealClock,
+	}
+}
+
+// SetClock replaces the clock used to time commands.
+func (cd *ConflictDetector) SetClock(c irtrx.Clock) {
+	cd.clock = c
+}
+
+// contradicts reports whether a and b press opposing buttons.
+func contradicts(a, b Cmd) bool {
+	return (a.Fwd() && b.Back()) || (a.Back() && b.Fwd()) ||
+		(a.Left() && b.Right()) || (a.Right() && b.Left())
+}
+
+// HandleCmd checks cmd against the recent commands on its channel.
+func (cd *ConflictDetector) HandleCmd(cmd Cmd) {
+	now := cd.clock.Now()
+	ch := cmd.Channel().index()
+
+	if now.Sub(cd.lastHit[ch]) > cd.Window {
+		// quiet for a while; start over
+		cd.flips[ch] = 0
+		cd.flagged[ch] = false
+	}
+
+	if !cmd.IsStop() {
+		if now.Sub(cd.lastAt[ch]) < cd.Window && contradicts(cd.last[ch], cmd) {
+			cd.flips[ch]++
+			cd.lastHit[ch] = now
+		}
+		cd.last[ch] = cmd
+		cd.lastAt[ch] = now
+	}
+
+	if cd.flips[ch] >= cd.Threshold && !cd.flagged[ch] {
+		cd.flagged[ch] = true
+		cd.handler(cmd.Channel())
+	}
+}
+
+// Conflicted reports whether a conflict is currently flagged on channel.
+func (cd *ConflictDetector) Conflicted(channel Channel) bool {
+	ch := channel.index()
+	return cd.flagged[ch] && cd.clock.Now().Sub(cd.lastHit[ch]) <= cd.Window
+}
@@ -0,0 +1,32 @@
+package hexbug
+
+import "time"
+
+type dedupe struct {
+	window time.Duration
+	last   Cmd
+	lastAt time.Time
+}
+
+// SetDedupe turns on de-duplication: identical consecutive commands arriving
+// within window of each other are collapsed into a single callback. The
+// remote sends every press at least twice and repeats while held, so this
+// suits applications that want discrete button events rather than a command
+// stream. Since each duplicate extends the window, a held button produces a
+// single callback. A window of zero turns de-duplication off.
+func (hb *StateMachine) SetDedupe(window time.Duration) {
+	hb.dedupe = dedupe{window: window}
+}
+
+// duplicate reports whether cmd should be dropped as a duplicate.
+func (hb *StateMachine) duplicate(cmd Cmd) bool {
+	d := &hb.dedupe
+	if d.window == 0 {
+		return false
+	}
+	now := hb.clock.Now()
+	dup := cmd == d.last && now.Sub(d.lastAt) < d.window
+	d.last = cmd
+	d.lastAt = now
+	return dup
+}
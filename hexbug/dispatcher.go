@@ -0,0 +1,50 @@
+package hexbug
+
+// Dispatcher routes commands from a single StateMachine to a handler per
+// channel, so one receiver can host the game logic for up to four robots.
+// A snoop handler, e.g. for a referee or scorekeeper, can watch all channels.
+//
+//	d := hexbug.NewDispatcher(hexbug.NewStateMachine(nil))
+//	d.Handle(hexbug.Channel1, redRobot.OnCmd)
+//	d.Handle(hexbug.Channel2, blueRobot.OnCmd)
+//	rx := irtrx.NewRxDevice(rxPin, d.StateMachine())
+type Dispatcher struct {
+	hb       *StateMachine
+	handlers [4]func(Cmd)
+	snoop    func(Cmd)
+}
+
+// NewDispatcher returns a Dispatcher for hb. It takes over hb's command
+// handler.
+func NewDispatcher(hb *StateMachine) *Dispatcher {
+	d := &Dispatcher{hb: hb}
+	hb.SetCmdHandler(d.HandleCmd)
+	return d
+}
+
+// StateMachine returns the StateMachine the Dispatcher is attached to.
+func (d *Dispatcher) StateMachine() *StateMachine {
+	return d.hb
+}
+
+// Handle sets the handler for commands on channel. Pass nil to ignore the
+// channel.
+func (d *Dispatcher) Handle(channel Channel, handler func(Cmd)) {
+	d.handlers[channel.index()] = handler
+}
+
+// Snoop sets a handler that sees commands on every channel, before the
+// per-channel handler. Pass nil to remove it.
+func (d *Dispatcher) Snoop(handler func(Cmd)) {
+	d.snoop = handler
+}
+
+// HandleCmd routes cmd to the handlers for its channel.
+func (d *Dispatcher) HandleCmd(cmd Cmd) {
+	if d.snoop != nil {
+		d.snoop(cmd)
+	}
+	if h := d.handlers[cmd.Channel().index()]; h != nil {
+		h(cmd)
+	}
+}
@@ -0,0 +1,102 @@
+package hexbug
+
+import (
+	"time"
+
+	"github.com/sparques/irtrx"
+)
+
+// Drive converts hexbug commands into left and right motor values for a
+// differential drive (tank steered) robot. Motor values are in -1..1,
+// suitable for scaling to an H-bridge's PWM duty cycle.
+//
+// Feed it commands with SetCmd (it can be passed straight to
+// NewStateMachine) and call Update from your control loop to get the motor
+// values.
+type Drive struct {
+	// Speed is the motor value for full forward or back.
+	Speed float32
+	// TurnRate is how much Left or Right adds to one side and takes from the
+	// other. When not driving forward or back, the robot spins in place at
+	// this rate.
+	TurnRate float32
+	// Ramp limits how fast the motor values can change, in units per second,
+	// to save gearboxes and wheelies. Zero means change instantly.
+	Ramp float32
+
+	targetL, targetR float32
+	left, right      float32
+	last             time.Time
+	clock            irtrx.Clock
+}
+
+// NewDrive returns a Drive at full speed, a moderate turn rate and no
+// ramping.
+func NewDrive() *Drive {
+	return &Drive{
+		Speed:    1,
+		TurnRate: 0.5,
+		clock:    irtrx.RealClock,
+	}
+}
+
+// SetClock replaces the clock used for ramping.
+func (d *Drive) SetClock(c irtrx.Clock) {
+	d.clock = c
+}
+
+// SetCmd sets the target motor values from cmd.
+func (d *Drive) SetCmd(cmd Cmd) {
+	var throttle, steer float32
+	if cmd.Fwd() {
+		throttle++
+	}
+	if cmd.Back() {
+		throttle--
+	}
+	if cmd.Right() {
+		steer++
+	}
+	if cmd.Left() {
+		steer--
+	}
+	throttle *= d.Speed
+	steer *= d.TurnRate
+	d.targetL = clamp(throttle + steer)
+	d.targetR = clamp(throttle - steer)
+}
+
+// Update moves the motor values toward their targets, as limited by Ramp, and
+// returns them.
+func (d *Drive) Update() (left, right float32) {
+	now := d.clock.Now()
+	if d.Ramp == 0 || d.last.IsZero() {
+		d.left, d.right = d.targetL, d.targetR
+	} else {
+		step := d.Ramp * float32(now.Sub(d.last)) / float32(time.Second)
+		d.left = approach(d.left, d.targetL, step)
+		d.right = approach(d.right, d.targetR, step)
+	}
+	d.last = now
+	return d.left, d.right
+}
+
+func approach(v, target, step float32) float32 {
+	switch {
+	case v < target-step:
+		return v + step
+	case v > target+step:
+		return v - step
+	}
+	return target
+}
+
+func clamp(f float32) float32 {
+	if f < -1 {
+		return -1
+	}
+	if f > 1 {
+		return 1
+	}
+	return f
+}
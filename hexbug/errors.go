@@ -0,0 +1,77 @@
+package hexbug
+
+import "time"
+
+const (
+	// bits shorter than this are glitches
+	minBitTime = 150 * time.Microsecond
+	// bits between this and the start flag are neither one nor start
+	maxOneTime = 1300 * time.Microsecond
+	// spaces within a frame are 350us; a longer one is the gap after it
+	maxSpaceTime = 1000 * time.Microsecond
+)
+
+// DecodeError identifies why a frame was dropped.
+type DecodeError uint8
+
+const (
+	// ErrParity means all 9 bits arrived but failed the parity check.
+	ErrParity DecodeError = iota + 1
+	// ErrTruncated means a new start flag arrived part way through a frame.
+	ErrTruncated
+	// ErrTiming means a bit's timing was neither a zero, a one, nor a start
+	// flag.
+	ErrTiming
+)
+
+func (de DecodeError) Error() string {
+	switch de {
+	case ErrParity:
+		return "hexbug: parity error"
+	case ErrTruncated:
+		return "hexbug: truncated frame"
+	case ErrTiming:
+		return "hexbug: bit timing out of range"
+	}
+	return "hexbug: unknown error"
+}
+
+// Errors counts the frames dropped by a StateMachine, by reason. Compare
+// them against the number of good frames to evaluate receiver placement or
+// interference from sunlight.
+type Errors struct {
+	Parity    int
+	Truncated int
+	Timing    int
+}
+
+// Errors returns the decode error counts.
+func (hb *StateMachine) Errors() Errors {
+	return hb.errors
+}
+
+// ResetErrors zeroes the decode error counts.
+func (hb *StateMachine) ResetErrors() {
+	hb.errors = Errors{}
+}
+
+// SetErrorHandler sets a callback that is called, from interrupt context,
+// for every dropped frame with the reason and the bits received so far. Pass
+// nil to remove it.
+func (hb *StateMachine) SetErrorHandler(errorHandler func(DecodeError, Cmd)) {
+	hb.errorHandler = errorHandler
+}
+
+func (hb *StateMachine) decodeError(de DecodeError) {
+	switch de {
+	case ErrParity:
+		hb.errors.Parity++
+	case ErrTruncated:
+		hb.errors.Truncated++
+	case ErrTiming:
+		hb.errors.Timing++
+	}
+	if hb.errorHandler != nil {
+		hb.errorHandler(de, hb.rcvbuf)
+	}
+}
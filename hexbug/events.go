@@ -0,0 +1,126 @@
+package hexbug
+
+import (
+	"time"
+
+	"github.com/sparques/irtrx"
+)
+
+// EventType is the kind of button Event.
+type EventType uint8
+
+const (
+	// Pressed is sent when a button goes down.
+	Pressed EventType = iota
+	// Held is sent for each repeated frame while a button stays down.
+	Held
+	// Released is sent when a button comes up, either because a frame
+	// without it arrived or because frames stopped arriving.
+	Released
+)
+
+func (et EventType) String() string {
+	switch et {
+	case Pressed:
+		return "Pressed"
+	case Held:
+		return "Held"
+	case Released:
+		return "Released"
+	}
+	return "Unknown"
+}
+
+// Event describes a change in the state of a single button.
+type Event struct {
+	Type EventType
+	// Button is one of the Cmd*Mask button constants.
+	Button  Cmd
+	Channel Channel
+	// Duration is how long the button has been down, for Held and Released.
+	Duration time.Duration
+}
+
+// EventTracker turns the stream of decoded commands into Pressed, Held and
+// Released events per button, so robot code can react to edges instead of
+// re-parsing repeated frames. Pass its HandleCmd method as the StateMachine's
+// command handler:
+//
+//	et := hexbug.NewEventTracker(onEvent)
+//	hb := hexbug.NewStateMachine(et.HandleCmd)
+type EventTracker struct {
+	handler func(Event)
+
+	// Timeout is how long after the last frame buttons are considered
+	// released, for when the robot drives out of range and the stop bytes
+	// are never seen. Timeouts are only detected when Poll is called.
+	Timeout time.Duration
+
+	// state per Channel.index()
+	buttons   [4]Cmd
+	pressedAt [4][6]time.Time
+	last      [4]time.Time
+	clock     irtrx.Clock
+}
+
+// NewEventTracker returns an EventTracker calling handler for every event.
+func NewEventTracker(handler func(Event)) *EventTracker {
+	return &EventTracker{
+		handler: handler,
+		Timeout: 500 * time.Millisecond,
+		clock:   irtrx.RealClock,
+	}
+}
+
+// SetClock replaces the clock used to time events and timeouts.
+func (et *EventTracker) SetClock(c irtrx.Clock) {
+	et.clock = c
+}
+
+// HandleCmd processes a decoded command. Events are sent from here, so when
+// called from the StateMachine, handler runs in interrupt context.
+func (et *EventTracker) HandleCmd(cmd Cmd) {
+	now := et.clock.Now()
+	ch := cmd.Channel().index()
+	et.last[ch] = now
+	et.update(ch, cmd.Buttons(), now)
+}
+
+// Poll releases any buttons on channels that have timed out. Call it
+// periodically from your main loop.
+func (et *EventTracker) Poll() {
+	now := et.clock.Now()
+	for ch := range et.buttons {
+		if et.buttons[ch] != 0 && now.Sub(et.last[ch]) > et.Timeout {
+			et.update(ch, CmdStop, now)
+		}
+	}
+}
+
+// Buttons returns the buttons currently down on channel.
+func (et *EventTracker) Buttons(channel Channel) Cmd {
+	return et.buttons[channel.index()]
+}
+
+func (et *EventTracker) update(ch int, buttons Cmd, now time.Time) {
+	prev := et.buttons[ch]
+	et.buttons[ch] = buttons
+	for bit := 0; bit < 6; bit++ {
+		mask := Cmd(1) << bit
+		ev := Event{Button: mask, Channel: ChannelFromBits(Cmd(ch) << 6)}
+		switch {
+		case buttons&mask != 0 && prev&mask == 0:
+			et.pressedAt[ch][bit] = now
+			ev.Type = Pressed
+		case buttons&mask != 0:
+			ev.Type = Held
+			ev.Duration = now.Sub(et.pressedAt[ch][bit])
+		case prev&mask != 0:
+			ev.Type = Released
+			ev.Duration = now.Sub(et.pressedAt[ch][bit])
+		default:
+			continue
+		}
+		et.handler(ev)
+	}
+}
@@ -0,0 +1,56 @@
+package hexbug
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+type failsafe struct {
+	timeout time.Duration
+	running bool
+	// last command received and when, in nanoseconds since the Unix epoch
+	last   atomic.Int32
+	lastAt atomic.Int64
+}
+
+// SetFailsafe makes the StateMachine synthesize a stop command (all buttons
+// released, on the same channel) if timeout passes without a frame after a
+// command with buttons pressed. Without it, a robot driving out of range with
+// forward held keeps going forever. A timeout of zero turns the failsafe
+// off.
+//
+// The synthesized stop is delivered to the command handler from a goroutine
+// started by the first call to SetFailsafe, not from interrupt context.
+func (hb *StateMachine) SetFailsafe(timeout time.Duration) {
+	hb.failsafe.timeout = timeout
+	if hb.failsafe.running || timeout == 0 {
+		return
+	}
+	hb.failsafe.running = true
+	go hb.watchFailsafe()
+}
+
+// received is called from the interrupt handler for every good command.
+func (hb *StateMachine) received(cmd Cmd) {
+	hb.failsafe.last.Store(int32(cmd))
+	hb.failsafe.lastAt.Store(hb.clock.Now().UnixNano())
+}
+
+func (hb *StateMachine) watchFailsafe() {
+	for {
+		timeout := hb.failsafe.timeout
+		if timeout == 0 {
+			timeout = time.Second
+		}
+		cmd := Cmd(hb.failsafe.last.Load())
+		since := hb.clock.Now().Sub(time.Unix(0, hb.failsafe.lastAt.Load()))
+		if hb.failsafe.timeout == 0 || cmd.IsStop() || since < timeout {
+			hb.clock.Sleep(timeout - since%timeout)
+			continue
+		}
+		stop := cmd & CmdChannelMask
+		if hb.failsafe.last.CompareAndSwap(int32(cmd), int32(stop)) {
+			hb.cmdHandler(stop)
+		}
+	}
+}
@@ -95,6 +95,10 @@ I don't have a big enough space to actually find the indoor range limit, but I g
 non-line-of-sight functionality. Slightly around a corner doesn't seem to be a problem. Totally acceptable for low-speed, lightweight ground
 robots.
 
+## Sending by Name
+
+The protocol is registered with irtrx as "hexbug", for irtrx.Encode and cmd/irsend. Given one argument it is the whole Cmd; given two, the first is the Channel, 1 to 4, and the second the button bits.
+
 ## Examples
 
 ### An example that dumps the bytes received
@@ -104,8 +108,8 @@ robots.
 	const rxPin = machine.GPIOX
 	// create the hexbug StateMachine with a callback func that prints
 	// the bits of the received byte. (on RP2040 devices, this defaults to usb UART)
-	hb := hexbug.NewStateMachine(func(cmd int16) {
-		fmt.Printf("%09b\r\n", cmd)
+	hb := hexbug.NewStateMachine(func(cmd hexbug.Cmd) {
+		fmt.Printf("%09b %v\r\n", cmd, cmd)
 	})
 	// create the irrx Rx device; configures rxPin as an input
 	rx := irtrx.NewRxDevice(rxPin, hb)
@@ -127,9 +131,9 @@ A separate control loop checks r.Cmd and responds to it.
 
 ```
 
-	func (r *robot) OnCmd(cmd int16) {
-		addr := cmd & irrx.HexbugChMask
-		if r.Id == -1 {
+	func (r *robot) OnCmd(cmd hexbug.Cmd) {
+		addr := cmd.Channel()
+		if r.Id == hexbug.NoChannel {
 			// don't have an id set? take the first one we encounter
 			r.Id = addr
 		}
@@ -145,6 +149,7 @@ A separate control loop checks r.Cmd and responds to it.
 package hexbug
 
 import (
+	"math/bits"
 	"time"
 
 	"github.com/sparques/irtrx"
@@ -172,42 +177,75 @@ const (
 )
 
 type StateMachine struct {
-	cmdHandler func(int16)
-	rcvbuf     int16
+	cmdHandler func(Cmd)
+	rcvbuf     Cmd
 	bitcount   int
 	parity     bool
+
+	// command-loss failsafe; see failsafe.go
+	failsafe failsafe
+
+	// extended variant decoding; see variant.go
+	extbuf     uint32
+	extHandler func(ExtCmd)
+
+	// de-duplication; see dedupe.go
+	dedupe dedupe
+
+	clock irtrx.Clock
+
+	// decode error accounting; see errors.go
+	errors       Errors
+	errorHandler func(DecodeError, Cmd)
 }
 
 // Hexbug creates an implementation of irrx.RxStateMachine that decodes hexbug commands.
 // When a command is received, cmdHandler is called. This call comes from an interrupt
 // handler so you cannot make any blocking calls and should try to keep this as quick
 // as possible. It's a good idea to pass off the data to another control loop.
-func NewStateMachine(cmdHandler func(int16)) *StateMachine {
+func NewStateMachine(cmdHandler func(Cmd)) *StateMachine {
 	return &StateMachine{
 		cmdHandler: cmdHandler,
+		clock:      irtrx.RealClock,
 	}
 }
 
 // SetCmdHandler lets you change the callback for when a cmd is received.
-func (hb *StateMachine) SetCmdHandler(cmdHandler func(int16)) {
+func (hb *StateMachine) SetCmdHandler(cmdHandler func(Cmd)) {
 	hb.cmdHandler = cmdHandler
 }
 
+// SetClock replaces the clock used by the failsafe and de-duplication. Set it
+// before SetFailsafe.
+func (hb *StateMachine) SetClock(c irtrx.Clock) {
+	hb.clock = c
+}
+
 // HandleTimePair implements the irtrx.RxStateMachine interface
 func (hb *StateMachine) HandleTimePair(pair irtrx.TimePair) {
 	off := pair[1]
 
+	// a gap before this mark ends the frame in progress, whatever follows
+	if pair[0] > maxSpaceTime && hb.bitcount != 0 {
+		hb.endFrame()
+	}
+
 	if off > 1600*time.Microsecond {
 		// start of frame/byte
-		hb.rcvbuf = 0
-		hb.bitcount = 0
-		hb.parity = false
+		hb.endFrame()
+		return
+	}
+
+	if off < minBitTime || (off > maxOneTime && off <= 1600*time.Microsecond) {
+		hb.decodeError(ErrTiming)
+		hb.reset()
 		return
 	}
 
 	// off time > 750 is a one
 	if off > 750*time.Microsecond {
 		hb.rcvbuf |= 1 << hb.bitcount
+		hb.extbuf |= 1 << hb.bitcount
 		// parity starts off false, if we toggle it everytime we get a one,
 		// then an odd number of ones results in a "true"; voila, easy odd parity check
 		hb.parity = !hb.parity
@@ -215,25 +253,167 @@ func (hb *StateMachine) HandleTimePair(pair irtrx.TimePair) {
 
 	hb.bitcount++
 
-	if hb.bitcount == 9 {
-		// verify parity
-		if hb.parity {
-			hb.cmdHandler(hb.rcvbuf)
+	switch {
+	case hb.bitcount == 9 && hb.extHandler == nil:
+		hb.deliver()
+		hb.reset()
+	case hb.bitcount == MaxExtendedBits:
+		hb.endExtended()
+		hb.reset()
+	}
+}
+
+// endFrame ends the frame in progress, delivering it if extended frames are
+// on and otherwise counting it as truncated.
+func (hb *StateMachine) endFrame() {
+	if hb.bitcount != 0 {
+		if hb.extHandler != nil {
+			hb.endExtended()
+		} else {
+			hb.decodeError(ErrTruncated)
+		}
+	}
+	hb.reset()
+}
+
+// deliver hands a complete 9 bit command to the command handler.
+func (hb *StateMachine) deliver() {
+	// verify parity
+	if hb.parity {
+		hb.received(hb.rcvbuf)
+		if hb.duplicate(hb.rcvbuf) {
+			return
 		}
-		hb.rcvbuf = 0
-		hb.bitcount = 0
-		hb.parity = false
+		hb.cmdHandler(hb.rcvbuf)
+	} else {
+		hb.decodeError(ErrParity)
 	}
 }
 
+func (hb *StateMachine) reset() {
+	hb.extbuf = 0
+	hb.rcvbuf = 0
+	hb.bitcount = 0
+	hb.parity = false
+}
+
+// Cmd is a single hexbug command byte, as decoded by StateMachine or sent
+// with a TxDevice: the button bits, the channel bits and (on the wire) the
+// parity bit.
 type Cmd int16
 
+// Buttons returns just the button bits of c.
+func (c Cmd) Buttons() Cmd { return c & CmdButtonMask }
+
+// Channel returns the channel c was sent on.
+func (c Cmd) Channel() Channel { return ChannelFromBits(c) }
+
+// Fwd reports whether the forward button is pressed.
+func (c Cmd) Fwd() bool { return c&CmdFwdMask != 0 }
+
+// Back reports whether the back button is pressed.
+func (c Cmd) Back() bool { return c&CmdBackMask != 0 }
+
+// Left reports whether the left button is pressed.
+func (c Cmd) Left() bool { return c&CmdLeftMask != 0 }
+
+// Right reports whether the right button is pressed.
+func (c Cmd) Right() bool { return c&CmdRightMask != 0 }
+
+// LeftWeapon reports whether the left weapon button is pressed.
+func (c Cmd) LeftWeapon() bool { return c&CmdLeftWeapMask != 0 }
+
+// RightWeapon reports whether the right weapon button is pressed.
+func (c Cmd) RightWeapon() bool { return c&CmdRightWeapMask != 0 }
+
+// IsStop reports whether c is a stop byte, i.e. no buttons are pressed.
+func (c Cmd) IsStop() bool { return c&CmdButtonMask == CmdStop }
+
+var buttonNames = [6]string{"Fwd", "Back", "Left", "Right", "RightWeap", "LeftWeap"}
+
+// String returns a readable form of c, e.g. "CH2 Fwd+Left" or "CH1 Stop".
+func (c Cmd) String() string {
+	s := c.Channel().String()
+	if c.IsStop() {
+		return s + " Stop"
+	}
+	sep := " "
+	for bit, name := range buttonNames {
+		if c&(1<<bit) != 0 {
+			s += sep + name
+			sep = "+"
+		}
+	}
+	return s
+}
+
+func init() {
+	irtrx.RegisterProtocol(irtrx.Protocol{
+		Name:  "hexbug",
+		Usage: "channel buttons | cmd",
+		Encode: func(args ...uint64) (irtrx.FrameMarshaller, error) {
+			switch {
+			case len(args) == 1 && args[0] <= 0xFF:
+				return Cmd(args[0]), nil
+			case len(args) == 2 && args[0] >= 1 && args[0] <= 4 && args[1] <= CmdButtonMask:
+				return Channel(args[0]).Bits() | Cmd(args[1]), nil
+			}
+			return nil, irtrx.ErrArgs
+		},
+	})
+}
+
+// Protocol implements irtrx.Frame.
+func (c Cmd) Protocol() string { return "hexbug" }
+
+// Bits implements irtrx.Frame, returning the 9 bits sent for c, parity bit
+// included.
+func (c Cmd) Bits() []byte {
+	b := uint16(c) & 0xFF
+	if bits.OnesCount16(b)%2 == 0 {
+		b |= 1 << 8
+	}
+	return []byte{byte(b), byte(b >> 8)}
+}
+
 var (
 	hbStart = irtrx.TimePair{1750 * time.Microsecond, 350 * time.Microsecond}
 	hbZero  = irtrx.TimePair{350 * time.Microsecond, 350 * time.Microsecond}
 	hbOne   = irtrx.TimePair{1000 * time.Microsecond, 350 * time.Microsecond}
 )
 
+// UnmarshalTimePairs implements irtrx.FrameUnmarshaller. As with
+// StateMachine, the parity bit is kept as bit 8. Anything before the start
+// flag is skipped.
+func (c *Cmd) UnmarshalTimePairs(pairs []irtrx.TimePair) error {
+	start := -1
+	for i, p := range pairs {
+		if p[0] > 1600*time.Microsecond {
+			start = i
+			break
+		}
+	}
+	if start == -1 || len(pairs) < start+10 {
+		return ErrTruncated
+	}
+	var cmd Cmd
+	var parity bool
+	for bit, p := range pairs[start+1 : start+10] {
+		if p[0] < minBitTime || p[0] > maxOneTime {
+			return ErrTiming
+		}
+		if p[0] > 750*time.Microsecond {
+			cmd |= 1 << bit
+			parity = !parity
+		}
+	}
+	if !parity {
+		return ErrParity
+	}
+	*c = cmd
+	return nil
+}
+
 func (c Cmd) MarshalFrame() []irtrx.TimePair {
 	out := [10]irtrx.TimePair{}
 	out[0] = hbStart
@@ -256,3 +436,18 @@ func (c Cmd) MarshalFrame() []irtrx.TimePair {
 
 	return out[:]
 }
+
+// MarshalBinary implements encoding.BinaryMarshaler. A Cmd is stored as two
+// bytes, little endian, so the parity bit is the low bit of the second.
+func (c Cmd) MarshalBinary() ([]byte, error) {
+	return []byte{byte(c), byte(c>>8) & 0x01}, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (c *Cmd) UnmarshalBinary(b []byte) error {
+	if len(b) != 2 {
+		return irtrx.ErrBinary
+	}
+	*c = Cmd(b[0]) | Cmd(b[1]&0x01)<<8
+	return nil
+}
@@ -0,0 +1,201 @@
+package hexbug_test
+
+import (
+	"math/bits"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/hexbug"
+	"github.com/sparques/irtrx/irtest"
+)
+
+// protocol round trips every hexbug.Cmd. The decoder delivers commands with
+// the parity bit, bit 8, as received, so Random includes it.
+var protocol = irtest.Protocol[hexbug.Cmd]{
+	Name: "hexbug",
+	Random: func(r *rand.Rand) hexbug.Cmd {
+		c := r.Intn(hexbug.CmdButtonMask | hexbug.CmdChannelMask + 1)
+		if bits.OnesCount(uint(c))%2 == 0 {
+			c |= 1 << 8
+		}
+		return hexbug.Cmd(c)
+	},
+	Marshal: func(c hexbug.Cmd) irtrx.FrameMarshaller { return &c },
+	NewDecoder: func(h func(hexbug.Cmd)) irtrx.RxStateMachine {
+		return hexbug.NewStateMachine(h)
+	},
+}
+
+var (
+	start = irtrx.TimePair{1750 * time.Microsecond, 350 * time.Microsecond}
+	zero  = irtrx.TimePair{350 * time.Microsecond, 350 * time.Microsecond}
+	one   = irtrx.TimePair{1000 * time.Microsecond, 350 * time.Microsecond}
+)
+
+func TestRoundTrip(t *testing.T) {
+	irtest.RoundTrip(t, protocol, 1000, 1)
+}
+
+func TestMarshalFrame(t *testing.T) {
+	for _, tc := range []struct {
+		cmd  hexbug.Cmd
+		want []irtrx.TimePair
+	}{
+		// two ones, so the parity bit is set
+		{hexbug.CH2 | hexbug.CmdFwdMask, []irtrx.TimePair{start, one, zero, zero, zero, zero, zero, one, zero, one}},
+		// channel 3 is both channel bits, channel 4 only the second
+		{hexbug.CH3 | hexbug.CmdBackMask, []irtrx.TimePair{start, zero, one, zero, zero, zero, zero, one, one, zero}},
+		{hexbug.CH4 | hexbug.CmdLeftWeapMask, []irtrx.TimePair{start, zero, zero, zero, zero, zero, one, zero, one, one}},
+		// the boundaries: no ones at all and all eight
+		{hexbug.CH1 | hexbug.CmdStop, []irtrx.TimePair{start, zero, zero, zero, zero, zero, zero, zero, zero, one}},
+		{0xFF, []irtrx.TimePair{start, one, one, one, one, one, one, one, one, one}},
+	} {
+		if got := tc.cmd.MarshalFrame(); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%v: MarshalFrame() = %v, want %v", tc.cmd, got, tc.want)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	// what every decoder must do, on random frames
+	irtest.Conform(t, protocol)
+
+	for _, tc := range []struct {
+		cmd  hexbug.Cmd
+		want hexbug.Cmd
+		str  string
+	}{
+		{hexbug.CH2 | hexbug.CmdFwdMask, 0x141, "CH2 Fwd"},
+		{hexbug.CH3 | hexbug.CmdBackMask | hexbug.CmdRightMask, 0x1CA, "CH3 Back+Right"},
+		{hexbug.CH1 | hexbug.CmdStop, 0x100, "CH1 Stop"},
+		{0xFF, 0x1FF, "CH3 Fwd+Back+Left+Right+RightWeap+LeftWeap"},
+	} {
+		var got []hexbug.Cmd
+		w := irtest.NewWire(hexbug.NewStateMachine(func(c hexbug.Cmd) { got = append(got, c) }), false)
+		w.SendFrame(tc.cmd)
+		if len(got) != 1 || got[0] != tc.want {
+			t.Errorf("%v: decoded %v, want %#x", tc.cmd, got, tc.want)
+			continue
+		}
+		if s := got[0].String(); s != tc.str {
+			t.Errorf("%#x: String() = %q, want %q", tc.want, s, tc.str)
+		}
+	}
+}
+
+func TestBits(t *testing.T) {
+	c := hexbug.Cmd(hexbug.CH2 | hexbug.CmdFwdMask)
+	if got, want := c.Bits(), []byte{0x41, 0x01}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Bits() = %x, want %x", got, want)
+	}
+	// already odd, so no parity bit
+	c = hexbug.CH1 | hexbug.CmdFwdMask
+	if got, want := c.Bits(), []byte{0x01, 0x00}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Bits() = %x, want %x", got, want)
+	}
+}
+
+func TestUnmarshalTimePairs(t *testing.T) {
+	frame := []irtrx.TimePair{start, one, zero, zero, zero, zero, zero, one, zero, one}
+	with := func(f []irtrx.TimePair, i int, p irtrx.TimePair) []irtrx.TimePair {
+		f = append([]irtrx.TimePair(nil), f...)
+		f[i] = p
+		return f
+	}
+	for _, tc := range []struct {
+		name  string
+		pairs []irtrx.TimePair
+		want  hexbug.Cmd
+		err   error
+	}{
+		{"Known", frame, 0x141, nil},
+		{"Leading", append([]irtrx.TimePair{zero, one}, frame...), 0x141, nil},
+		// either side of the 750us threshold between a zero and a one
+		{"LongZero", with(frame, 2, irtrx.TimePair{740 * time.Microsecond, 350 * time.Microsecond}), 0x141, nil},
+		{"ShortOne", with(with(frame, 2, irtrx.TimePair{760 * time.Microsecond, 350 * time.Microsecond}), 9, zero), 0x043, nil},
+		{"LongOne", with(frame, 1, irtrx.TimePair{1250 * time.Microsecond, 350 * time.Microsecond}), 0x141, nil},
+		{"Empty", nil, 0, hexbug.ErrTruncated},
+		{"NoStart", frame[1:], 0, hexbug.ErrTruncated},
+		{"Truncated", frame[:9], 0, hexbug.ErrTruncated},
+		{"Parity", with(frame, 9, zero), 0, hexbug.ErrParity},
+		{"Glitch", with(frame, 3, irtrx.TimePair{100 * time.Microsecond, 350 * time.Microsecond}), 0, hexbug.ErrTiming},
+		{"TooLong", with(frame, 3, irtrx.TimePair{1400 * time.Microsecond, 350 * time.Microsecond}), 0, hexbug.ErrTiming},
+	} {
+		var c hexbug.Cmd
+		err := c.UnmarshalTimePairs(tc.pairs)
+		if err != tc.err || c != tc.want {
+			t.Errorf("%s: got %#x, %v; want %#x, %v", tc.name, c, err, tc.want, tc.err)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	var got []hexbug.Cmd
+	var errs []hexbug.DecodeError
+	hb := hexbug.NewStateMachine(func(c hexbug.Cmd) { got = append(got, c) })
+	hb.SetErrorHandler(func(de hexbug.DecodeError, _ hexbug.Cmd) { errs = append(errs, de) })
+	w := irtest.NewWire(hb, false)
+
+	frame := []irtrx.TimePair{start, one, zero, zero, zero, zero, zero, one, zero, one}
+	bad := append([]irtrx.TimePair(nil), frame...)
+	bad[9] = zero
+	w.SendPairs(bad...)
+	// cut short by the next start flag
+	w.SendPairs(frame[:5]...)
+	w.SendPairs(frame...)
+	bad[3] = irtrx.TimePair{100 * time.Microsecond, 350 * time.Microsecond}
+	w.SendPairs(bad...)
+
+	if len(got) != 1 || got[0] != 0x141 {
+		t.Errorf("decoded %v, want [0x141]", got)
+	}
+	if want := []hexbug.DecodeError{hexbug.ErrParity, hexbug.ErrTruncated, hexbug.ErrTiming}; !reflect.DeepEqual(errs, want) {
+		t.Errorf("errors %v, want %v", errs, want)
+	}
+	if got, want := hb.Errors(), (hexbug.Errors{Parity: 1, Truncated: 1, Timing: 1}); got != want {
+		t.Errorf("Errors() = %+v, want %+v", got, want)
+	}
+	hb.ResetErrors()
+	if got := hb.Errors(); got != (hexbug.Errors{}) {
+		t.Errorf("after ResetErrors: %+v", got)
+	}
+}
+
+func TestBinary(t *testing.T) {
+	c := hexbug.Cmd(0x141)
+	b, _ := c.MarshalBinary()
+	if want := []byte{0x41, 0x01}; !reflect.DeepEqual(b, want) {
+		t.Errorf("MarshalBinary() = %x, want %x", b, want)
+	}
+	var got hexbug.Cmd
+	if err := got.UnmarshalBinary(b); err != nil || got != c {
+		t.Errorf("UnmarshalBinary(%x) = %#x, %v", b, got, err)
+	}
+	for _, b := range [][]byte{nil, {0x41}, {0x41, 0x01, 0x00}} {
+		if err := got.UnmarshalBinary(b); err != irtrx.ErrBinary {
+			t.Errorf("UnmarshalBinary(%x) = %v, want %v", b, err, irtrx.ErrBinary)
+		}
+	}
+}
+
+// TestCorpus replays the captures in testdata, each of which holds the
+// value it should decode to.
+func TestCorpus(t *testing.T) {
+	caps, err := irtest.LoadCorpus("testdata/captures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	irtest.ReplayCorpus(t, caps, "hexbug", protocol.NewDecoder)
+}
+
+func BenchmarkStateMachine(b *testing.B) {
+	c := hexbug.Cmd(hexbug.CH2 | hexbug.CmdFwdMask | hexbug.CmdLeftMask)
+	irtest.BenchmarkDecoder(b, irtest.Bench{
+		Name:  "hexbug",
+		New:   func() irtrx.RxStateMachine { return hexbug.NewStateMachine(func(hexbug.Cmd) {}) },
+		Frame: &c,
+	})
+}
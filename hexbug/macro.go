@@ -0,0 +1,95 @@
+package hexbug
+
+import (
+	"time"
+
+	"github.com/sparques/irtrx"
+)
+
+// Step is a single command in a Macro.
+type Step struct {
+	// At is when the command happened, relative to the start of the Macro.
+	At  time.Duration
+	Cmd Cmd
+}
+
+// Macro is a timed sequence of commands, for repeatable test runs or "demo
+// mode" behaviors.
+type Macro []Step
+
+// Play calls handler with each command at its recorded time, e.g. to feed a
+// robot's control loop. It blocks until the Macro is done or stop is closed
+// (stop may be nil).
+func (m Macro) Play(handler func(Cmd), stop <-chan struct{}) {
+	m.PlayClock(irtrx.RealClock, handler, stop)
+}
+
+// PlayClock is Play timed by c.
+func (m Macro) PlayClock(c irtrx.Clock, handler func(Cmd), stop <-chan struct{}) {
+	start := c.Now()
+	for _, step := range m {
+		if d := step.At - c.Now().Sub(start); d > 0 {
+			select {
+			case <-stop:
+				return
+			case <-irtrx.After(c, d):
+			}
+		}
+		handler(step.Cmd)
+	}
+}
+
+// Transmit re-transmits the Macro's commands with tx at their recorded
+// times, so a recorded session can drive a stock hexbug.
+func (m Macro) Transmit(tx irtrx.Transmitter, stop <-chan struct{}) {
+	m.TransmitClock(irtrx.RealClock, tx, stop)
+}
+
+// TransmitClock is Transmit timed by c.
+func (m Macro) TransmitClock(c irtrx.Clock, tx irtrx.Transmitter, stop <-chan struct{}) {
+	m.PlayClock(c, func(cmd Cmd) {
+		tx.SendFrame(cmd)
+	}, stop)
+}
+
+// MacroRecorder records decoded commands into a Macro. Pass its HandleCmd
+// method as (or call it from) the StateMachine's command handler.
+type MacroRecorder struct {
+	steps     Macro
+	start     time.Time
+	recording bool
+	clock     irtrx.Clock
+}
+
+// NewMacroRecorder returns a MacroRecorder with room for size steps. Space
+// is allocated up front since commands are recorded from interrupt context;
+// once full, further commands are dropped.
+func NewMacroRecorder(size int) *MacroRecorder {
+	return &MacroRecorder{steps: make(Macro, 0, size), clock: irtrx.RealClock}
+}
+
+// SetClock replaces the clock used to time steps.
+func (mr *MacroRecorder) SetClock(c irtrx.Clock) {
+	mr.clock = c
+}
+
+// Start discards anything previously recorded and starts recording.
+func (mr *MacroRecorder) Start() {
+	mr.steps = mr.steps[:0]
+	mr.start = mr.clock.Now()
+	mr.recording = true
+}
+
+// Stop stops recording and returns a copy of what was recorded.
+func (mr *MacroRecorder) Stop() Macro {
+	mr.recording = false
+	return append(Macro(nil), mr.steps...)
+}
+
+// HandleCmd records cmd if recording.
+func (mr *MacroRecorder) HandleCmd(cmd Cmd) {
+	if !mr.recording || len(mr.steps) == cap(mr.steps) {
+		return
+	}
+	mr.steps = append(mr.steps, Step{At: mr.clock.Now().Sub(mr.start), Cmd: cmd})
+}
@@ -0,0 +1,66 @@
+package hexbug
+
+import (
+	"time"
+
+	"github.com/sparques/irtrx"
+)
+
+// Sighting is the most recent command seen on a channel.
+type Sighting struct {
+	Cmd Cmd
+	// At is when Cmd was received; zero if nothing has been seen.
+	At time.Time
+}
+
+// Snooper keeps track of the most recent command on each of the four
+// channels at once, rather than filtering to one. It is handy for arena
+// scorekeepers, spectator displays, and working out which remote is on which
+// channel. Pass its HandleCmd method as (or call it from) the StateMachine's
+// command handler, or use it as a Dispatcher's snoop handler.
+type Snooper struct {
+	// indexed by Channel-1
+	seen  [4]Sighting
+	clock irtrx.Clock
+}
+
+// NewSnooper returns an empty Snooper.
+func NewSnooper() *Snooper {
+	return &Snooper{clock: irtrx.RealClock}
+}
+
+// SetClock replaces the clock used to time sightings.
+func (s *Snooper) SetClock(c irtrx.Clock) {
+	s.clock = c
+}
+
+// HandleCmd records cmd as the latest on its channel.
+func (s *Snooper) HandleCmd(cmd Cmd) {
+	s.seen[cmd.Channel()-Channel1] = Sighting{Cmd: cmd, At: s.clock.Now()}
+}
+
+// Last returns the most recent command on channel, and whether anything has
+// been seen on it.
+func (s *Snooper) Last(channel Channel) (Sighting, bool) {
+	if channel < Channel1 || channel > Channel4 {
+		return Sighting{}, false
+	}
+	sg := s.seen[channel-Channel1]
+	return sg, !sg.At.IsZero()
+}
+
+// Active returns the channels that have had a command within d.
+func (s *Snooper) Active(d time.Duration) []Channel {
+	var out []Channel
+	for i, sg := range s.seen {
+		if !sg.At.IsZero() && s.clock.Now().Sub(sg.At) <= d {
+			out = append(out, Channel(i)+Channel1)
+		}
+	}
+	return out
+}
+
+// All returns the latest Sighting on each channel, indexed by Channel-1.
+func (s *Snooper) All() [4]Sighting {
+	return s.seen
+}
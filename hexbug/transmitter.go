@@ -0,0 +1,160 @@
+package hexbug
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/sparques/irtrx"
+)
+
+const (
+	// PressGap is the gap between the doubled frames sent on a press.
+	PressGap = 6 * time.Millisecond
+	// StopGap is the gap between the stop bytes sent on release.
+	StopGap = 200 * time.Millisecond
+	// StopCount is the number of stop bytes sent on release.
+	StopCount = 10
+)
+
+// Transmitter emulates a HEXBUG BattleBots remote, so a board with an IR LED
+// can drive stock hexbugs. It reproduces what the real remote sends: a
+// doubled frame when buttons are pressed, repeats while they're held, and
+// StopCount stop bytes when they're released.
+type Transmitter struct {
+	tx      irtrx.Transmitter
+	channel Channel
+	buttons Cmd
+
+	// RepeatInterval is the time between repeated frames while buttons are
+	// held. I haven't measured the real remote's interval; 50ms works fine.
+	RepeatInterval time.Duration
+
+	// for Run
+	desired atomic.Int32
+	changed chan struct{}
+	clock   irtrx.Clock
+}
+
+// NewTransmitter returns a Transmitter sending on channel via tx.
+func NewTransmitter(tx irtrx.Transmitter, channel Channel) *Transmitter {
+	return &Transmitter{
+		tx:             tx,
+		channel:        channel,
+		RepeatInterval: 50 * time.Millisecond,
+		changed:        make(chan struct{}, 1),
+		clock:          irtrx.RealClock,
+	}
+}
+
+// SetClock replaces the clock used to time repeats and gaps.
+func (t *Transmitter) SetClock(c irtrx.Clock) {
+	t.clock = c
+}
+
+func (t *Transmitter) cmd() Cmd {
+	return t.channel.Bits() | t.buttons
+}
+
+// Press presses buttons (a combination of the Cmd*Mask constants), sending
+// the doubled frame the remote sends on a press. Buttons stay pressed until
+// Release.
+func (t *Transmitter) Press(buttons Cmd) {
+	t.buttons = buttons & CmdButtonMask
+	t.tx.SendFrame(t.cmd())
+	t.clock.Sleep(PressGap)
+	t.tx.SendFrame(t.cmd())
+}
+
+// Repeat sends the currently pressed buttons once. Call it every
+// RepeatInterval while buttons are held.
+func (t *Transmitter) Repeat() {
+	t.tx.SendFrame(t.cmd())
+}
+
+// Hold presses buttons, holds them for d while sending repeats, then
+// releases them. It blocks for d plus the time taken by Release.
+func (t *Transmitter) Hold(buttons Cmd, d time.Duration) {
+	start := t.clock.Now()
+	t.Press(buttons)
+	for t.clock.Now().Sub(start)+t.RepeatInterval < d {
+		t.clock.Sleep(t.RepeatInterval)
+		t.Repeat()
+	}
+	t.Release()
+}
+
+// Release releases all buttons and sends the stop bytes. This takes about
+// two seconds.
+func (t *Transmitter) Release() {
+	t.buttons = CmdStop
+	t.sendStops()
+}
+
+// SetChannel changes the channel and, like the real remote, sends stop bytes
+// on the new channel.
+func (t *Transmitter) SetChannel(channel Channel) {
+	t.channel = channel
+	t.buttons = CmdStop
+	t.sendStops()
+}
+
+func (t *Transmitter) sendStops() {
+	for i := 0; i < StopCount; i++ {
+		if i != 0 {
+			t.clock.Sleep(StopGap)
+		}
+		t.tx.SendFrame(t.cmd())
+	}
+}
+
+// SetButtons sets which buttons are held, for use with Run. It never
+// blocks; Run takes care of the presses, repeats and stop bytes.
+func (t *Transmitter) SetButtons(buttons Cmd) {
+	t.desired.Store(int32(buttons & CmdButtonMask))
+	select {
+	case t.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Run manages transmission in the background based on SetButtons, until stop
+// is closed: new presses get the doubled frame, held buttons are repeated
+// every RepeatInterval, and releases get StopCount stop bytes spaced StopGap
+// apart (cut short if buttons are pressed again). Run it in its own
+// goroutine, and don't call Press, Hold or Release while it runs.
+func (t *Transmitter) Run(stop <-chan struct{}) {
+	stops := 0
+	var next time.Time
+	for {
+		want := Cmd(t.desired.Load())
+		now := t.clock.Now()
+		switch {
+		case want != CmdStop && want != t.buttons:
+			t.Press(want)
+			stops = StopCount
+			next = now.Add(t.RepeatInterval)
+		case now.Before(next):
+			// woken early; nothing due yet
+		case want != CmdStop:
+			t.Repeat()
+			next = now.Add(t.RepeatInterval)
+		case stops > 0:
+			t.buttons = CmdStop
+			t.tx.SendFrame(t.cmd())
+			stops--
+			next = now.Add(StopGap)
+		}
+
+		// with nothing left to send, wait stays nil and we wait for a change
+		var wait <-chan time.Time
+		if want != CmdStop || stops > 0 {
+			wait = irtrx.After(t.clock, next.Sub(t.clock.Now()))
+		}
+		select {
+		case <-stop:
+			return
+		case <-t.changed:
+		case <-wait:
+		}
+	}
+}
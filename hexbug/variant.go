@@ -0,0 +1,93 @@
+package hexbug
+
+import (
+	"fmt"
+
+	"github.com/sparques/irtrx"
+)
+
+// MaxExtendedBits is the longest frame an extended-aware StateMachine will
+// accumulate.
+const MaxExtendedBits = 32
+
+// Variant identifies which family of HEXBUG remote sent a frame.
+type Variant uint8
+
+const (
+	// BattleBots is the 6-button, 4-channel remote with 9 bit frames that
+	// the rest of this package decodes.
+	BattleBots Variant = iota
+	// Extended covers newer remotes (dual-stick, vehicles with extra
+	// functions) that use the same bit timings but longer frames.
+	Extended
+)
+
+func (v Variant) String() string {
+	switch v {
+	case BattleBots:
+		return "BattleBots"
+	case Extended:
+		return "Extended"
+	}
+	return "Unknown"
+}
+
+// ExtCmd is a frame from a remote that isn't the BattleBots remote. The
+// layouts of these remotes haven't been worked out, so the bits are
+// delivered raw, LSB first as with Cmd.
+type ExtCmd struct {
+	Variant Variant
+	// Bits holds the frame's bits, first received in bit 0.
+	Bits uint32
+	// Len is the number of bits in the frame.
+	Len int
+	// Parity reports whether the frame has odd parity, as BattleBots
+	// frames do.
+	Parity bool
+}
+
+func (ec ExtCmd) String() string {
+	return fmt.Sprintf("{%v %d bits: %0*b}", ec.Variant, ec.Len, ec.Len, ec.Bits)
+}
+
+// SetExtendedHandler turns on decoding of extended remotes: frames of any
+// length up to MaxExtendedBits are accepted. 9 bit frames still go to the
+// command handler as usual; anything else goes to extHandler. Pass nil to go
+// back to BattleBots frames only.
+//
+// Since extended frames vary in length, the end of a frame is only known
+// from the gap after it, which the StateMachine sees when the next mark
+// arrives, so commands are delivered one frame later than usual while this
+// is on. To get the last frame of a burst, have the RxDevice flush once the
+// remote goes quiet:
+//
+//	hb.SetExtendedHandler(onExt)
+//	rx := irtrx.NewRxDevice(rxPin, hb)
+//	rx.SetAutoFlush(5 * time.Millisecond)
+//	rx.Start()
+func (hb *StateMachine) SetExtendedHandler(extHandler func(ExtCmd)) {
+	hb.extHandler = extHandler
+}
+
+// Flush implements irtrx.Flusher, ending the frame in progress as the gap
+// after it would. Call it, or have RxDevice.Flush call it, once the line has
+// been quiet for longer than a frame's spaces.
+func (hb *StateMachine) Flush() {
+	hb.endFrame()
+}
+
+// endExtended delivers the frame accumulated so far, of whatever length.
+func (hb *StateMachine) endExtended() {
+	if hb.bitcount == 9 {
+		hb.deliver()
+		return
+	}
+	hb.extHandler(ExtCmd{
+		Variant: Extended,
+		Bits:    hb.extbuf,
+		Len:     hb.bitcount,
+		Parity:  hb.parity,
+	})
+}
+
+var _ irtrx.Flusher = (*StateMachine)(nil)
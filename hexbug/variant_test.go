@@ -0,0 +1,79 @@
+package hexbug_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/hexbug"
+	"github.com/sparques/irtrx/irtest"
+)
+
+// extFrame returns the pairs of an extended frame of n bits holding bits.
+func extFrame(bits uint32, n int) []irtrx.TimePair {
+	pairs := []irtrx.TimePair{{1750 * time.Microsecond, 350 * time.Microsecond}}
+	for i := 0; i < n; i++ {
+		mark := 350 * time.Microsecond
+		if bits>>i&1 != 0 {
+			mark = 1000 * time.Microsecond
+		}
+		pairs = append(pairs, irtrx.TimePair{mark, 350 * time.Microsecond})
+	}
+	return pairs
+}
+
+func TestExtendedFlush(t *testing.T) {
+	var got []hexbug.ExtCmd
+	hb := hexbug.NewStateMachine(func(hexbug.Cmd) {})
+	hb.SetExtendedHandler(func(ec hexbug.ExtCmd) { got = append(got, ec) })
+	w := irtest.NewWire(hb, false)
+
+	w.SendPairs(extFrame(0b101100111011, 12)...)
+	w.SendPairs(extFrame(0b1, 12)...)
+	if len(got) != 1 {
+		t.Fatalf("before Flush: got %v, want the first frame only", got)
+	}
+	hb.Flush()
+	want := []hexbug.ExtCmd{
+		{Variant: hexbug.Extended, Bits: 0b101100111011, Len: 12},
+		{Variant: hexbug.Extended, Bits: 0b1, Len: 12, Parity: true},
+	}
+	if len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	hb.Flush()
+	if len(got) != 2 {
+		t.Errorf("second Flush delivered %v", got[2:])
+	}
+}
+
+func TestExtendedEndsOnGap(t *testing.T) {
+	var got []hexbug.ExtCmd
+	hb := hexbug.NewStateMachine(func(hexbug.Cmd) {})
+	hb.SetExtendedHandler(func(ec hexbug.ExtCmd) { got = append(got, ec) })
+	w := irtest.NewWire(hb, false)
+
+	frame := extFrame(0b110, 12)
+	frame[len(frame)-1][1] = 20 * time.Millisecond
+	w.SendPairs(frame...)
+	// a stray mark, not a start flag, after the gap
+	w.SendPair(irtrx.TimePair{350 * time.Microsecond, 350 * time.Microsecond})
+	want := hexbug.ExtCmd{Variant: hexbug.Extended, Bits: 0b110, Len: 12}
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("got %v, want [%v]", got, want)
+	}
+}
+
+func TestFlushTruncated(t *testing.T) {
+	var errs []hexbug.DecodeError
+	hb := hexbug.NewStateMachine(func(c hexbug.Cmd) { t.Errorf("decoded %v", c) })
+	hb.SetErrorHandler(func(de hexbug.DecodeError, _ hexbug.Cmd) { errs = append(errs, de) })
+	w := irtest.NewWire(hb, false)
+
+	c := hexbug.Cmd(hexbug.CmdFwdMask)
+	w.SendPairs(c.MarshalFrame()[:6]...)
+	hb.Flush()
+	if len(errs) != 1 || errs[0] != hexbug.ErrTruncated {
+		t.Errorf("errors %v, want [%v]", errs, hexbug.ErrTruncated)
+	}
+}
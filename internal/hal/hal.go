@@ -0,0 +1,8 @@
+// hal is the hardware abstraction the rest of the module is written against.
+// Under TinyGo it is nothing but aliases for machine and
+// github.com/sparques/pwm. Under standard Go it is a simulation: pins hold a
+// level and fire their interrupt handlers when it changes, and PWM channels
+// drive their pin high while their duty cycle is non-zero, i.e. the pin
+// follows the envelope of the carrier. This lets the whole module build,
+// and be exercised, on a desktop.
+package hal
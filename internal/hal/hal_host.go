@@ -0,0 +1,248 @@
+//go:build !tinygo
+
+package hal
+
+import (
+	"errors"
+	"sync"
+)
+
+// Pin is a simulated GPIO pin.
+type Pin uint8
+
+// NoPin is used where a pin isn't connected.
+const NoPin Pin = 0xff
+
+type PinMode uint8
+
+const (
+	PinInput PinMode = iota
+	PinOutput
+	PinPWM
+)
+
+type PinConfig struct {
+	Mode PinMode
+}
+
+type PinChange uint8
+
+const (
+	PinRising PinChange = 1 << iota
+	PinFalling
+)
+
+type pinState struct {
+	mode     PinMode
+	level    bool
+	change   PinChange
+	callback func(Pin)
+	watchers []func(Pin, bool)
+}
+
+var (
+	mu   sync.Mutex
+	pins [NoPin]pinState
+	// irq is held while an interrupt handler runs, and by
+	// DisableInterrupts, so the two exclude each other as on hardware
+	irq sync.Mutex
+)
+
+// InterruptState is returned by DisableInterrupts.
+type InterruptState struct{}
+
+// DisableInterrupts waits for any running interrupt handler to return and
+// holds off the rest until RestoreInterrupts. Don't call it from an
+// interrupt handler.
+func DisableInterrupts() InterruptState {
+	irq.Lock()
+	return InterruptState{}
+}
+
+// RestoreInterrupts undoes DisableInterrupts.
+func RestoreInterrupts(InterruptState) {
+	irq.Unlock()
+}
+
+// Configure sets the pin's mode.
+func (p Pin) Configure(cfg PinConfig) {
+	if p == NoPin {
+		return
+	}
+	mu.Lock()
+	pins[p].mode = cfg.Mode
+	mu.Unlock()
+}
+
+// Get returns the pin's level.
+func (p Pin) Get() bool {
+	if p == NoPin {
+		return false
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	return pins[p].level
+}
+
+// Set changes the pin's level, calling its interrupt handler and watchers if
+// it changed. This is how simulated signals are fed to input pins.
+func (p Pin) Set(high bool) {
+	if p == NoPin {
+		return
+	}
+	mu.Lock()
+	st := &pins[p]
+	if st.level == high {
+		mu.Unlock()
+		return
+	}
+	st.level = high
+	var cb func(Pin)
+	if (high && st.change&PinRising != 0) || (!high && st.change&PinFalling != 0) {
+		cb = st.callback
+	}
+	watchers := st.watchers
+	mu.Unlock()
+
+	if cb != nil {
+		irq.Lock()
+		cb(p)
+		irq.Unlock()
+	}
+	for _, w := range watchers {
+		w(p, high)
+	}
+}
+
+func (p Pin) High() { p.Set(true) }
+func (p Pin) Low()  { p.Set(false) }
+
+// SetInterrupt sets the handler called on the given level changes. A nil
+// callback removes it.
+func (p Pin) SetInterrupt(change PinChange, callback func(Pin)) error {
+	if p == NoPin {
+		return errors.New("hal: no pin")
+	}
+	mu.Lock()
+	pins[p].change = change
+	pins[p].callback = callback
+	mu.Unlock()
+	return nil
+}
+
+// Watch calls fn on every level change of pin, after its interrupt handler.
+// It is how simulations observe outputs, e.g. to wire a transmitter's pin to
+// a receiver's.
+func Watch(pin Pin, fn func(Pin, bool)) {
+	if pin == NoPin {
+		return
+	}
+	mu.Lock()
+	pins[pin].watchers = append(pins[pin].watchers, fn)
+	mu.Unlock()
+}
+
+// Reset returns every pin and PWM slice to its initial state, dropping
+// interrupt handlers and watchers.
+func Reset() {
+	mu.Lock()
+	pins = [NoPin]pinState{}
+	mu.Unlock()
+	for i := range slices {
+		s := &slices[i]
+		s.mu.Lock()
+		s.top, s.duty, s.pins, s.claimed = 0, [2]uint32{}, [2]Pin{}, [2]bool{}
+		s.mu.Unlock()
+	}
+}
+
+type PWMConfig struct {
+	Period uint64
+}
+
+// PWMGroup mirrors github.com/sparques/pwm's Group.
+type PWMGroup interface {
+	Configure(PWMConfig) error
+	Channel(Pin) (uint8, error)
+	SetPeriod(uint64) error
+	Set(uint8, uint32)
+	Get(uint8) uint32
+	Top() uint32
+}
+
+// simPWM simulates an RP2040 PWM slice: two channels, on pins 2n and 2n+1
+// (mod 16), counting at 125MHz.
+type simPWM struct {
+	mu   sync.Mutex
+	top  uint32
+	duty [2]uint32
+	pins [2]Pin
+	// claimed is set for channels whose pin is known
+	claimed [2]bool
+}
+
+var slices [8]simPWM
+
+// GetPWM returns the simulated PWM slice driving pin.
+func GetPWM(pin Pin) PWMGroup {
+	return &slices[(pin/2)%8]
+}
+
+func (s *simPWM) Configure(cfg PWMConfig) error {
+	return s.SetPeriod(cfg.Period)
+}
+
+func (s *simPWM) Channel(pin Pin) (uint8, error) {
+	ch := uint8(pin % 2)
+	s.mu.Lock()
+	s.pins[ch] = pin
+	s.claimed[ch] = true
+	s.mu.Unlock()
+	return ch, nil
+}
+
+// SetPeriod sets the period in ns, slowing the counter with an integer
+// divider, as the RP2040's clock divider does, for periods beyond its 16
+// bits.
+func (s *simPWM) SetPeriod(period uint64) error {
+	top := period * 125 / 1000
+	div := (top + 0xFFFF) / (0xFFFF + 1)
+	if top == 0 || div > 255 {
+		return errors.New("hal: period out of range")
+	}
+	top /= max(div, 1)
+	s.mu.Lock()
+	s.top = uint32(top - 1)
+	s.mu.Unlock()
+	return nil
+}
+
+// Set sets a channel's duty cycle. The channel's pin, if in PinPWM mode, is
+// high while the duty cycle is non-zero.
+func (s *simPWM) Set(ch uint8, v uint32) {
+	s.mu.Lock()
+	s.duty[ch] = v
+	pin, claimed := s.pins[ch], s.claimed[ch]
+	s.mu.Unlock()
+	if !claimed {
+		return
+	}
+	mu.Lock()
+	pwmMode := pins[pin].mode == PinPWM
+	mu.Unlock()
+	if pwmMode {
+		pin.Set(v != 0)
+	}
+}
+
+func (s *simPWM) Get(ch uint8) uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.duty[ch]
+}
+
+func (s *simPWM) Top() uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.top
+}
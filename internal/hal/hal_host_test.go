@@ -0,0 +1,181 @@
+//go:build !tinygo
+
+package hal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPinLevel(t *testing.T) {
+	defer Reset()
+	p := Pin(1)
+	if p.Get() {
+		t.Fatal("pin starts high")
+	}
+	p.High()
+	if !p.Get() {
+		t.Error("High: pin low")
+	}
+	p.Low()
+	if p.Get() {
+		t.Error("Low: pin high")
+	}
+
+	NoPin.Set(true)
+	if NoPin.Get() {
+		t.Error("NoPin reads high")
+	}
+	if err := NoPin.SetInterrupt(PinRising, func(Pin) {}); err == nil {
+		t.Error("SetInterrupt on NoPin succeeded")
+	}
+}
+
+func TestInterrupt(t *testing.T) {
+	defer Reset()
+	p := Pin(1)
+	var edges []bool
+	p.SetInterrupt(PinRising, func(p Pin) { edges = append(edges, p.Get()) })
+	p.Set(true)
+	p.Set(true) // no change, no interrupt
+	p.Set(false)
+	if len(edges) != 1 || !edges[0] {
+		t.Errorf("rising only: handler saw %v, want [true]", edges)
+	}
+
+	edges = nil
+	p.SetInterrupt(PinRising|PinFalling, func(p Pin) { edges = append(edges, p.Get()) })
+	p.Set(true)
+	p.Set(false)
+	if len(edges) != 2 || !edges[0] || edges[1] {
+		t.Errorf("both edges: handler saw %v, want [true false]", edges)
+	}
+
+	edges = nil
+	p.SetInterrupt(PinRising|PinFalling, nil)
+	p.Set(true)
+	if len(edges) != 0 {
+		t.Errorf("handler removed but saw %v", edges)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	defer Reset()
+	p := Pin(1)
+	var order []string
+	p.SetInterrupt(PinRising|PinFalling, func(Pin) { order = append(order, "irq") })
+	Watch(p, func(_ Pin, high bool) {
+		if high {
+			order = append(order, "watch high")
+		} else {
+			order = append(order, "watch low")
+		}
+	})
+	p.Set(true)
+	p.Set(false)
+	want := []string{"irq", "watch high", "irq", "watch low"}
+	if len(order) != len(want) {
+		t.Fatalf("got %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("got %v, want %v", order, want)
+		}
+	}
+
+	Reset()
+	order = nil
+	p.Set(true)
+	if p.Get() != true || len(order) != 0 {
+		t.Errorf("after Reset: handlers still called: %v", order)
+	}
+}
+
+func TestDisableInterrupts(t *testing.T) {
+	defer Reset()
+	p := Pin(1)
+	ran := make(chan struct{})
+	p.SetInterrupt(PinRising, func(Pin) { close(ran) })
+
+	state := DisableInterrupts()
+	go p.Set(true)
+	select {
+	case <-ran:
+		t.Fatal("interrupt handler ran with interrupts disabled")
+	case <-time.After(20 * time.Millisecond):
+	}
+	RestoreInterrupts(state)
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("interrupt handler didn't run once interrupts were restored")
+	}
+}
+
+func TestPWMSlices(t *testing.T) {
+	defer Reset()
+	if GetPWM(2) != GetPWM(3) {
+		t.Error("pins 2 and 3 are on different slices")
+	}
+	if GetPWM(2) == GetPWM(4) || GetPWM(0) != GetPWM(16) {
+		t.Error("slices don't repeat every 16 pins")
+	}
+	g := GetPWM(2)
+	for pin, want := range map[Pin]uint8{2: 0, 3: 1} {
+		if ch, err := g.Channel(pin); err != nil || ch != want {
+			t.Errorf("Channel(%d) = %d, %v; want %d", pin, ch, err, want)
+		}
+	}
+}
+
+func TestPWMPeriod(t *testing.T) {
+	defer Reset()
+	g := GetPWM(2)
+	for _, tc := range []struct {
+		period uint64
+		top    uint32
+		ok     bool
+	}{
+		// 38kHz: 26315ns is 3289 counts at 125MHz
+		{uint64(1e9) / 38000, 3288, true},
+		{524288, 65535, true},
+		// 20ms needs a divider of 39: 2500000/39 is 64102 counts
+		{20e6, 64101, true},
+		{0, 0, false},
+		{200e6, 0, false},
+	} {
+		err := g.Configure(PWMConfig{Period: tc.period})
+		if (err == nil) != tc.ok {
+			t.Errorf("period %dns: err %v", tc.period, err)
+			continue
+		}
+		if tc.ok && g.Top() != tc.top {
+			t.Errorf("period %dns: top %d, want %d", tc.period, g.Top(), tc.top)
+		}
+	}
+}
+
+func TestPWMDrivesPin(t *testing.T) {
+	defer Reset()
+	out, plain := Pin(2), Pin(3)
+	out.Configure(PinConfig{Mode: PinPWM})
+	g := GetPWM(out)
+	g.Configure(PWMConfig{Period: uint64(1e9) / 38000})
+	chOut, _ := g.Channel(out)
+	chPlain, _ := g.Channel(plain)
+
+	g.Set(chOut, g.Top()/2)
+	if !out.Get() || g.Get(chOut) != g.Top()/2 {
+		t.Errorf("duty %d: pin %v, want high", g.Get(chOut), out.Get())
+	}
+	g.Set(chOut, 0)
+	if out.Get() {
+		t.Error("duty 0: pin high")
+	}
+
+	// a pin not in PinPWM mode isn't driven
+	g.Set(chPlain, g.Top())
+	if plain.Get() {
+		t.Error("pin not in PinPWM mode driven high")
+	}
+}
@@ -0,0 +1,47 @@
+//go:build tinygo
+
+package hal
+
+import (
+	"machine"
+	"runtime/interrupt"
+
+	"github.com/sparques/pwm"
+)
+
+type (
+	Pin       = machine.Pin
+	PinConfig = machine.PinConfig
+	PinMode   = machine.PinMode
+	PinChange = machine.PinChange
+	PWMConfig = machine.PWMConfig
+	PWMGroup  = pwm.Group
+)
+
+const (
+	PinInput   = machine.PinInput
+	PinOutput  = machine.PinOutput
+	PinPWM     = machine.PinPWM
+	PinRising  = machine.PinRising
+	PinFalling = machine.PinFalling
+	NoPin      = machine.NoPin
+)
+
+// GetPWM returns the PWM peripheral driving pin.
+func GetPWM(pin Pin) PWMGroup {
+	return pwm.Get(pin)
+}
+
+// InterruptState is returned by DisableInterrupts.
+type InterruptState = interrupt.State
+
+// DisableInterrupts masks interrupts until RestoreInterrupts, so state shared
+// with interrupt handlers can be changed safely.
+func DisableInterrupts() InterruptState {
+	return interrupt.Disable()
+}
+
+// RestoreInterrupts undoes DisableInterrupts.
+func RestoreInterrupts(state InterruptState) {
+	interrupt.Restore(state)
+}
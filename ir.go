@@ -1,6 +1,9 @@
 package irtrx
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 const (
 	// Freq38Khz is the most commonly used frequency for IR remotes
@@ -14,3 +17,64 @@ type TimePair [2]time.Duration
 type FrameMarshaller interface {
 	MarshalFrame() []TimePair
 }
+
+// FrameUnmarshaller is the mirror of FrameMarshaller, decoding a single frame
+// from mark-space pairs such as those produced by MarshalFrame or held in a
+// Recording. It lets tools decode stored captures without feeding them
+// through an RxStateMachine. Protocols whose frames fit in a word also have
+// an UnmarshalFrame method taking the raw bits.
+type FrameUnmarshaller interface {
+	UnmarshalTimePairs(pairs []TimePair) error
+}
+
+// Frame is implemented by the decoded frame type of every protocol, so
+// frames can be logged, bridged and stored without knowing which protocol
+// they came from.
+type Frame interface {
+	// Protocol returns the name of the frame's protocol, e.g. "samsung".
+	Protocol() string
+	// Bits returns the frame's payload as sent, including any check bits,
+	// with the first bit sent in the lowest bit of the first byte.
+	Bits() []byte
+	fmt.Stringer
+}
+
+// CarrierHinter may be implemented by a FrameMarshaller whose protocol uses a
+// carrier other than 38kHz, e.g. Sony's 40kHz. TxDevice switches to the hinted
+// carrier for the duration of the frame. A Carrier of zero means no
+// preference.
+type CarrierHinter interface {
+	Carrier() uint32
+}
+
+// RepeatMarshaller is implemented by frames of protocols that have a specific
+// way of indicating a button is being held, such as NEC's short repeat code or
+// Samsung's fixed-period retransmission.
+type RepeatMarshaller interface {
+	FrameMarshaller
+	// MarshalRepeat returns the pairs sent for each repeat while a button is
+	// held.
+	MarshalRepeat() []TimePair
+	// RepeatPeriod returns the time from the start of one frame (or repeat)
+	// to the start of the next.
+	RepeatPeriod() time.Duration
+}
+
+// FrameStreamer is an alternative to FrameMarshaller for very long frames,
+// e.g. air conditioner state frames with hundreds of bits. Rather than
+// allocating every TimePair up front, NextPair is called for each pair as it
+// is transmitted. NextPair returns false once the frame is complete.
+//
+// NextPair is called between edges, so it must be quick.
+type FrameStreamer interface {
+	NextPair() (TimePair, bool)
+}
+
+// Transmitter is implemented by anything that can send TimePairs, most notably
+// TxDevice. Code that only needs to send should accept a Transmitter so that
+// it can be exercised off-device with irtest.Transmitter.
+type Transmitter interface {
+	SendPair(TimePair)
+	SendPairs(...TimePair)
+	SendFrame(FrameMarshaller)
+}
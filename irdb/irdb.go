@@ -0,0 +1,201 @@
+// irdb generates Go tables of named commands from IRDB CSV files
+// (https://github.com/probonopd/irdb), so firmware can embed a device's full
+// command set without transcribing it by hand. Each command is bound to the
+// encoder of its protocol in this module, giving a map from function name to
+// irtrx.FrameMarshaller.
+//
+// See cmd/irdbgen for a command line tool wrapping Generate.
+package irdb
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"go/format"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrHeader is returned for CSV without the expected columns.
+	ErrHeader = errors.New("irdb: missing column")
+	// ErrField is returned for a malformed numeric field.
+	ErrField = errors.New("irdb: bad field")
+)
+
+// Command is a row of an IRDB CSV file.
+type Command struct {
+	Name     string
+	Protocol string
+	Device   int
+	// Subdevice is -1 when the protocol's default applies.
+	Subdevice int
+	Function  int
+}
+
+var columns = []string{"functionname", "protocol", "device", "subdevice", "function"}
+
+// Parse reads an IRDB CSV file. Columns are found by their header, so their
+// order doesn't matter.
+func Parse(r io.Reader) ([]Command, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	idx := make([]int, len(columns))
+	for i, col := range columns {
+		idx[i] = -1
+		for j, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), col) {
+				idx[i] = j
+			}
+		}
+		if idx[i] < 0 {
+			return nil, fmt.Errorf("%w %q", ErrHeader, col)
+		}
+	}
+
+	var cmds []Command
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return cmds, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var nums [3]int
+		for i := range nums {
+			nums[i], err = strconv.Atoi(strings.TrimSpace(rec[idx[2+i]]))
+			if err != nil {
+				line, _ := cr.FieldPos(idx[2+i])
+				return nil, fmt.Errorf("%w on line %d", ErrField, line)
+			}
+		}
+		cmds = append(cmds, Command{
+			Name:      rec[idx[0]],
+			Protocol:  rec[idx[1]],
+			Device:    nums[0],
+			Subdevice: nums[1],
+			Function:  nums[2],
+		})
+	}
+}
+
+// binding is how a protocol's commands are written as Go expressions.
+type binding struct {
+	pkg  string
+	expr func(c Command) string
+}
+
+func subdevice(c Command, def int) int {
+	if c.Subdevice < 0 {
+		return def
+	}
+	return c.Subdevice
+}
+
+func necExpr(c Command) string {
+	addr := uint16(subdevice(c, int(^uint8(c.Device))))<<8 | uint16(uint8(c.Device))
+	return fmt.Sprintf("codec.NEC.Code(0x%08X)", uint64(uint8(^uint8(c.Function)))<<24|uint64(uint8(c.Function))<<16|uint64(addr))
+}
+
+// bindings maps lower cased IRDB protocol names onto encoders.
+var bindings = map[string]binding{
+	"nec":   {"codec", necExpr},
+	"nec1":  {"codec", necExpr},
+	"nec2":  {"codec", necExpr},
+	"necx1": {"codec", necExpr},
+	"necx2": {"codec", necExpr},
+	"samsung32": {"samsung", func(c Command) string {
+		addr := uint16(subdevice(c, c.Device))<<8 | uint16(uint8(c.Device))
+		cmd := uint16(^uint8(c.Function))<<8 | uint16(uint8(c.Function))
+		return fmt.Sprintf("&samsung.Frame{Addr: 0x%04X, Cmd: 0x%04X}", addr, cmd)
+	}},
+}
+
+var imports = map[string]string{
+	"codec":   "github.com/sparques/irtrx/codec",
+	"samsung": "github.com/sparques/irtrx/samsung",
+}
+
+// Supported reports whether commands of protocol can be generated.
+func Supported(protocol string) bool {
+	_, ok := bindings[strings.ToLower(protocol)]
+	return ok
+}
+
+// Duplicates returns the commands of Supported protocols whose names an
+// earlier such command already has, which Generate leaves out.
+func Duplicates(cmds []Command) []Command {
+	var dups []Command
+	names := map[string]bool{}
+	for _, c := range cmds {
+		if !Supported(c.Protocol) {
+			continue
+		}
+		if names[c.Name] {
+			dups = append(dups, c)
+		}
+		names[c.Name] = true
+	}
+	return dups
+}
+
+// Generate writes a Go source file for package pkg declaring a variable
+// called name, mapping each command's name to its FrameMarshaller. Commands
+// in protocols that aren't Supported, and those whose name is already taken
+// (see Duplicates), are listed in comments instead. source names the CSV
+// file in the generated header.
+func Generate(w io.Writer, pkg, name, source string, cmds []Command) error {
+	var body, skipped, dups bytes.Buffer
+	used := map[string]bool{}
+	names := map[string]bool{}
+	for _, c := range cmds {
+		b, ok := bindings[strings.ToLower(c.Protocol)]
+		if !ok {
+			fmt.Fprintf(&skipped, "//\t%s (%s %d.%d.%d)\n", c.Name, c.Protocol, c.Device, c.Subdevice, c.Function)
+			continue
+		}
+		if names[c.Name] {
+			fmt.Fprintf(&dups, "//\t%s (%s %d.%d.%d)\n", c.Name, c.Protocol, c.Device, c.Subdevice, c.Function)
+			continue
+		}
+		names[c.Name] = true
+		used[b.pkg] = true
+		fmt.Fprintf(&body, "\t%q: %s,\n", c.Name, b.expr(c))
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by irdbgen from %s; DO NOT EDIT.\n\npackage %s\n\n", source, pkg)
+	fmt.Fprintf(&src, "import (\n\t\"github.com/sparques/irtrx\"\n")
+	pkgs := make([]string, 0, len(used))
+	for p := range used {
+		pkgs = append(pkgs, p)
+	}
+	sort.Strings(pkgs)
+	for _, p := range pkgs {
+		fmt.Fprintf(&src, "\t%q\n", imports[p])
+	}
+	fmt.Fprintf(&src, ")\n\n")
+	if skipped.Len() > 0 {
+		fmt.Fprintf(&src, "// Unsupported protocols, left out:\n%s\n", skipped.Bytes())
+	}
+	if dups.Len() > 0 {
+		fmt.Fprintf(&src, "// Duplicate names, left out:\n%s\n", dups.Bytes())
+	}
+	fmt.Fprintf(&src, "// %s maps function names to the frames that send them.\n", name)
+	fmt.Fprintf(&src, "var %s = map[string]irtrx.FrameMarshaller{\n%s}\n", name, body.Bytes())
+
+	out, err := format.Source(src.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
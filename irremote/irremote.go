@@ -0,0 +1,74 @@
+// irremote imports the raw timing arrays printed by the Arduino IRremote
+// library and shared all over forums and wikis, e.g.
+//
+//	uint16_t rawData[67] = {9050,4450, 600,550, 600,1650, ...  600};  // NEC 20DF10EF
+//
+// The values are alternating mark and space durations in microseconds,
+// starting with a mark. The result can be sent with a TxDevice or fed to a
+// decoder to test it.
+package irremote
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sparques/irtrx"
+)
+
+// TrailingSpace is the space given to the final mark, which the arrays leave
+// out. It is long enough for any decoder to see the end of the frame.
+const TrailingSpace = 40 * time.Millisecond
+
+var (
+	// ErrNoArray is returned by Parse when there is no {...} array in the
+	// source.
+	ErrNoArray = errors.New("irremote: no array found")
+	// ErrValue is returned by Parse for an array element that isn't a
+	// number.
+	ErrValue = errors.New("irremote: bad array value")
+)
+
+// Pairs converts raw durations in microseconds, alternating mark and space,
+// to TimePairs.
+func Pairs(raw []uint16) []irtrx.TimePair {
+	out := make([]irtrx.TimePair, 0, (len(raw)+1)/2)
+	for i := 0; i < len(raw); i += 2 {
+		p := irtrx.TimePair{time.Duration(raw[i]) * time.Microsecond, TrailingSpace}
+		if i+1 < len(raw) {
+			p[1] = time.Duration(raw[i+1]) * time.Microsecond
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// Recording converts raw durations to a Recording at the carrier freq, in
+// Hz. IRremote's sendRaw takes the carrier in kHz; multiply by 1000.
+func Recording(raw []uint16, freq uint32) irtrx.Recording {
+	return irtrx.Recording{Freq: freq, Pairs: Pairs(raw)}
+}
+
+// Parse extracts the array from C source as printed by IRremote, e.g. a
+// whole "uint16_t rawData[67] = {...};" line. Only the part between the
+// braces is looked at; comments after the array are ignored. The Recording
+// uses a 38kHz carrier, which is what IRremote's dumps assume.
+func Parse(src string) (irtrx.Recording, error) {
+	start := strings.IndexByte(src, '{')
+	end := strings.IndexByte(src, '}')
+	if start < 0 || end < start {
+		return irtrx.Recording{}, ErrNoArray
+	}
+	var raw []uint16
+	for _, f := range strings.FieldsFunc(src[start+1:end], func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	}) {
+		v, err := strconv.ParseUint(f, 0, 16)
+		if err != nil {
+			return irtrx.Recording{}, ErrValue
+		}
+		raw = append(raw, uint16(v))
+	}
+	return Recording(raw, irtrx.Freq38Khz), nil
+}
@@ -0,0 +1,75 @@
+package irtest
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/sparques/irtrx"
+)
+
+// Bench describes a decoder to benchmark: HandleTimePair runs in interrupt
+// context, so it must be fast and must not allocate.
+type Bench struct {
+	Name string
+	// New returns a fresh decoder.
+	New func() irtrx.RxStateMachine
+	// Frame is sent to the decoder over and over.
+	Frame irtrx.FrameMarshaller
+	// Inverted is passed to NewWire.
+	Inverted bool
+}
+
+// Bench returns a Bench decoding a random frame of p.
+func (p Protocol[T]) Bench() Bench {
+	return Bench{
+		Name:     p.Name,
+		New:      func() irtrx.RxStateMachine { return p.NewDecoder(func(T) {}) },
+		Frame:    p.Marshal(p.Random(rand.New(rand.NewSource(1)))),
+		Inverted: p.Inverted,
+	}
+}
+
+// pairLog is an RxStateMachine recording what it is fed.
+type pairLog []irtrx.TimePair
+
+func (pl *pairLog) HandleTimePair(p irtrx.TimePair) {
+	*pl = append(*pl, p)
+}
+
+// Delivered returns the pairs a decoder receives for fm, in the order
+// selected by inverted, just as a Wire feeds them.
+func Delivered(fm irtrx.FrameMarshaller, inverted bool) []irtrx.TimePair {
+	var pl pairLog
+	NewWire(&pl, inverted).SendFrame(fm)
+	return pl
+}
+
+// AllocsPerFrame returns the average number of allocations sm makes
+// decoding pairs, as from Delivered.
+func AllocsPerFrame(sm irtrx.RxStateMachine, pairs []irtrx.TimePair) float64 {
+	return testing.AllocsPerRun(100, func() {
+		for _, p := range pairs {
+			sm.HandleTimePair(p)
+		}
+	})
+}
+
+// BenchmarkDecoder benchmarks bn's decoder, reporting the time per pair as
+// ns/pair alongside the usual time per frame. It fails b if the decoder
+// allocates.
+func BenchmarkDecoder(b *testing.B, bn Bench) {
+	b.Helper()
+	pairs := Delivered(bn.Frame, bn.Inverted)
+	sm := bn.New()
+	if n := AllocsPerFrame(sm, pairs); n > 0 {
+		b.Fatalf("%s: %v allocations per frame", bn.Name, n)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, p := range pairs {
+			sm.HandleTimePair(p)
+		}
+	}
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(pairs)), "ns/pair")
+}
@@ -0,0 +1,128 @@
+package irtest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sparques/irtrx"
+)
+
+// Clock is an irtrx.Clock that only moves when told to. Pass it to a
+// decoder's SetClock to test timeouts, repeat windows and failsafes without
+// waiting for them:
+//
+//	clk := irtest.NewClock()
+//	sm.SetClock(clk)
+//	sm.SetFailsafeHandler(onFailsafe)
+//	clk.Advance(sm.Timeout + time.Millisecond)
+//
+// Sleep blocks until Advance moves the clock past the sleeper's deadline.
+type Clock struct {
+	mu       sync.Mutex
+	now      time.Time
+	sleepers []sleeper
+	// signalled whenever a sleeper is added
+	added chan struct{}
+}
+
+type sleeper struct {
+	until time.Time
+	wake  chan struct{}
+}
+
+// NewClock returns a Clock set to an arbitrary fixed time.
+func NewClock() *Clock {
+	return &Clock{
+		now:   time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+		added: make(chan struct{}, 1),
+	}
+}
+
+// Now implements irtrx.Clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Sleep implements irtrx.Clock.
+func (c *Clock) Sleep(d time.Duration) {
+	c.mu.Lock()
+	if d <= 0 {
+		c.mu.Unlock()
+		return
+	}
+	s := sleeper{until: c.now.Add(d), wake: make(chan struct{})}
+	c.sleepers = append(c.sleepers, s)
+	c.mu.Unlock()
+	select {
+	case c.added <- struct{}{}:
+	default:
+	}
+	<-s.wake
+}
+
+// Advance moves the clock forward by d, waking every sleeper whose deadline
+// has passed. Sleepers are woken in deadline order, with the clock set to
+// each deadline in turn, so a goroutine that sleeps in a loop sees every
+// step it would have seen in real time as long as it gets back to sleep
+// promptly.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	for {
+		i := c.next(end)
+		if i < 0 {
+			break
+		}
+		s := c.sleepers[i]
+		c.sleepers = append(c.sleepers[:i], c.sleepers[i+1:]...)
+		c.now = s.until
+		c.mu.Unlock()
+		c.settle(s.wake)
+		c.mu.Lock()
+	}
+	c.now = end
+	c.mu.Unlock()
+}
+
+// next returns the index of the earliest sleeper due by end, or -1.
+func (c *Clock) next(end time.Time) int {
+	i := -1
+	for j, s := range c.sleepers {
+		if !s.until.After(end) && (i < 0 || s.until.Before(c.sleepers[i].until)) {
+			i = j
+		}
+	}
+	return i
+}
+
+// settle wakes a sleeper and gives it a moment to run and go back to sleep
+// before the clock moves on.
+func (c *Clock) settle(wake chan struct{}) {
+	select {
+	case <-c.added:
+	default:
+	}
+	close(wake)
+	select {
+	case <-c.added:
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+// Set moves the clock to t without waking anyone; t may be in the past.
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	c.now = t
+	c.mu.Unlock()
+}
+
+// Sleepers returns the number of goroutines blocked in Sleep.
+func (c *Clock) Sleepers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.sleepers)
+}
+
+var _ irtrx.Clock = (*Clock)(nil)
@@ -0,0 +1,114 @@
+package irtest
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sparques/irtrx"
+)
+
+// ConformanceJitter is the edge jitter every frame must survive in Conform.
+const ConformanceJitter = 25 * time.Microsecond
+
+// Conform runs the checks every decoder is expected to pass, each as a
+// subtest, so protocol packages outside this module can make sure they
+// keep working with the rest of irtrx:
+//
+//   - RoundTrip: random frames decode back to themselves.
+//   - Interface: the frames p marshals implement irtrx.Frame and
+//     irtrx.FrameUnmarshaller, and unmarshalling undoes marshalling.
+//   - Reset: a frame cut short doesn't stop the next one decoding, and
+//     back to back frames are all decoded.
+//   - Noise: frames with ConformanceJitter always decode, and heavy noise
+//     never makes the decoder panic.
+//   - Allocs: HandleTimePair doesn't allocate.
+//
+// Call it from the decoder's own tests, with a Protocol defined alongside
+// them, before checking the frames and errors particular to the protocol:
+//
+//	func TestDecode(t *testing.T) {
+//		irtest.Conform(t, protocol)
+//		// known frames, bad input, ...
+//	}
+func Conform[T comparable](t *testing.T, p Protocol[T]) {
+	t.Helper()
+	r := rand.New(rand.NewSource(1))
+	want := p.Random(r)
+	pairs := p.Marshal(want).MarshalFrame()
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		RoundTrip(t, p, 100, 1)
+	})
+
+	t.Run("Interface", func(t *testing.T) {
+		fm := p.Marshal(want)
+		f, ok := fm.(irtrx.Frame)
+		if !ok {
+			t.Fatalf("%T doesn't implement irtrx.Frame", fm)
+		}
+		if f.Protocol() == "" || len(f.Bits()) == 0 || f.String() == "" {
+			t.Errorf("%T: empty Protocol, Bits or String", fm)
+		}
+		// unmarshal into a copy of another frame, so fields such as a
+		// codec.Code's Spec are set but the value differs
+		other := p.Marshal(p.Random(r))
+		v := reflect.ValueOf(other)
+		if v.Kind() != reflect.Pointer {
+			t.Fatalf("%T doesn't implement irtrx.FrameUnmarshaller", other)
+		}
+		cp := reflect.New(v.Type().Elem())
+		cp.Elem().Set(v.Elem())
+		fu, ok := cp.Interface().(irtrx.FrameUnmarshaller)
+		if !ok {
+			t.Fatalf("%T doesn't implement irtrx.FrameUnmarshaller", other)
+		}
+		if err := fu.UnmarshalTimePairs(pairs); err != nil {
+			t.Fatalf("UnmarshalTimePairs: %v", err)
+		}
+		if got := fu.(irtrx.FrameMarshaller).MarshalFrame(); !reflect.DeepEqual(got, pairs) {
+			t.Errorf("UnmarshalTimePairs doesn't undo MarshalFrame: got %v, want %v", fu, fm)
+		}
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		// the last pair is left alone as a frame missing only its final
+		// space or stop bit may well be complete
+		for cut := 1; cut < len(pairs)-1; cut++ {
+			var got []T
+			w := NewWire(p.NewDecoder(func(v T) { got = append(got, v) }), p.Inverted)
+			partial := append([]irtrx.TimePair(nil), pairs[:cut]...)
+			partial[cut-1][1] = DefaultIdle
+			w.SendPairs(partial...)
+			w.SendPairs(pairs...)
+			w.SendPairs(pairs...)
+			if len(got) != 2 || got[0] != want || got[1] != want {
+				t.Fatalf("frame cut after %d pairs, then two frames: decoded %v, want [%v %v]", cut, got, want, want)
+			}
+		}
+	})
+
+	t.Run("Noise", func(t *testing.T) {
+		res := Stress(pairs, want, p.NewDecoder, p.Inverted, Jitter(ConformanceJitter), 200, 1)
+		if res.Decoded != res.Trials {
+			t.Errorf("with %v jitter: %+v", ConformanceJitter, res)
+		}
+		for name, noise := range map[string]Perturbation{
+			"DropEdges": DropEdges(0.1),
+			"Glitches":  Glitches(0.1, 100*time.Microsecond),
+			"Truncate":  Truncate(),
+		} {
+			if res := Stress(pairs, want, p.NewDecoder, p.Inverted, noise, 200, 1); res.Panicked != 0 {
+				t.Errorf("%s: decoder panicked in %d of %d trials", name, res.Panicked, res.Trials)
+			}
+		}
+	})
+
+	t.Run("Allocs", func(t *testing.T) {
+		delivered := Delivered(p.Marshal(want), p.Inverted)
+		if n := AllocsPerFrame(p.NewDecoder(func(T) {}), delivered); n > 0 {
+			t.Errorf("%v allocations per frame", n)
+		}
+	})
+}
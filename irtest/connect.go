@@ -0,0 +1,28 @@
+//go:build !tinygo
+
+package irtest
+
+import (
+	"time"
+
+	"github.com/sparques/irtrx/internal/hal"
+)
+
+// Connect wires the simulated pin of a TxDevice to the simulated pin of an
+// RxDevice, so a real TxDevice and RxDevice can be tested together in real
+// time. The receiver's pin follows the transmitter's envelope, inverted as a
+// demodulating receiver's output is, after latency. Pass raw pin numbers,
+// e.g. irtest.Connect(2, 3, 0).
+//
+// The host's timer resolution limits how faithfully short marks and spaces
+// come through; prefer Wire for decoding tests.
+func Connect(tx, rx hal.Pin, latency time.Duration) {
+	rx.Set(true)
+	hal.Watch(tx, func(_ hal.Pin, high bool) {
+		if latency == 0 {
+			rx.Set(!high)
+			return
+		}
+		time.AfterFunc(latency, func() { rx.Set(!high) })
+	})
+}
@@ -0,0 +1,44 @@
+//go:build !tinygo
+
+package irtest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sparques/irtrx/internal/hal"
+)
+
+func TestConnect(t *testing.T) {
+	defer hal.Reset()
+	const tx, rx hal.Pin = 2, 3
+	Connect(tx, rx, 0)
+	if !rx.Get() {
+		t.Fatal("receiver not idle high")
+	}
+	tx.Set(true)
+	if rx.Get() {
+		t.Error("mark: receiver high")
+	}
+	tx.Set(false)
+	if !rx.Get() {
+		t.Error("space: receiver low")
+	}
+}
+
+func TestConnectLatency(t *testing.T) {
+	defer hal.Reset()
+	const tx, rx hal.Pin = 2, 3
+	Connect(tx, rx, 5*time.Millisecond)
+	tx.Set(true)
+	if !rx.Get() {
+		t.Fatal("receiver followed before the latency passed")
+	}
+	deadline := time.Now().Add(time.Second)
+	for rx.Get() {
+		if time.Now().After(deadline) {
+			t.Fatal("receiver never followed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
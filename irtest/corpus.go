@@ -0,0 +1,130 @@
+package irtest
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sparques/irtrx"
+)
+
+// Capture is a real frame captured from a remote, with what it should
+// decode to, kept as a golden file so decoder changes are checked against
+// hardware rather than only against frames from MarshalFrame.
+//
+// A corpus is a directory laid out as <protocol>/<remote>/<name>.json, each
+// file holding a Capture in JSON. Protocol, Remote and Name are taken from
+// the path and not stored.
+type Capture struct {
+	Protocol string `json:"-"`
+	Remote   string `json:"-"`
+	Name     string `json:"-"`
+
+	// Recording holds mark-space pairs, as from an irtrx.Recorder.
+	Recording irtrx.Recording `json:"recording"`
+	// Inverted is true for protocols decoded with StartInverted; otherwise
+	// the pairs are replayed as Start would deliver them.
+	Inverted bool `json:"inverted"`
+	// Want is the JSON of what the capture decodes to.
+	Want json.RawMessage `json:"want"`
+	// Note is free text, e.g. the receiver and distance used.
+	Note string `json:"note,omitempty"`
+}
+
+// LoadCorpus reads every Capture under dir.
+func LoadCorpus(dir string) ([]Capture, error) {
+	return LoadCorpusFS(os.DirFS(dir), ".")
+}
+
+// LoadCorpusFS reads every Capture under root in fsys, e.g. an embed.FS.
+func LoadCorpusFS(fsys fs.FS, root string) ([]Capture, error) {
+	var caps []Capture
+	err := fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(p) != ".json" {
+			return err
+		}
+		b, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		var c Capture
+		if err := json.Unmarshal(b, &c); err != nil {
+			return &fs.PathError{Op: "load capture", Path: p, Err: err}
+		}
+		rel := strings.TrimPrefix(p, strings.TrimSuffix(root, "/")+"/")
+		parts := strings.Split(rel, "/")
+		if len(parts) >= 3 {
+			c.Protocol, c.Remote = parts[0], parts[1]
+		}
+		c.Name = strings.TrimSuffix(parts[len(parts)-1], ".json")
+		caps = append(caps, c)
+		return nil
+	})
+	return caps, err
+}
+
+// SaveCapture writes c into the corpus in dir, where its Protocol, Remote
+// and Name place it.
+func SaveCapture(dir string, c Capture) error {
+	p := filepath.Join(dir, c.Protocol, c.Remote, c.Name+".json")
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(c, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, append(b, '\n'), 0o644)
+}
+
+// NewCapture makes a Capture of rec which should decode to want.
+func NewCapture(protocol, remote, name string, rec irtrx.Recording, inverted bool, want any) (Capture, error) {
+	b, err := json.Marshal(want)
+	return Capture{
+		Protocol:  protocol,
+		Remote:    remote,
+		Name:      name,
+		Recording: rec,
+		Inverted:  inverted,
+		Want:      b,
+	}, err
+}
+
+// ReplayCorpus replays every capture of protocol through a fresh decoder
+// from newDecoder, as a subtest per capture, failing unless exactly the
+// wanted value is decoded. It fails if caps holds no capture of protocol.
+//
+//	caps, _ := irtest.LoadCorpus("testdata/captures")
+//	irtest.ReplayCorpus(t, caps, "samsung", func(h func(samsung.Frame)) irtrx.RxStateMachine {
+//		return samsung.NewStateMachine(h)
+//	})
+func ReplayCorpus[T comparable](t *testing.T, caps []Capture, protocol string, newDecoder func(handler func(T)) irtrx.RxStateMachine) {
+	t.Helper()
+	var n int
+	for _, c := range caps {
+		if c.Protocol != protocol {
+			continue
+		}
+		n++
+		c := c
+		t.Run(c.Protocol+"/"+c.Remote+"/"+c.Name, func(t *testing.T) {
+			var want T
+			if err := json.Unmarshal(c.Want, &want); err != nil {
+				t.Fatalf("bad want: %v", err)
+			}
+			var got []T
+			w := NewWire(newDecoder(func(v T) { got = append(got, v) }), c.Inverted)
+			w.SendPairs(c.Recording.Pairs...)
+			if len(got) != 1 || got[0] != want {
+				t.Errorf("decoded %v, want [%v]", got, want)
+			}
+		})
+	}
+	if n == 0 {
+		t.Errorf("no %s captures", protocol)
+	}
+}
@@ -0,0 +1,45 @@
+// irtest provides utilities for testing code built on irtrx without any IR
+// hardware attached.
+package irtest
+
+import (
+	"github.com/sparques/irtrx"
+)
+
+// Transmitter is an in-memory irtrx.Transmitter. Rather than blinking an LED,
+// it appends everything sent to Pairs. Each call to SendFrame is also
+// recorded as a separate entry in Frames.
+type Transmitter struct {
+	Pairs  []irtrx.TimePair
+	Frames [][]irtrx.TimePair
+}
+
+// NewTransmitter returns an empty Transmitter.
+func NewTransmitter() *Transmitter {
+	return &Transmitter{}
+}
+
+// SendPair implements irtrx.Transmitter.
+func (t *Transmitter) SendPair(pair irtrx.TimePair) {
+	t.Pairs = append(t.Pairs, pair)
+}
+
+// SendPairs implements irtrx.Transmitter.
+func (t *Transmitter) SendPairs(pairs ...irtrx.TimePair) {
+	t.Pairs = append(t.Pairs, pairs...)
+}
+
+// SendFrame implements irtrx.Transmitter.
+func (t *Transmitter) SendFrame(fm irtrx.FrameMarshaller) {
+	pairs := fm.MarshalFrame()
+	t.Frames = append(t.Frames, append([]irtrx.TimePair(nil), pairs...))
+	t.SendPairs(pairs...)
+}
+
+// Reset discards everything sent so far.
+func (t *Transmitter) Reset() {
+	t.Pairs = t.Pairs[:0]
+	t.Frames = t.Frames[:0]
+}
+
+var _ irtrx.Transmitter = (*Transmitter)(nil)
@@ -0,0 +1,162 @@
+package irtest
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/sparques/irtrx"
+)
+
+// Perturbation returns a damaged copy of pairs. It must not modify pairs.
+type Perturbation func(r *rand.Rand, pairs []irtrx.TimePair) []irtrx.TimePair
+
+// Jitter adds gaussian noise with standard deviation sigma to the time of
+// every edge.
+func Jitter(sigma time.Duration) Perturbation {
+	return func(r *rand.Rand, pairs []irtrx.TimePair) []irtrx.TimePair {
+		out := make([]irtrx.TimePair, len(pairs))
+		var last time.Duration
+		for i, p := range pairs {
+			for j := range p {
+				e := time.Duration(r.NormFloat64() * float64(sigma))
+				out[i][j] = nonNegative(p[j] + e - last)
+				last = e
+			}
+		}
+		return out
+	}
+}
+
+// DropEdges loses each mark with probability prob, as a weak signal does:
+// the mark and the spaces either side of it merge into one space.
+func DropEdges(prob float64) Perturbation {
+	return func(r *rand.Rand, pairs []irtrx.TimePair) []irtrx.TimePair {
+		out := make([]irtrx.TimePair, 0, len(pairs))
+		for _, p := range pairs {
+			if len(out) > 0 && r.Float64() < prob {
+				out[len(out)-1][1] += p[0] + p[1]
+				continue
+			}
+			out = append(out, p)
+		}
+		return out
+	}
+}
+
+// Glitches inserts, with probability prob per pair, a spurious mark of
+// width part way through the pair's space, as sunlight or a fluorescent
+// lamp does.
+func Glitches(prob float64, width time.Duration) Perturbation {
+	return func(r *rand.Rand, pairs []irtrx.TimePair) []irtrx.TimePair {
+		out := make([]irtrx.TimePair, 0, len(pairs))
+		for _, p := range pairs {
+			if p[1] <= width || r.Float64() >= prob {
+				out = append(out, p)
+				continue
+			}
+			at := time.Duration(r.Int63n(int64(p[1] - width)))
+			out = append(out, irtrx.TimePair{p[0], at}, irtrx.TimePair{width, p[1] - width - at})
+		}
+		return out
+	}
+}
+
+// Truncate cuts the stream off at a random point, ending with a long space
+// as if the transmitter had gone out of range.
+func Truncate() Perturbation {
+	return func(r *rand.Rand, pairs []irtrx.TimePair) []irtrx.TimePair {
+		if len(pairs) == 0 {
+			return nil
+		}
+		out := append([]irtrx.TimePair(nil), pairs[:r.Intn(len(pairs))+1]...)
+		out[len(out)-1][1] = DefaultIdle
+		return out
+	}
+}
+
+// Compose applies ps in order.
+func Compose(ps ...Perturbation) Perturbation {
+	return func(r *rand.Rand, pairs []irtrx.TimePair) []irtrx.TimePair {
+		for _, p := range ps {
+			pairs = p(r, pairs)
+		}
+		return pairs
+	}
+}
+
+// Result tallies the outcomes of Stress trials.
+type Result struct {
+	Trials int
+	// Decoded counts trials where exactly the wanted value was decoded.
+	Decoded int
+	// Missed counts trials where nothing was decoded.
+	Missed int
+	// Wrong counts trials where something else, or more than once, was
+	// decoded.
+	Wrong int
+	// Panicked counts trials where the decoder panicked.
+	Panicked int
+}
+
+// Clean reports whether the decoder failed cleanly in every trial it didn't
+// decode: it never decoded garbage and never panicked.
+func (r Result) Clean() bool {
+	return r.Wrong == 0 && r.Panicked == 0
+}
+
+// Stress sends perturbed copies of pairs to fresh decoders, made by
+// newDecoder, through a Wire, and tallies what they decode against want.
+// The seed makes runs repeatable.
+func Stress[T comparable](pairs []irtrx.TimePair, want T, newDecoder func(handler func(T)) irtrx.RxStateMachine, inverted bool, p Perturbation, trials int, seed int64) Result {
+	r := rand.New(rand.NewSource(seed))
+	res := Result{Trials: trials}
+	for i := 0; i < trials; i++ {
+		var got []T
+		damaged := p(r, pairs)
+		panicked := func() (panicked bool) {
+			defer func() {
+				if recover() != nil {
+					panicked = true
+				}
+			}()
+			w := NewWire(newDecoder(func(v T) { got = append(got, v) }), inverted)
+			w.SendPairs(damaged...)
+			return false
+		}()
+		switch {
+		case panicked:
+			res.Panicked++
+		case len(got) == 0:
+			res.Missed++
+		case len(got) == 1 && got[0] == want:
+			res.Decoded++
+		default:
+			res.Wrong++
+		}
+	}
+	return res
+}
+
+// JitterMargin finds the largest edge jitter, in steps of step up to max,
+// at which every one of trials decodes correctly. This quantifies how much
+// timing error a decoder tolerates.
+func JitterMargin[T comparable](pairs []irtrx.TimePair, want T, newDecoder func(handler func(T)) irtrx.RxStateMachine, inverted bool, step, max time.Duration, trials int) time.Duration {
+	var margin time.Duration
+	for sigma := step; sigma <= max; sigma += step {
+		res := Stress(pairs, want, newDecoder, inverted, Jitter(sigma), trials, int64(sigma))
+		if res.Decoded != trials {
+			break
+		}
+		margin = sigma
+	}
+	return margin
+}
+
+// AssertClean fails t if res isn't Clean.
+func AssertClean(t testing.TB, res Result) {
+	t.Helper()
+	if !res.Clean() {
+		t.Errorf("decoder failed uncleanly: %d of %d trials decoded wrong values, %d panicked", res.Wrong, res.Trials, res.Panicked)
+	}
+}
@@ -0,0 +1,54 @@
+package irtest
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/sparques/irtrx"
+)
+
+// Protocol ties a frame type to its encoder and decoder, so RoundTrip can
+// check that one undoes the other. T is what the decoder hands its handler.
+// A protocol package defines its own in its tests:
+//
+//	var protocol = irtest.Protocol[samsung.Frame]{
+//		Name: "samsung",
+//		Random: func(r *rand.Rand) samsung.Frame {
+//			return samsung.Frame{Addr: uint16(r.Uint32()), Cmd: uint16(r.Uint32())}
+//		},
+//		Marshal: func(f samsung.Frame) irtrx.FrameMarshaller { return &f },
+//		NewDecoder: func(h func(samsung.Frame)) irtrx.RxStateMachine {
+//			return samsung.NewStateMachine(h)
+//		},
+//		Inverted: true,
+//	}
+type Protocol[T comparable] struct {
+	Name string
+	// Random returns a random valid frame.
+	Random func(r *rand.Rand) T
+	// Marshal returns the FrameMarshaller that sends v.
+	Marshal func(v T) irtrx.FrameMarshaller
+	// NewDecoder returns a fresh decoder calling handler for each frame.
+	NewDecoder func(handler func(T)) irtrx.RxStateMachine
+	// Inverted is passed to NewWire.
+	Inverted bool
+}
+
+// RoundTrip sends n random frames from p, each through a Wire to a fresh
+// decoder, and fails t unless every one decodes back to exactly itself. The
+// seed makes runs repeatable; failures report it along with the frame.
+//
+//	irtest.RoundTrip(t, protocol, 1000, 1)
+func RoundTrip[T comparable](t testing.TB, p Protocol[T], n int, seed int64) {
+	t.Helper()
+	r := rand.New(rand.NewSource(seed))
+	for i := 0; i < n; i++ {
+		want := p.Random(r)
+		var got []T
+		w := NewWire(p.NewDecoder(func(v T) { got = append(got, v) }), p.Inverted)
+		w.SendFrame(p.Marshal(want))
+		if len(got) != 1 || got[0] != want {
+			t.Fatalf("%s: frame %d (seed %d): sent %v, decoded %v", p.Name, i, seed, want, got)
+		}
+	}
+}
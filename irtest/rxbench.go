@@ -0,0 +1,39 @@
+//go:build !tinygo
+
+package irtest
+
+import (
+	"testing"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/internal/hal"
+)
+
+// BenchmarkRxDevice benchmarks the RxDevice interrupt handler by toggling
+// the simulated pin of an RxDevice feeding sm, once per iteration. The
+// pairs sm sees are as long as the host takes between toggles, so use a
+// trivial sm to measure the handler alone. It fails b if the handler
+// allocates. The pin is left in its reset state.
+func BenchmarkRxDevice(b *testing.B, pin hal.Pin, sm irtrx.RxStateMachine, inverted bool) {
+	b.Helper()
+	defer hal.Reset()
+	rx := irtrx.NewRxDevice(pin, sm)
+	if inverted {
+		rx.StartInverted()
+	} else {
+		rx.Start()
+	}
+	level := pin.Get()
+	if n := testing.AllocsPerRun(100, func() {
+		level = !level
+		pin.Set(level)
+	}); n > 0 {
+		b.Fatalf("%v allocations per edge", n)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		level = !level
+		pin.Set(level)
+	}
+}
@@ -0,0 +1,98 @@
+package irtest
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/sparques/irtrx"
+)
+
+// DefaultIdle is the idle time a Wire assumes before the first pair it
+// sends.
+const DefaultIdle = 100 * time.Millisecond
+
+// Wire is an in-memory link from a transmitter to a receiver. It implements
+// irtrx.Transmitter by turning everything sent into the edges a demodulating
+// receiver would produce, optionally jittered, and feeding the resulting
+// pairs to an RxStateMachine, just as an RxDevice would. This makes
+// encode/decode round trips possible without hardware and without waiting
+// for frames to be sent in real time.
+//
+//	sm := samsung.NewStateMachine(func(f samsung.Frame) { ... })
+//	w := irtest.NewWire(sm, true)
+//	w.Jitter = 50 * time.Microsecond
+//	w.SendFrame(samsung.KeyPower)
+type Wire struct {
+	// Jitter is the largest error, either way, added to the time of each
+	// edge.
+	Jitter time.Duration
+	// Latency is added to the idle time before the first mark, as a
+	// receiver waking up would.
+	Latency time.Duration
+	// Rand is the source of jitter. If nil, a fixed seed is used so runs
+	// are repeatable.
+	Rand *rand.Rand
+
+	sm       irtrx.RxStateMachine
+	inverted bool
+	// the space leading into the next mark, for non-inverted delivery
+	idle time.Duration
+	// the jitter of the last edge
+	lastJitter time.Duration
+}
+
+// NewWire returns a Wire feeding sm. inverted selects mark-space pairs, as
+// from RxDevice.StartInverted; otherwise pairs are space-mark, as from
+// RxDevice.Start.
+func NewWire(sm irtrx.RxStateMachine, inverted bool) *Wire {
+	return &Wire{sm: sm, inverted: inverted, idle: DefaultIdle}
+}
+
+// jitter returns the change in length of a duration whose end edge is
+// jittered anew.
+func (w *Wire) jitter() time.Duration {
+	if w.Jitter <= 0 {
+		return 0
+	}
+	if w.Rand == nil {
+		w.Rand = rand.New(rand.NewSource(1))
+	}
+	j := time.Duration(w.Rand.Int63n(int64(2*w.Jitter+1))) - w.Jitter
+	d := j - w.lastJitter
+	w.lastJitter = j
+	return d
+}
+
+func nonNegative(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// SendPair implements irtrx.Transmitter.
+func (w *Wire) SendPair(pair irtrx.TimePair) {
+	mark := nonNegative(pair[0] + w.jitter())
+	space := nonNegative(pair[1] + w.jitter())
+	if w.inverted {
+		w.sm.HandleTimePair(irtrx.TimePair{mark, space})
+		return
+	}
+	w.sm.HandleTimePair(irtrx.TimePair{w.idle + w.Latency, mark})
+	w.idle = space
+	w.Latency = 0
+}
+
+// SendPairs implements irtrx.Transmitter.
+func (w *Wire) SendPairs(pairs ...irtrx.TimePair) {
+	for _, p := range pairs {
+		w.SendPair(p)
+	}
+}
+
+// SendFrame implements irtrx.Transmitter.
+func (w *Wire) SendFrame(fm irtrx.FrameMarshaller) {
+	w.SendPairs(fm.MarshalFrame()...)
+}
+
+var _ irtrx.Transmitter = (*Wire)(nil)
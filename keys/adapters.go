@@ -0,0 +1,82 @@
+package keys
+
+import (
+	"time"
+
+	"github.com/sparques/irtrx/cheapo"
+	"github.com/sparques/irtrx/codec"
+	"github.com/sparques/irtrx/hexbug"
+	"github.com/sparques/irtrx/samsung"
+)
+
+// repeatTimeout is the ReleaseTimeout for remotes repeating every period.
+func repeatTimeout(period time.Duration) time.Duration {
+	return 2*period + period/2
+}
+
+// Codec returns a decoder for s feeding a new Source of b. Codes are the
+// frame values; repeat bursts, if s has them, repeat the last one.
+func Codec(b *Bus, s *codec.Spec) *codec.Decoder {
+	period := s.RepeatPeriod
+	if period == 0 {
+		period = 108 * time.Millisecond
+	}
+	src := b.Source(s.Name, repeatTimeout(period))
+	d := codec.NewDecoder(s, src.Down)
+	d.RepeatHandler = src.Repeat
+	return d
+}
+
+// NEC returns a decoder for NEC remotes feeding a new Source of b. Codes are
+// the 32 bit frame values; see codec.NECValue.
+func NEC(b *Bus) *codec.Decoder {
+	return Codec(b, &codec.NEC)
+}
+
+// Samsung returns a decoder for Samsung remotes feeding a new Source of b.
+// Codes are Addr<<16 | Cmd.
+func Samsung(b *Bus) *samsung.StateMachine {
+	src := b.Source("samsung", repeatTimeout(samsung.RepeatPeriod))
+	return samsung.NewStateMachine(func(f samsung.Frame) {
+		src.Down(uint64(f.Addr)<<16 | uint64(f.Cmd))
+	})
+}
+
+// Cheapo returns a decoder for no-name remotes feeding a new Source of b.
+// Codes are the raw frames.
+func Cheapo(b *Bus) *cheapo.StateMachine {
+	src := b.Source("cheapo", repeatTimeout(108*time.Millisecond))
+	sm := cheapo.NewStateMachine(func(raw uint32) {
+		src.Down(uint64(raw))
+	})
+	sm.RepeatHandler = func(raw uint32, n int) {
+		src.Down(uint64(raw))
+	}
+	return sm
+}
+
+// Hexbug returns a decoder for hexbug remotes feeding a new Source of b.
+// Each button is a key of its own, so several can be down at once; codes
+// are a single Cmd*Mask button or'ed with the channel's bits. Buttons are
+// released by the remote's stop frames, or after 500ms without frames if the
+// robot drives out of range.
+func Hexbug(b *Bus) *hexbug.StateMachine {
+	src := b.Source("hexbug", 500*time.Millisecond)
+	src.Multi = true
+	// buttons down per channel, so only those are released
+	var down [4]hexbug.Cmd
+	return hexbug.NewStateMachine(func(cmd hexbug.Cmd) {
+		ch := cmd.Channel().Bits()
+		prev := &down[ch>>6]
+		for bit := 0; bit < 6; bit++ {
+			button := hexbug.Cmd(1) << bit
+			switch {
+			case cmd&button != 0:
+				src.Down(uint64(ch | button))
+			case *prev&button != 0:
+				src.Up(uint64(ch | button))
+			}
+		}
+		*prev = cmd.Buttons()
+	})
+}
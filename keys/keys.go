@@ -0,0 +1,336 @@
+// keys turns the frames of any remote into one stream of key events, so an
+// application can react to press, hold, repeat and release the same way
+// whichever remote the user points at the device. Each protocol signals a
+// held key differently (NEC sends repeat bursts, Samsung resends the frame,
+// hexbug remotes send stop frames on release); a Source for each decoder
+// hides that, and the Bus merges them:
+//
+//	b := keys.NewBus()
+//	b.Subscribe(func(ev keys.KeyEvent) {
+//	    if ev.Action == keys.Press { ... }
+//	})
+//	rx := irtrx.NewRxDevice(rxPin, keys.NEC(b))
+//	rx.StartInverted()
+//	b.Start()
+//
+// A Mapping on top binds keys to named commands from a config that can be
+// edited in the field.
+package keys
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/sparques/irtrx"
+)
+
+// Action is what happened to a key.
+type Action uint8
+
+const (
+	// Press is sent when a key goes down.
+	Press Action = iota
+	// Repeat is sent for each repeat the remote sends while the key stays
+	// down.
+	Repeat
+	// Hold is sent once, before the Repeat reaching HoldDelay, when a key
+	// has been down for HoldDelay.
+	Hold
+	// Release is sent when a key comes up, either because the remote said
+	// so or because repeats stopped arriving.
+	Release
+)
+
+func (a Action) String() string {
+	switch a {
+	case Press:
+		return "Press"
+	case Repeat:
+		return "Repeat"
+	case Hold:
+		return "Hold"
+	case Release:
+		return "Release"
+	}
+	return "Unknown"
+}
+
+// KeyEvent describes a change in the state of a key.
+type KeyEvent struct {
+	// Protocol is the name of the Source's protocol, e.g. "nec".
+	Protocol string
+	// Code identifies the key within Protocol; see the adapters for what
+	// each protocol's codes hold.
+	Code   uint64
+	Action Action
+	// HoldDuration is how long the key has been down; 0 for Press.
+	HoldDuration time.Duration
+}
+
+// MaxKeys is the number of keys, over all Sources, a Bus tracks as down at
+// once. Presses beyond it are ignored.
+const MaxKeys = 8
+
+// DefaultHoldDelay is how long a key must be down to be held.
+const DefaultHoldDelay = 500 * time.Millisecond
+
+// PollInterval is how often the goroutine started by Start polls.
+const PollInterval = 10 * time.Millisecond
+
+// key is a key that is down.
+type key struct {
+	src       *Source
+	code      uint64
+	pressedAt time.Time
+	last      time.Time
+	held      bool
+}
+
+// Bus merges the key events of several Sources and delivers them to its
+// subscribers. Sources are fed from interrupt handlers; the events are only
+// worked out, and subscribers called, by Poll, so subscribers run in the
+// main loop or the goroutine started by Start and may block.
+type Bus struct {
+	// HoldDelay is how long a key must be down before Hold is sent.
+	HoldDelay time.Duration
+
+	sources     []*Source
+	subscribers []func(KeyEvent)
+	keys        [MaxKeys]key
+	clock       irtrx.Clock
+	running     atomic.Bool
+}
+
+// NewBus returns a Bus with no Sources or subscribers.
+func NewBus() *Bus {
+	return &Bus{
+		HoldDelay: DefaultHoldDelay,
+		clock:     irtrx.RealClock,
+	}
+}
+
+// SetClock replaces the clock used to time events.
+func (b *Bus) SetClock(c irtrx.Clock) {
+	b.clock = c
+}
+
+// Subscribe adds handler to those called with every event. Subscribe
+// before starting the receivers and calling Start or Poll.
+func (b *Bus) Subscribe(handler func(KeyEvent)) {
+	b.subscribers = append(b.subscribers, handler)
+}
+
+// Start polls from a goroutine every PollInterval. Use either Start or
+// Poll, not both.
+func (b *Bus) Start() {
+	if b.running.Swap(true) {
+		return
+	}
+	go func() {
+		for b.running.Load() {
+			b.Poll()
+			b.clock.Sleep(PollInterval)
+		}
+	}()
+}
+
+// Stop stops the goroutine started by Start.
+func (b *Bus) Stop() {
+	b.running.Store(false)
+}
+
+// Poll sends the events for everything the Sources have seen since the
+// last Poll, and releases keys whose repeats have stopped.
+func (b *Bus) Poll() {
+	for _, s := range b.sources {
+		for {
+			in, ok := s.pop()
+			if !ok {
+				break
+			}
+			at := time.Unix(0, in.at)
+			switch in.op {
+			case opDown:
+				b.down(s, in.code, at)
+			case opRepeat:
+				b.repeat(s, at)
+			case opUp:
+				if k := b.lookup(s, in.code); k != nil {
+					b.release(k, at)
+				}
+			}
+		}
+	}
+	now := b.clock.Now()
+	for i := range b.keys {
+		k := &b.keys[i]
+		if k.src != nil && now.Sub(k.last) > k.src.ReleaseTimeout {
+			b.release(k, k.last.Add(k.src.ReleaseTimeout))
+		}
+	}
+}
+
+func (b *Bus) lookup(s *Source, code uint64) *key {
+	for i := range b.keys {
+		if k := &b.keys[i]; k.src == s && k.code == code {
+			return k
+		}
+	}
+	return nil
+}
+
+func (b *Bus) down(s *Source, code uint64, at time.Time) {
+	k := b.lookup(s, code)
+	if k != nil && at.Sub(k.last) > s.ReleaseTimeout {
+		b.release(k, k.last.Add(s.ReleaseTimeout))
+		k = nil
+	}
+	if k != nil {
+		b.repeated(k, at)
+		return
+	}
+	if !s.Multi {
+		for i := range b.keys {
+			if o := &b.keys[i]; o.src == s {
+				b.release(o, at)
+			}
+		}
+	}
+	for i := range b.keys {
+		k := &b.keys[i]
+		if k.src != nil {
+			continue
+		}
+		*k = key{src: s, code: code, pressedAt: at, last: at}
+		s.current = code
+		b.send(k, Press, 0)
+		return
+	}
+}
+
+// repeat handles a repeat burst, which repeats the Source's last key.
+func (b *Bus) repeat(s *Source, at time.Time) {
+	k := b.lookup(s, s.current)
+	if k == nil {
+		return
+	}
+	if at.Sub(k.last) > s.ReleaseTimeout {
+		b.release(k, k.last.Add(s.ReleaseTimeout))
+		return
+	}
+	b.repeated(k, at)
+}
+
+func (b *Bus) repeated(k *key, at time.Time) {
+	k.last = at
+	d := at.Sub(k.pressedAt)
+	if !k.held && d >= b.HoldDelay {
+		k.held = true
+		b.send(k, Hold, d)
+	}
+	b.send(k, Repeat, d)
+}
+
+func (b *Bus) release(k *key, at time.Time) {
+	b.send(k, Release, at.Sub(k.pressedAt))
+	k.src = nil
+}
+
+func (b *Bus) send(k *key, a Action, d time.Duration) {
+	ev := KeyEvent{Protocol: k.src.Protocol, Code: k.code, Action: a, HoldDuration: d}
+	for _, h := range b.subscribers {
+		h(ev)
+	}
+}
+
+const (
+	opDown uint8 = iota
+	opRepeat
+	opUp
+)
+
+// input is something a Source saw, queued for Poll.
+type input struct {
+	op   uint8
+	code uint64
+	// in Unix nanoseconds
+	at int64
+}
+
+// queueSize is the number of inputs a Source holds between Polls; it must
+// be a power of two.
+const queueSize = 32
+
+// Source feeds the keys of one protocol into a Bus. Its methods are meant
+// to be called from a decoder's handlers, in interrupt context, by a single
+// receiver; give each receiver its own Source. The adapters, such as NEC,
+// return decoders already wired to a new Source.
+type Source struct {
+	// Protocol names the Source's keys in KeyEvents.
+	Protocol string
+	// ReleaseTimeout is how long after the last frame or repeat a key is
+	// considered released. It should be comfortably longer than the time
+	// between the remote's repeats.
+	ReleaseTimeout time.Duration
+	// Multi is set for remotes that can hold several keys down at once.
+	// Otherwise pressing a key releases the last one.
+	Multi bool
+
+	bus *Bus
+	// the last key pressed, for repeat bursts; only used by Poll
+	current uint64
+	// single producer, single consumer ring of inputs
+	queue      [queueSize]input
+	head, tail atomic.Uint32
+}
+
+// Source returns a new Source of b for protocol. Add Sources before
+// starting the receivers and calling Start or Poll.
+func (b *Bus) Source(protocol string, releaseTimeout time.Duration) *Source {
+	s := &Source{
+		Protocol:       protocol,
+		ReleaseTimeout: releaseTimeout,
+		bus:            b,
+	}
+	b.sources = append(b.sources, s)
+	return s
+}
+
+// Down reports a frame for the key code: a press if it isn't down, else a
+// repeat.
+func (s *Source) Down(code uint64) {
+	s.push(input{op: opDown, code: code})
+}
+
+// Repeat reports a repeat burst, standing for whichever key was last
+// pressed.
+func (s *Source) Repeat() {
+	s.push(input{op: opRepeat})
+}
+
+// Up reports that the key code was released. Keys that aren't down are
+// ignored.
+func (s *Source) Up(code uint64) {
+	s.push(input{op: opUp, code: code})
+}
+
+// push queues in, dropping it if Poll has fallen behind.
+func (s *Source) push(in input) {
+	in.at = s.bus.clock.Now().UnixNano()
+	head := s.head.Load()
+	if head-s.tail.Load() == queueSize {
+		return
+	}
+	s.queue[head&(queueSize-1)] = in
+	s.head.Store(head + 1)
+}
+
+func (s *Source) pop() (input, bool) {
+	tail := s.tail.Load()
+	if tail == s.head.Load() {
+		return input{}, false
+	}
+	in := s.queue[tail&(queueSize-1)]
+	s.tail.Store(tail + 1)
+	return in, true
+}
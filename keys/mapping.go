@@ -0,0 +1,229 @@
+package keys
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrSyntax is returned when loading a malformed mapping.
+var ErrSyntax = errors.New("keys: syntax error")
+
+// Binding binds a key to a named application command.
+type Binding struct {
+	Protocol string
+	Code     uint64
+	// On is the Action of the key that triggers Command.
+	On      Action
+	Command string
+}
+
+func (b Binding) String() string {
+	return fmt.Sprintf("%s %#x %s %s", b.Protocol, b.Code, strings.ToLower(b.On.String()), b.Command)
+}
+
+type binding struct {
+	protocol string
+	code     uint64
+	on       Action
+}
+
+// Mapping turns key events into application commands, so the application
+// deals in "power" or "volume-up" rather than in codes and remotes can be
+// swapped or remapped in the field. Subscribe its HandleKeyEvent method to a
+// Bus:
+//
+//	m := keys.NewMapping(func(cmd string, ev keys.KeyEvent) { ... })
+//	m.Load(strings.NewReader(config))
+//	b.Subscribe(m.HandleKeyEvent)
+//
+// The config format has one binding per line, as protocol, code, the
+// actions triggering it (press if left out) and the command:
+//
+//	# power and volume on the TV remote
+//	nec 0xba4500ff power
+//	nec 0xb946ff00 press,repeat volume-up
+//	hexbug 0x41 hold turbo
+//
+// Mappings can be edited at any time, also while events are being handled.
+type Mapping struct {
+	// Handler is called with the command bound to each event that has one.
+	Handler func(command string, ev KeyEvent)
+
+	mu       sync.Mutex
+	bindings map[binding]string
+	// command to bind to the next key pressed, if learning
+	learn   string
+	learnOn []Action
+}
+
+// NewMapping returns an empty Mapping calling handler.
+func NewMapping(handler func(command string, ev KeyEvent)) *Mapping {
+	return &Mapping{
+		Handler:  handler,
+		bindings: make(map[binding]string),
+	}
+}
+
+// HandleKeyEvent runs the command bound to ev, if any.
+func (m *Mapping) HandleKeyEvent(ev KeyEvent) {
+	m.mu.Lock()
+	if m.learn != "" && ev.Action == Press {
+		for _, on := range m.learnOn {
+			m.bindings[binding{ev.Protocol, ev.Code, on}] = m.learn
+		}
+		m.learn, m.learnOn = "", nil
+	}
+	cmd, ok := m.bindings[binding{ev.Protocol, ev.Code, ev.Action}]
+	m.mu.Unlock()
+	if ok && m.Handler != nil {
+		m.Handler(cmd, ev)
+	}
+}
+
+// Bind binds command to the key code of protocol, for each of on; with no
+// on, for Press. Any command already bound to those is replaced.
+func (m *Mapping) Bind(protocol string, code uint64, command string, on ...Action) {
+	if len(on) == 0 {
+		on = []Action{Press}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, a := range on {
+		m.bindings[binding{protocol, code, a}] = command
+	}
+}
+
+// Unbind removes the bindings of the key code of protocol for each of on;
+// with no on, for every Action.
+func (m *Mapping) Unbind(protocol string, code uint64, on ...Action) {
+	if len(on) == 0 {
+		on = []Action{Press, Repeat, Hold, Release}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, a := range on {
+		delete(m.bindings, binding{protocol, code, a})
+	}
+}
+
+// UnbindCommand removes every binding to command.
+func (m *Mapping) UnbindCommand(command string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for b, cmd := range m.bindings {
+		if cmd == command {
+			delete(m.bindings, b)
+		}
+	}
+}
+
+// Learn binds command to the next key pressed, for each of on as with Bind,
+// so a user can remap a button by pressing it. The key's other bindings are
+// kept; call UnbindCommand first to move a command rather than add a key
+// for it.
+func (m *Mapping) Learn(command string, on ...Action) {
+	if len(on) == 0 {
+		on = []Action{Press}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.learn, m.learnOn = command, on
+}
+
+// Lookup returns the command bound to the key code of protocol for on.
+func (m *Mapping) Lookup(protocol string, code uint64, on Action) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cmd, ok := m.bindings[binding{protocol, code, on}]
+	return cmd, ok
+}
+
+// Bindings returns every binding, sorted by protocol, code and action.
+func (m *Mapping) Bindings() []Binding {
+	m.mu.Lock()
+	out := make([]Binding, 0, len(m.bindings))
+	for b, cmd := range m.bindings {
+		out = append(out, Binding{Protocol: b.protocol, Code: b.code, On: b.on, Command: cmd})
+	}
+	m.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		if a.Code != b.Code {
+			return a.Code < b.Code
+		}
+		return a.On < b.On
+	})
+	return out
+}
+
+// ParseAction returns the Action named s, in any case.
+func ParseAction(s string) (Action, bool) {
+	for a := Press; a <= Release; a++ {
+		if strings.EqualFold(s, a.String()) {
+			return a, true
+		}
+	}
+	return 0, false
+}
+
+// Load adds the bindings read from r, in the config format described on
+// Mapping, replacing those for the same keys and actions. Nothing is added
+// if r holds an error.
+func (m *Mapping) Load(r io.Reader) error {
+	var loaded []Binding
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text, _, _ := strings.Cut(s.Text(), "#")
+		f := strings.Fields(text)
+		if len(f) == 0 {
+			continue
+		}
+		if len(f) != 3 && len(f) != 4 {
+			return fmt.Errorf("keys: line %d: %w", line, ErrSyntax)
+		}
+		code, err := strconv.ParseUint(f[1], 0, 64)
+		if err != nil {
+			return fmt.Errorf("keys: line %d: %w: bad code %q", line, ErrSyntax, f[1])
+		}
+		b := Binding{Protocol: f[0], Code: code, Command: f[len(f)-1]}
+		if len(f) == 3 {
+			loaded = append(loaded, b)
+			continue
+		}
+		for _, name := range strings.Split(f[2], ",") {
+			a, ok := ParseAction(name)
+			if !ok {
+				return fmt.Errorf("keys: line %d: %w: bad action %q", line, ErrSyntax, name)
+			}
+			b.On = a
+			loaded = append(loaded, b)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+	for _, b := range loaded {
+		m.Bind(b.Protocol, b.Code, b.Command, b.On)
+	}
+	return nil
+}
+
+// Save writes every binding to w in the config format, one per line, so
+// Load reads them back.
+func (m *Mapping) Save(w io.Writer) error {
+	for _, b := range m.Bindings() {
+		if _, err := fmt.Fprintln(w, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
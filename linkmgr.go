@@ -0,0 +1,171 @@
+package irtrx
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// LinkManager shares an optical channel between several boards, each with a
+// Transceiver, much as CSMA/CA shares a radio channel:
+//
+//   - Listen before talk: a transmission waits for the channel to have been
+//     quiet for the Transceiver's Idle.
+//   - Random backoff: if the channel was busy, the transmission also waits a
+//     random number of quiet Slots, so boards that were all waiting for the
+//     same frame to end don't all start together. The range doubles each
+//     time a transmission finds the channel busy, up to MaxWindow, and goes
+//     back to MinWindow once one finds it clear.
+//   - Turnaround: Reply waits only for Turnaround of quiet, shorter than
+//     Idle, so answers to a frame (acks, responses) get the channel before
+//     anyone else's new traffic.
+//
+// MaxWait still bounds how long a transmission is held off. LinkManager
+// implements Transmitter; stream.Link sends its acks with Reply.
+type LinkManager struct {
+	T *Transceiver
+
+	// Slot is the unit of random backoff. It should be longer than the
+	// longest mark or space the boards send, so a frame in progress can't
+	// go unnoticed for a whole slot.
+	Slot time.Duration
+	// MinWindow and MaxWindow bound the number of slots backoff is drawn
+	// from.
+	MinWindow, MaxWindow int
+	// Turnaround is how long the channel must be quiet before a Reply.
+	Turnaround time.Duration
+
+	// serializes transmissions from different goroutines
+	txMu   sync.Mutex
+	mu     sync.Mutex
+	window int
+	stats  LinkStats
+}
+
+// LinkStats counts what a LinkManager has done.
+type LinkStats struct {
+	// Sent counts transmissions, including Replies.
+	Sent int
+	// Deferred counts transmissions that found the channel busy and backed
+	// off.
+	Deferred int
+	// Forced counts transmissions made anyway once MaxWait had passed.
+	Forced int
+}
+
+// NewLinkManager returns a LinkManager coordinating t, with defaults suited
+// to remote control protocols.
+func NewLinkManager(t *Transceiver) *LinkManager {
+	return &LinkManager{
+		T:          t,
+		Slot:       10 * time.Millisecond,
+		MinWindow:  4,
+		MaxWindow:  64,
+		Turnaround: 8 * time.Millisecond,
+		window:     4,
+	}
+}
+
+// Stats returns the counts so far.
+func (lm *LinkManager) Stats() LinkStats {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+	return lm.stats
+}
+
+// quiet returns how long the channel has been quiet.
+func (lm *LinkManager) quiet() time.Duration {
+	return lm.T.Rx.clock.Now().Sub(lm.T.Rx.LastActivity())
+}
+
+// acquire blocks until the channel has been quiet for idle and, if it was
+// busy, for a random backoff on top. It returns whether it gave up waiting.
+func (lm *LinkManager) acquire(idle time.Duration, backoff bool) (forced bool) {
+	clock := lm.T.Rx.clock
+	start := clock.Now()
+	slots := -1
+	for {
+		if lm.T.MaxWait != 0 && clock.Now().Sub(start) > lm.T.MaxWait {
+			return true
+		}
+		if q := lm.quiet(); q < idle {
+			if backoff && slots < 0 {
+				slots = lm.deferred()
+			}
+			clock.Sleep(idle - q)
+			continue
+		}
+		if slots <= 0 {
+			if backoff && slots < 0 {
+				lm.clear()
+			}
+			return false
+		}
+		// count down only slots that stay quiet throughout; activity
+		// freezes the countdown until the channel has been idle again
+		last := lm.T.Rx.LastActivity()
+		clock.Sleep(lm.Slot)
+		if lm.T.Rx.LastActivity().Equal(last) {
+			slots--
+		}
+	}
+}
+
+// deferred picks a backoff and widens the window for next time.
+func (lm *LinkManager) deferred() int {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+	lm.stats.Deferred++
+	slots := rand.Intn(lm.window) + 1
+	lm.window = min(lm.window*2, lm.MaxWindow)
+	return slots
+}
+
+// clear resets the window after finding the channel clear.
+func (lm *LinkManager) clear() {
+	lm.mu.Lock()
+	lm.window = lm.MinWindow
+	lm.mu.Unlock()
+}
+
+// transmit waits for the channel and then calls send with reception muted.
+// Transmissions from different goroutines take turns, each acquiring the
+// channel afresh once the last has ended.
+func (lm *LinkManager) transmit(idle time.Duration, backoff bool, send func()) {
+	lm.txMu.Lock()
+	defer lm.txMu.Unlock()
+	forced := lm.acquire(idle, backoff)
+	lm.T.Rx.Mute()
+	send()
+	lm.T.end()
+
+	lm.mu.Lock()
+	lm.stats.Sent++
+	if forced {
+		lm.stats.Forced++
+	}
+	lm.mu.Unlock()
+}
+
+// SendPair implements Transmitter.
+func (lm *LinkManager) SendPair(pair TimePair) {
+	lm.transmit(lm.T.Idle, true, func() { lm.T.Tx.SendPair(pair) })
+}
+
+// SendPairs implements Transmitter.
+func (lm *LinkManager) SendPairs(pairs ...TimePair) {
+	lm.transmit(lm.T.Idle, true, func() { lm.T.Tx.SendPairs(pairs...) })
+}
+
+// SendFrame implements Transmitter.
+func (lm *LinkManager) SendFrame(fm FrameMarshaller) {
+	lm.transmit(lm.T.Idle, true, func() { lm.T.Tx.SendFrame(fm) })
+}
+
+// Reply sends fm in answer to a frame just received, waiting only for
+// Turnaround of quiet and without backoff.
+func (lm *LinkManager) Reply(fm FrameMarshaller) {
+	lm.transmit(lm.Turnaround, false, func() { lm.T.Tx.SendFrame(fm) })
+}
+
+var _ Transmitter = (*LinkManager)(nil)
@@ -0,0 +1,357 @@
+package lirc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sparques/irtrx"
+)
+
+// Parse reads the remote definitions in a lircd.conf.
+func Parse(rd io.Reader) ([]*Remote, error) {
+	p := parser{}
+	sc := bufio.NewScanner(rd)
+	for sc.Scan() {
+		p.line++
+		text := sc.Text()
+		if i := strings.IndexByte(text, '#'); i >= 0 {
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if err := p.parse(fields); err != nil {
+			return nil, fmt.Errorf("lirc: line %d: %w", p.line, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if p.remote != nil {
+		return nil, fmt.Errorf("lirc: line %d: %w: missing end remote", p.line, ErrSyntax)
+	}
+	return p.remotes, nil
+}
+
+type section uint8
+
+const (
+	sectionNone section = iota
+	sectionRemote
+	sectionCodes
+	sectionRawCodes
+)
+
+type parser struct {
+	line    int
+	section section
+	remote  *Remote
+	remotes []*Remote
+	raw     *RawCode
+	// a pulse read without its space yet, in raw codes
+	pulse time.Duration
+}
+
+func (p *parser) parse(f []string) error {
+	switch {
+	case f[0] == "begin" && len(f) == 2:
+		return p.begin(f[1])
+	case f[0] == "end" && len(f) == 2:
+		return p.end(f[1])
+	}
+
+	switch p.section {
+	case sectionRemote:
+		return p.setting(f)
+	case sectionCodes:
+		v, err := parseUint(f, 1)
+		if err != nil {
+			return err
+		}
+		p.remote.Codes = append(p.remote.Codes, Code{Name: f[0], Value: v})
+		return nil
+	case sectionRawCodes:
+		return p.rawCode(f)
+	}
+	return ErrSyntax
+}
+
+func (p *parser) begin(what string) error {
+	switch {
+	case what == "remote" && p.section == sectionNone:
+		p.remote = &Remote{}
+		p.section = sectionRemote
+	case what == "codes" && p.section == sectionRemote:
+		p.section = sectionCodes
+	case what == "raw_codes" && p.section == sectionRemote:
+		p.section = sectionRawCodes
+	default:
+		return ErrSyntax
+	}
+	return nil
+}
+
+func (p *parser) end(what string) error {
+	switch {
+	case what == "remote" && p.section == sectionRemote:
+		p.remotes = append(p.remotes, p.remote)
+		p.remote = nil
+		p.section = sectionNone
+	case what == "codes" && p.section == sectionCodes:
+		p.section = sectionRemote
+	case what == "raw_codes" && p.section == sectionRawCodes:
+		p.endRaw()
+		p.section = sectionRemote
+	default:
+		return ErrSyntax
+	}
+	return nil
+}
+
+func (p *parser) setting(f []string) error {
+	r := p.remote
+	var err error
+	switch f[0] {
+	case "name":
+		if len(f) < 2 {
+			return ErrSyntax
+		}
+		r.Name = f[1]
+	case "flags":
+		if len(f) < 2 {
+			return ErrSyntax
+		}
+		r.Flags = strings.Split(f[1], "|")
+	case "bits":
+		r.Bits, err = parseInt(f)
+	case "eps":
+		r.Eps, err = parseInt(f)
+	case "aeps":
+		r.Aeps, err = parseDuration(f, 1)
+	case "header":
+		r.Header, err = parsePair(f)
+	case "one":
+		r.One, err = parsePair(f)
+	case "zero":
+		r.Zero, err = parsePair(f)
+	case "repeat":
+		r.Repeat, err = parsePair(f)
+	case "ptrail":
+		r.Ptrail, err = parseDuration(f, 1)
+	case "gap":
+		r.Gap, err = parseDuration(f, 1)
+	case "pre_data_bits":
+		r.PreDataBits, err = parseInt(f)
+	case "pre_data":
+		r.PreData, err = parseUint(f, 1)
+	case "post_data_bits":
+		r.PostDataBits, err = parseInt(f)
+	case "post_data":
+		r.PostData, err = parseUint(f, 1)
+	case "frequency":
+		var v uint64
+		v, err = parseUint(f, 1)
+		r.Frequency = uint32(v)
+	}
+	// anything else is a setting we don't use
+	return err
+}
+
+func (p *parser) rawCode(f []string) error {
+	if f[0] == "name" {
+		if len(f) != 2 {
+			return ErrSyntax
+		}
+		p.endRaw()
+		p.raw = &RawCode{Name: f[1]}
+		return nil
+	}
+	if p.raw == nil {
+		return ErrSyntax
+	}
+	for i := range f {
+		d, err := parseDuration(f, i)
+		if err != nil {
+			return err
+		}
+		if p.pulse == 0 {
+			p.pulse = d
+			continue
+		}
+		p.raw.Pairs = append(p.raw.Pairs, irtrx.TimePair{p.pulse, d})
+		p.pulse = 0
+	}
+	return nil
+}
+
+// endRaw finishes the raw code being read. A trailing pulse gets the
+// remote's gap as its space.
+func (p *parser) endRaw() {
+	if p.raw == nil {
+		return
+	}
+	if p.pulse != 0 {
+		p.raw.Pairs = append(p.raw.Pairs, irtrx.TimePair{p.pulse, p.remote.Gap})
+		p.pulse = 0
+	}
+	p.remote.RawCodes = append(p.remote.RawCodes, *p.raw)
+	p.raw = nil
+}
+
+func parseUint(f []string, i int) (uint64, error) {
+	if len(f) <= i {
+		return 0, ErrSyntax
+	}
+	v, err := strconv.ParseUint(f[i], 0, 64)
+	if err != nil {
+		return 0, ErrSyntax
+	}
+	return v, nil
+}
+
+func parseInt(f []string) (int, error) {
+	v, err := parseUint(f, 1)
+	return int(v), err
+}
+
+func parseDuration(f []string, i int) (time.Duration, error) {
+	v, err := parseUint(f, i)
+	return time.Duration(v) * time.Microsecond, err
+}
+
+func parsePair(f []string) (irtrx.TimePair, error) {
+	mark, err := parseDuration(f, 1)
+	if err != nil {
+		return irtrx.TimePair{}, err
+	}
+	space, err := parseDuration(f, 2)
+	return irtrx.TimePair{mark, space}, err
+}
+
+// Write writes remotes in lircd.conf format, e.g. to hand remotes learned
+// with an irtrx.Recorder over to LIRC.
+func Write(w io.Writer, remotes ...*Remote) error {
+	bw := bufio.NewWriter(w)
+	for i, r := range remotes {
+		if i > 0 {
+			fmt.Fprintln(bw)
+		}
+		writeRemote(bw, r)
+	}
+	return bw.Flush()
+}
+
+func us(d time.Duration) int64 {
+	return d.Microseconds()
+}
+
+func writeRemote(w io.Writer, r *Remote) {
+	fmt.Fprintln(w, "begin remote")
+	fmt.Fprintf(w, "  name  %s\n", r.Name)
+	flags := r.Flags
+	if len(flags) == 0 && r.Raw() {
+		flags = []string{"RAW_CODES"}
+	}
+	if len(flags) > 0 {
+		fmt.Fprintf(w, "  flags %s\n", strings.Join(flags, "|"))
+	}
+	if r.Bits != 0 {
+		fmt.Fprintf(w, "  bits  %d\n", r.Bits)
+	}
+	if r.Eps != 0 {
+		fmt.Fprintf(w, "  eps   %d\n", r.Eps)
+	}
+	if r.Aeps != 0 {
+		fmt.Fprintf(w, "  aeps  %d\n", us(r.Aeps))
+	}
+	pairs := []struct {
+		name string
+		p    irtrx.TimePair
+	}{{"header", r.Header}, {"one", r.One}, {"zero", r.Zero}, {"repeat", r.Repeat}}
+	for _, np := range pairs {
+		if np.p != (irtrx.TimePair{}) {
+			fmt.Fprintf(w, "  %-6s %d %d\n", np.name, us(np.p[0]), us(np.p[1]))
+		}
+	}
+	if r.Ptrail != 0 {
+		fmt.Fprintf(w, "  ptrail %d\n", us(r.Ptrail))
+	}
+	if r.PreDataBits != 0 {
+		fmt.Fprintf(w, "  pre_data_bits %d\n  pre_data 0x%X\n", r.PreDataBits, r.PreData)
+	}
+	if r.PostDataBits != 0 {
+		fmt.Fprintf(w, "  post_data_bits %d\n  post_data 0x%X\n", r.PostDataBits, r.PostData)
+	}
+	if r.Gap != 0 {
+		fmt.Fprintf(w, "  gap   %d\n", us(r.Gap))
+	}
+	if r.Frequency != 0 {
+		fmt.Fprintf(w, "  frequency %d\n", r.Frequency)
+	}
+
+	if len(r.Codes) > 0 {
+		fmt.Fprintln(w, "\n  begin codes")
+		for _, c := range r.Codes {
+			fmt.Fprintf(w, "    %-24s 0x%X\n", c.Name, c.Value)
+		}
+		fmt.Fprintln(w, "  end codes")
+	}
+	if len(r.RawCodes) > 0 {
+		fmt.Fprintln(w, "\n  begin raw_codes")
+		for _, rc := range r.RawCodes {
+			fmt.Fprintf(w, "    name %s\n", rc.Name)
+			writeRaw(w, rc.Pairs)
+		}
+		fmt.Fprintln(w, "  end raw_codes")
+	}
+	fmt.Fprintln(w, "end remote")
+}
+
+// writeRaw writes pairs six durations to a line. The last space is the gap
+// and is left out, as LIRC expects.
+func writeRaw(w io.Writer, pairs []irtrx.TimePair) {
+	n := 0
+	put := func(d time.Duration) {
+		if n%6 == 0 {
+			fmt.Fprint(w, "     ")
+		}
+		fmt.Fprintf(w, " %7d", us(d))
+		n++
+		if n%6 == 0 {
+			fmt.Fprintln(w)
+		}
+	}
+	for i, p := range pairs {
+		put(p[0])
+		if i < len(pairs)-1 {
+			put(p[1])
+		}
+	}
+	if n%6 != 0 {
+		fmt.Fprintln(w)
+	}
+}
+
+// NewRawRemote returns a RAW_CODES Remote with a key for each of recordings,
+// named by names, ready to Write.
+func NewRawRemote(name string, names []string, recordings []irtrx.Recording) *Remote {
+	r := &Remote{Name: name, Flags: []string{"RAW_CODES"}, Eps: 30, Aeps: 100 * time.Microsecond}
+	for i, rec := range recordings {
+		if i >= len(names) {
+			break
+		}
+		if r.Frequency == 0 {
+			r.Frequency = rec.Freq
+		}
+		if n := len(rec.Pairs); n > 0 && rec.Pairs[n-1][1] > r.Gap {
+			r.Gap = rec.Pairs[n-1][1]
+		}
+		r.RawCodes = append(r.RawCodes, RawCode{Name: names[i], Pairs: rec.Pairs})
+	}
+	return r
+}
@@ -0,0 +1,272 @@
+// lirc reads and writes lircd.conf remote definitions, for interop with
+// Linux IR tooling. Remotes using SPACE_ENC or PULSE_ENC are turned into
+// codec Specs, so each key can be sent and received; RAW_CODES remotes are
+// sent as Recordings and received by matching against the raw timings.
+// Bi-phase (RC5, RC6) remotes are parsed but can't be sent or received.
+package lirc
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/codec"
+)
+
+var (
+	// ErrSyntax is returned for a malformed lircd.conf.
+	ErrSyntax = errors.New("lirc: syntax error")
+	// ErrUnsupported is returned for remotes whose encoding isn't supported.
+	ErrUnsupported = errors.New("lirc: unsupported encoding")
+	// ErrNoKey is returned when a remote has no key with the name asked for.
+	ErrNoKey = errors.New("lirc: no such key")
+)
+
+// Code is a key of a remote that sends values.
+type Code struct {
+	Name  string
+	Value uint64
+}
+
+// RawCode is a key of a RAW_CODES remote.
+type RawCode struct {
+	Name  string
+	Pairs []irtrx.TimePair
+}
+
+// Remote is a remote definition, i.e. a "begin remote" section of a
+// lircd.conf. Zero TimePairs and durations mean the setting is absent.
+type Remote struct {
+	Name  string
+	Bits  int
+	Flags []string
+	// Eps is the relative tolerance in percent and Aeps the absolute one.
+	Eps  int
+	Aeps time.Duration
+
+	Header irtrx.TimePair
+	One    irtrx.TimePair
+	Zero   irtrx.TimePair
+	Ptrail time.Duration
+	Repeat irtrx.TimePair
+	Gap    time.Duration
+
+	PreDataBits  int
+	PreData      uint64
+	PostDataBits int
+	PostData     uint64
+
+	Frequency uint32
+
+	Codes    []Code
+	RawCodes []RawCode
+}
+
+// HasFlag reports whether flag is among r's flags.
+func (r *Remote) HasFlag(flag string) bool {
+	for _, f := range r.Flags {
+		if strings.EqualFold(f, flag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Raw reports whether r is a RAW_CODES remote.
+func (r *Remote) Raw() bool {
+	return r.HasFlag("RAW_CODES") || (len(r.RawCodes) > 0 && len(r.Codes) == 0)
+}
+
+var supportedFlags = map[string]bool{
+	"SPACE_ENC":     true,
+	"PULSE_ENC":     true,
+	"CONST_LENGTH":  true,
+	"REVERSE":       true,
+	"NO_HEAD_REP":   true,
+	"NO_FOOT_REP":   true,
+	"REPEAT_HEADER": true,
+}
+
+// totalBits is the length of a frame including pre and post data.
+func (r *Remote) totalBits() int {
+	return r.PreDataBits + r.Bits + r.PostDataBits
+}
+
+// Spec returns the codec Spec for r's frames, which carry the full value
+// (pre data, code and post data) of a key; see Value.
+func (r *Remote) Spec() (*codec.Spec, error) {
+	if r.Raw() {
+		return nil, ErrUnsupported
+	}
+	for _, f := range r.Flags {
+		if !supportedFlags[strings.ToUpper(f)] {
+			return nil, ErrUnsupported
+		}
+	}
+	if r.totalBits() == 0 || r.totalBits() > codec.MaxBits {
+		return nil, ErrUnsupported
+	}
+	s := &codec.Spec{
+		Name:      r.Name,
+		Freq:      r.Frequency,
+		Header:    r.Header,
+		One:       r.One,
+		Zero:      r.Zero,
+		Trailer:   r.Ptrail,
+		Gap:       r.Gap,
+		Bits:      r.totalBits(),
+		MSBFirst:  !r.HasFlag("REVERSE"),
+		Repeat:    r.Repeat,
+		Tolerance: r.Eps,
+	}
+	if r.HasFlag("CONST_LENGTH") {
+		// gap is the time from the start of one frame to the next
+		s.RepeatPeriod = r.Gap
+		s.Gap = r.Gap - r.frameLength()
+	}
+	return s, nil
+}
+
+// frameLength is the length of a frame up to the gap, for a key of average
+// value.
+func (r *Remote) frameLength() time.Duration {
+	d := r.Header[0] + r.Header[1] + r.Ptrail
+	d += time.Duration(r.totalBits()) * (r.One[0] + r.One[1] + r.Zero[0] + r.Zero[1]) / 2
+	return d
+}
+
+// Value returns the full value sent for code, including pre and post data.
+func (r *Remote) Value(code uint64) uint64 {
+	v := r.PreData<<(r.Bits+r.PostDataBits) | code<<r.PostDataBits | r.PostData
+	if n := r.totalBits(); n < 64 {
+		v &= 1<<n - 1
+	}
+	return v
+}
+
+// code returns the code within a full value, and whether the pre and post
+// data match.
+func (r *Remote) code(v uint64) (uint64, bool) {
+	post := v & (1<<r.PostDataBits - 1)
+	code := (v >> r.PostDataBits) & (1<<r.Bits - 1)
+	pre := v >> (r.Bits + r.PostDataBits)
+	return code, pre == r.PreData && post == r.PostData
+}
+
+// Key returns a FrameMarshaller sending the key called name.
+func (r *Remote) Key(name string) (irtrx.FrameMarshaller, error) {
+	if r.Raw() {
+		for _, rc := range r.RawCodes {
+			if rc.Name == name {
+				return irtrx.Recording{Freq: r.Frequency, Pairs: rc.Pairs}, nil
+			}
+		}
+		return nil, ErrNoKey
+	}
+	s, err := r.Spec()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range r.Codes {
+		if c.Name == name {
+			return s.Code(r.Value(c.Value)), nil
+		}
+	}
+	return nil, ErrNoKey
+}
+
+// Decoder returns an irtrx.RxStateMachine calling handler with the name of
+// every key of r received. Decoding requires StartInverted() and not
+// Start().
+func (r *Remote) Decoder(handler func(name string)) (irtrx.RxStateMachine, error) {
+	if r.Raw() {
+		return newRawDecoder(r, handler), nil
+	}
+	s, err := r.Spec()
+	if err != nil {
+		return nil, err
+	}
+	return codec.NewDecoder(s, func(v uint64) {
+		code, ok := r.code(v)
+		if !ok {
+			return
+		}
+		for _, c := range r.Codes {
+			if c.Value == code {
+				handler(c.Name)
+				return
+			}
+		}
+	}), nil
+}
+
+// rawDecoder matches received pairs against the raw codes of a remote.
+type rawDecoder struct {
+	r       *Remote
+	handler func(string)
+	gap     time.Duration
+	buf     []irtrx.TimePair
+}
+
+func newRawDecoder(r *Remote, handler func(string)) *rawDecoder {
+	max := 0
+	for _, rc := range r.RawCodes {
+		max = maxInt(max, len(rc.Pairs))
+	}
+	gap := r.Gap / 2
+	if gap == 0 {
+		gap = 20 * time.Millisecond
+	}
+	return &rawDecoder{r: r, handler: handler, gap: gap, buf: make([]irtrx.TimePair, 0, max)}
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func (d *rawDecoder) HandleTimePair(pair irtrx.TimePair) {
+	if len(d.buf) < cap(d.buf) {
+		d.buf = append(d.buf, pair)
+	}
+	if pair[1] < d.gap && len(d.buf) < cap(d.buf) {
+		return
+	}
+	defer func() { d.buf = d.buf[:0] }()
+	for _, rc := range d.r.RawCodes {
+		if d.matches(rc.Pairs) {
+			d.handler(rc.Name)
+			return
+		}
+	}
+}
+
+func (d *rawDecoder) matches(pairs []irtrx.TimePair) bool {
+	if len(pairs) != len(d.buf) {
+		return false
+	}
+	eps := d.r.Eps
+	if eps == 0 {
+		eps = codec.DefaultTolerance
+	}
+	within := func(got, want time.Duration) bool {
+		diff := got - want
+		if diff < 0 {
+			diff = -diff
+		}
+		return diff <= d.r.Aeps || codec.Within(got, want, eps)
+	}
+	for i, p := range pairs {
+		if !within(d.buf[i][0], p[0]) {
+			return false
+		}
+		// the last space runs into the gap
+		if i < len(pairs)-1 && !within(d.buf[i][1], p[1]) {
+			return false
+		}
+	}
+	return true
+}
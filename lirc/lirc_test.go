@@ -0,0 +1,287 @@
+package lirc_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/irtest"
+	"github.com/sparques/irtrx/lirc"
+)
+
+func us(n int) time.Duration { return time.Duration(n) * time.Microsecond }
+
+// conf holds an NEC remote with a trailer, a Sony remote without one, whose
+// last bit's space runs into the gap, and a raw remote whose last code ends
+// on a pulse.
+const conf = `
+# comments and blank lines are skipped
+
+begin remote
+  name  nec
+  bits           16
+  flags SPACE_ENC|CONST_LENGTH
+  eps            30
+  aeps          100
+  header       9000  4500
+  one           562  1687
+  zero          562   562
+  ptrail        562
+  repeat       9000  2250
+  pre_data_bits  16
+  pre_data   0x00FF
+  gap        108000
+  toggle_bit_mask 0x0   # not used
+
+  begin codes
+    KEY_POWER                0x45BA
+    KEY_MUTE                 0x47B8   # trailing comment
+  end codes
+end remote
+
+begin remote
+  name  sony
+  bits  12
+  flags SPACE_ENC|CONST_LENGTH
+  eps   30
+  aeps  100
+  header 2400 600
+  one    1200 600
+  zero    600 600
+  gap   45000
+
+  begin codes
+    KEY_POWER 0xA90
+    KEY_ODD   0xA91
+  end codes
+end remote
+
+begin remote
+  name  learned
+  flags RAW_CODES
+  eps   30
+  aeps  100
+  gap   40000
+
+  begin raw_codes
+    name KEY_A
+      9000 4500  560  560
+       560 1690  560
+    name KEY_B
+      9000
+      4500  560 1690
+       560
+  end raw_codes
+end remote
+`
+
+func parse(t *testing.T) []*lirc.Remote {
+	t.Helper()
+	remotes, err := lirc.Parse(strings.NewReader(conf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remotes) != 3 {
+		t.Fatalf("parsed %d remotes, want 3", len(remotes))
+	}
+	return remotes
+}
+
+func TestParse(t *testing.T) {
+	nec := parse(t)[0]
+	want := &lirc.Remote{
+		Name:        "nec",
+		Bits:        16,
+		Flags:       []string{"SPACE_ENC", "CONST_LENGTH"},
+		Eps:         30,
+		Aeps:        us(100),
+		Header:      irtrx.TimePair{us(9000), us(4500)},
+		One:         irtrx.TimePair{us(562), us(1687)},
+		Zero:        irtrx.TimePair{us(562), us(562)},
+		Ptrail:      us(562),
+		Repeat:      irtrx.TimePair{us(9000), us(2250)},
+		PreDataBits: 16,
+		PreData:     0x00FF,
+		Gap:         us(108000),
+		Codes:       []lirc.Code{{"KEY_POWER", 0x45BA}, {"KEY_MUTE", 0x47B8}},
+	}
+	if !reflect.DeepEqual(nec, want) {
+		t.Errorf("got %+v, want %+v", nec, want)
+	}
+	if v := nec.Value(0x45BA); v != 0x00FF45BA {
+		t.Errorf("Value(0x45BA) = %#x, want 0xff45ba", v)
+	}
+}
+
+func TestParseRawCodes(t *testing.T) {
+	r := parse(t)[2]
+	if !r.Raw() {
+		t.Error("Raw() = false")
+	}
+	want := []lirc.RawCode{
+		// an odd number of durations: the trailing pulse gets the gap
+		{"KEY_A", []irtrx.TimePair{{us(9000), us(4500)}, {us(560), us(560)}, {us(560), us(1690)}, {us(560), us(40000)}}},
+		// pairs split across lines
+		{"KEY_B", []irtrx.TimePair{{us(9000), us(4500)}, {us(560), us(1690)}, {us(560), us(40000)}}},
+	}
+	if !reflect.DeepEqual(r.RawCodes, want) {
+		t.Errorf("got %v, want %v", r.RawCodes, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		conf string
+		line int
+	}{
+		{"CodesOutsideRemote", "begin codes\nend codes\n", 1},
+		{"NestedRemote", "begin remote\n name a\n begin remote\n", 3},
+		{"UnknownSection", "begin remote\n begin foo\n end foo\nend remote\n", 2},
+		{"UnknownEnd", "begin remote\n end foo\nend remote\n", 2},
+		{"MismatchedEnd", "begin remote\n begin raw_codes\n end codes\n", 3},
+		{"RemoteEndsCodes", "begin remote\n begin codes\n  KEY_A 0x1\nend remote\n", 4},
+		{"EndOutsideRemote", "end remote\n", 1},
+		{"MissingEnd", "begin remote\n name a\n", 2},
+		{"CodeOutsideCodes", "KEY_A 0x1\n", 1},
+		{"BadValue", "begin remote\n begin codes\n  KEY_A 0xZZ\n", 3},
+		{"NoValue", "begin remote\n begin codes\n  KEY_A\n", 3},
+		{"RawBeforeName", "begin remote\n begin raw_codes\n  9000 4500\n", 3},
+		{"BadDuration", "begin remote\n begin raw_codes\n  name KEY_A\n  9000 -4500\n", 4},
+		{"BadSetting", "begin remote\n header 9000\n", 2},
+	} {
+		_, err := lirc.Parse(strings.NewReader(tc.conf))
+		if !errors.Is(err, lirc.ErrSyntax) {
+			t.Errorf("%s: got %v, want %v", tc.name, err, lirc.ErrSyntax)
+			continue
+		}
+		if want := fmt.Sprintf("line %d:", tc.line); !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: %q doesn't say %q", tc.name, err, want)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	remotes := parse(t)
+	var buf bytes.Buffer
+	if err := lirc.Write(&buf, remotes...); err != nil {
+		t.Fatal(err)
+	}
+	got, err := lirc.Parse(&buf)
+	if err != nil {
+		t.Fatalf("parsing what Write wrote: %v\n%s", err, buf.String())
+	}
+	for i := range remotes {
+		if !reflect.DeepEqual(got[i], remotes[i]) {
+			t.Errorf("got %+v, want %+v", got[i], remotes[i])
+		}
+	}
+}
+
+func TestNewRawRemote(t *testing.T) {
+	rec := irtrx.Recording{Freq: 38000, Pairs: []irtrx.TimePair{{us(9000), us(4500)}, {us(560), us(45000)}}}
+	r := lirc.NewRawRemote("learned", []string{"KEY_A", "KEY_B"}, []irtrx.Recording{rec})
+	if r.Frequency != 38000 || r.Gap != us(45000) || len(r.RawCodes) != 1 {
+		t.Errorf("got %+v", r)
+	}
+	// written without the last space, which comes back as the gap
+	var buf bytes.Buffer
+	lirc.Write(&buf, r)
+	got, err := lirc.Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got[0].RawCodes[0].Pairs, rec.Pairs) {
+		t.Errorf("got %v, want %v", got[0].RawCodes[0].Pairs, rec.Pairs)
+	}
+}
+
+// roundTrip sends each of keys of r through a Wire to r's Decoder and
+// returns the names decoded.
+func roundTrip(t *testing.T, r *lirc.Remote, keys ...string) []string {
+	t.Helper()
+	var got []string
+	sm, err := r.Decoder(func(name string) { got = append(got, name) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := irtest.NewWire(sm, true)
+	for _, k := range keys {
+		fm, err := r.Key(k)
+		if err != nil {
+			t.Fatalf("Key(%q): %v", k, err)
+		}
+		w.SendFrame(fm)
+	}
+	// the mark that ends the last frame's final space
+	w.SendPair(irtrx.TimePair{us(560), us(560)})
+	return got
+}
+
+func TestKey(t *testing.T) {
+	nec := parse(t)[0]
+	fm, err := nec.Key("KEY_POWER")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 0x00FF45BA, MSB first, packed in the order sent
+	f := fm.(irtrx.Frame)
+	if got, want := f.Bits(), []byte{0x00, 0xFF, 0xA2, 0x5D}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Bits() = %x, want %x", got, want)
+	}
+	if _, err := nec.Key("KEY_NONE"); err != lirc.ErrNoKey {
+		t.Errorf("Key(KEY_NONE): got %v, want %v", err, lirc.ErrNoKey)
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	remotes := parse(t)
+	for _, tc := range []struct {
+		r    *lirc.Remote
+		keys []string
+	}{
+		{remotes[0], []string{"KEY_POWER", "KEY_MUTE"}},
+		// without a trailer the last bit, 0 then 1, is told apart by
+		// its mark alone
+		{remotes[1], []string{"KEY_POWER", "KEY_ODD", "KEY_POWER"}},
+		{remotes[2], []string{"KEY_A", "KEY_B"}},
+	} {
+		if got := roundTrip(t, tc.r, tc.keys...); !reflect.DeepEqual(got, tc.keys) {
+			t.Errorf("%s: decoded %v, want %v", tc.r.Name, got, tc.keys)
+		}
+	}
+}
+
+func TestDecoderPreData(t *testing.T) {
+	nec := parse(t)[0]
+	other := *nec
+	other.PreData = 0x01FE
+	var got []string
+	sm, _ := nec.Decoder(func(name string) { got = append(got, name) })
+	fm, _ := other.Key("KEY_POWER")
+	w := irtest.NewWire(sm, true)
+	w.SendFrame(fm)
+	w.SendPair(irtrx.TimePair{us(560), us(560)})
+	if len(got) != 0 {
+		t.Errorf("decoded %v from another remote's pre data", got)
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	r := &lirc.Remote{Name: "rc5", Bits: 13, Flags: []string{"RC5", "CONST_LENGTH"}}
+	if _, err := r.Spec(); err != lirc.ErrUnsupported {
+		t.Errorf("Spec(): got %v, want %v", err, lirc.ErrUnsupported)
+	}
+	if _, err := r.Decoder(func(string) {}); err != lirc.ErrUnsupported {
+		t.Errorf("Decoder(): got %v, want %v", err, lirc.ErrUnsupported)
+	}
+	r = &lirc.Remote{Name: "long", Bits: 60, PreDataBits: 8, Flags: []string{"SPACE_ENC"}}
+	if _, err := r.Spec(); err != lirc.ErrUnsupported {
+		t.Errorf("68 bits: got %v, want %v", err, lirc.ErrUnsupported)
+	}
+}
@@ -0,0 +1,209 @@
+// mqttbridge turns a board into an IR gateway for Home Assistant and other
+// MQTT based home automation: decoded frames are published to MQTT and codes
+// published to a send topic are transmitted.
+//
+// The bridge doesn't depend on any particular MQTT library. Wrap whichever
+// one your target has (e.g. paho on Linux, natiu-mqtt on TinyGo) in a Client.
+//
+//	b := mqttbridge.New(client, tx, "livingroom/ir")
+//	rx := irtrx.NewRxDevice(rxPin, samsung.NewStateMachine(mqttbridge.Handler[samsung.Frame](b, "samsung")))
+//	rx.StartInverted()
+//	b.Start()
+//	b.Run(nil)
+//
+// Frames are published as JSON to <prefix>/received/<protocol>. Codes are
+// sent by publishing to <prefix>/send, e.g.
+//
+//	{"protocol": "samsung", "code": "0xFD020707"}
+//	{"protocol": "broadlink", "data": "JgBQAAAB..."}
+//	{"protocol": "raw", "freq": 38000, "pairs": [[9000, 4500], [560, 560]]}
+package mqttbridge
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/broadlink"
+	"github.com/sparques/irtrx/codec"
+	"github.com/sparques/irtrx/hexbug"
+	"github.com/sparques/irtrx/samsung"
+)
+
+// Client is the part of an MQTT client the bridge uses.
+type Client interface {
+	Publish(topic string, payload []byte) error
+	Subscribe(topic string, handler func(topic string, payload []byte)) error
+}
+
+// Encoder turns a code into a frame.
+type Encoder func(code uint64) (irtrx.FrameMarshaller, error)
+
+// QueueLen is the number of received frames that can wait to be published.
+// Frames received while the queue is full are dropped.
+const QueueLen = 16
+
+var (
+	// ErrProtocol is published for send requests with an unknown protocol.
+	ErrProtocol = errors.New("mqttbridge: unknown protocol")
+	// ErrPayload is published for send requests that can't be parsed.
+	ErrPayload = errors.New("mqttbridge: bad payload")
+)
+
+type received struct {
+	protocol string
+	frame    any
+}
+
+// Bridge connects a Client to a Transmitter and any number of decoders.
+type Bridge struct {
+	client   Client
+	tx       irtrx.Transmitter
+	prefix   string
+	encoders map[string]Encoder
+	queue    chan received
+}
+
+// New returns a Bridge using topics under prefix. It knows how to send the
+// samsung, samsung48, nec and hexbug protocols as well as broadlink and raw
+// codes; add more with Register.
+func New(client Client, tx irtrx.Transmitter, prefix string) *Bridge {
+	b := &Bridge{
+		client:   client,
+		tx:       tx,
+		prefix:   strings.TrimSuffix(prefix, "/"),
+		encoders: map[string]Encoder{},
+		queue:    make(chan received, QueueLen),
+	}
+	b.Register("samsung", func(code uint64) (irtrx.FrameMarshaller, error) {
+		var f samsung.Frame
+		err := f.UnmarshalFrame(uint32(code))
+		return &f, err
+	})
+	b.Register("samsung48", func(code uint64) (irtrx.FrameMarshaller, error) {
+		var f samsung.ExtFrame
+		err := f.UnmarshalFrame(code)
+		return &f, err
+	})
+	b.Register("nec", func(code uint64) (irtrx.FrameMarshaller, error) {
+		return codec.NEC.Code(code), nil
+	})
+	b.Register("hexbug", func(code uint64) (irtrx.FrameMarshaller, error) {
+		return hexbug.Cmd(code), nil
+	})
+	return b
+}
+
+// Register adds or replaces the Encoder for protocol.
+func (b *Bridge) Register(protocol string, enc Encoder) {
+	b.encoders[protocol] = enc
+}
+
+// Topic returns the full topic for a subtopic.
+func (b *Bridge) Topic(sub string) string {
+	return b.prefix + "/" + sub
+}
+
+// Start subscribes to the send topic and publishes "online" to
+// <prefix>/status.
+func (b *Bridge) Start() error {
+	if err := b.client.Subscribe(b.Topic("send"), b.handleSend); err != nil {
+		return err
+	}
+	return b.client.Publish(b.Topic("status"), []byte("online"))
+}
+
+// Received queues a decoded frame for publishing. It doesn't block, so it
+// is safe to call from a decoder's handler in interrupt context.
+func (b *Bridge) Received(protocol string, frame any) {
+	select {
+	case b.queue <- received{protocol, frame}:
+	default:
+	}
+}
+
+// Handler returns a decoder handler that queues frames as protocol.
+func Handler[T any](b *Bridge, protocol string) func(T) {
+	return func(frame T) {
+		b.Received(protocol, frame)
+	}
+}
+
+// Run publishes queued frames until done is closed. A nil done runs forever.
+func (b *Bridge) Run(done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case r := <-b.queue:
+			payload, err := json.Marshal(r.frame)
+			if err != nil {
+				continue
+			}
+			b.client.Publish(b.Topic("received/"+r.protocol), payload)
+		}
+	}
+}
+
+// sendRequest is the payload of the send topic.
+type sendRequest struct {
+	Protocol string           `json:"protocol"`
+	Code     json.RawMessage  `json:"code"`
+	Data     string           `json:"data"`
+	Freq     uint32           `json:"freq"`
+	Pairs    []irtrx.TimePair `json:"pairs"`
+}
+
+// code parses the code, which may be a JSON number or a string holding a
+// decimal or 0x prefixed hex number; large codes lose precision as numbers.
+func (r *sendRequest) code() (uint64, error) {
+	var s string
+	if err := json.Unmarshal(r.Code, &s); err != nil {
+		s = string(r.Code)
+	}
+	v, err := strconv.ParseUint(strings.TrimSpace(s), 0, 64)
+	if err != nil {
+		return 0, ErrPayload
+	}
+	return v, nil
+}
+
+func (b *Bridge) frame(req *sendRequest) (irtrx.FrameMarshaller, error) {
+	switch req.Protocol {
+	case "raw":
+		return irtrx.Recording{Freq: req.Freq, Pairs: req.Pairs}, nil
+	case "broadlink":
+		p, err := broadlink.DecodeBase64(req.Data)
+		if err != nil {
+			return nil, err
+		}
+		return &p, nil
+	}
+	enc, ok := b.encoders[req.Protocol]
+	if !ok {
+		return nil, ErrProtocol
+	}
+	code, err := req.code()
+	if err != nil {
+		return nil, err
+	}
+	return enc(code)
+}
+
+// handleSend transmits a send request. Errors are published to
+// <prefix>/error.
+func (b *Bridge) handleSend(topic string, payload []byte) {
+	var req sendRequest
+	err := json.Unmarshal(payload, &req)
+	var fm irtrx.FrameMarshaller
+	if err == nil {
+		fm, err = b.frame(&req)
+	}
+	if err != nil {
+		b.client.Publish(b.Topic("error"), []byte(err.Error()))
+		return
+	}
+	b.tx.SendFrame(fm)
+}
@@ -0,0 +1,275 @@
+// pairing binds a receiver to a single transmitter, so several robots and
+// their remotes can share a room. The transmitter picks a random session
+// ID and advertises it at low power while held close to the receiver;
+// the receiver, put in bind mode, takes the first ID it hears. From then on
+// a Filter drops frames that don't carry the session, and the ID can be
+// saved to flash to survive power cycles.
+//
+// Protocols without room for a full ID carry a reduced form of it: the
+// hexbug channel (HexbugChannel) or the PPM model ID (ModelID). Transmitter
+// side:
+//
+//	id := pairing.NewID()
+//	pairing.Advertise(tx, id, 3*time.Second)
+//	remote := hexbug.NewTransmitter(tx, id.HexbugChannel())
+//
+// Receiver side:
+//
+//	p := pairing.NewPairing()
+//	p.Save, p.Load = saveID, loadID
+//	if !p.Restore() {
+//	    p.Bind()
+//	}
+//	hb := hexbug.NewStateMachine(pairing.Filter(p, pairing.MatchHexbug, handle))
+//	rx := irtrx.NewRxDevice(rxPin, irtrx.MultiRxStateMachine(p, hb))
+//	rx.Start()
+//
+// Bind frames are stream frames, which are decoded with StartInverted while
+// hexbug and PPM need Start; Pairing copes with either.
+package pairing
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/hexbug"
+	"github.com/sparques/irtrx/ppm"
+	"github.com/sparques/irtrx/stream"
+)
+
+// ID identifies a pairing session. The zero ID means unpaired.
+type ID uint32
+
+// NewID returns a random, non-zero ID.
+func NewID() ID {
+	for {
+		if id := ID(rand.Uint32()); id != 0 {
+			return id
+		}
+	}
+}
+
+func (id ID) String() string {
+	return fmt.Sprintf("%08X", uint32(id))
+}
+
+// HexbugChannel returns the hexbug channel carrying the session.
+func (id ID) HexbugChannel() hexbug.Channel {
+	return hexbug.Channel1 + hexbug.Channel(id%4)
+}
+
+// ModelID returns the PPM model ID carrying the session.
+func (id ID) ModelID() int {
+	return int(id % (ppm.MaxModelID + 1))
+}
+
+// MatchHexbug is a Filter match function for hexbug commands, matching
+// those on the session's channel.
+func MatchHexbug(cmd hexbug.Cmd, id ID) bool {
+	return cmd.Channel() == id.HexbugChannel()
+}
+
+// Filter returns a handler passing frames on to handler only if match
+// reports they belong to p's session. Everything is dropped while p is
+// unpaired.
+func Filter[T any](p *Pairing, match func(frame T, id ID) bool, handler func(T)) func(T) {
+	return func(frame T) {
+		if id, ok := p.ID(); ok && match(frame, id) {
+			handler(frame)
+		}
+	}
+}
+
+// magic starts the payload of every bind frame.
+const magic = "PAIR"
+
+// ErrNotBind is returned when unmarshalling a frame that isn't a bind frame.
+var ErrNotBind = errors.New("pairing: not a bind frame")
+
+// BindFrame advertises a session ID. It is sent as a stream.Frame.
+type BindFrame struct {
+	ID ID
+}
+
+func (bf *BindFrame) payload() stream.Frame {
+	return binary.LittleEndian.AppendUint32([]byte(magic), uint32(bf.ID))
+}
+
+// MarshalFrame implements irtrx.FrameMarshaller.
+func (bf *BindFrame) MarshalFrame() []irtrx.TimePair {
+	return bf.payload().MarshalFrame()
+}
+
+// UnmarshalTimePairs implements irtrx.FrameUnmarshaller.
+func (bf *BindFrame) UnmarshalTimePairs(pairs []irtrx.TimePair) error {
+	var f stream.Frame
+	if err := f.UnmarshalTimePairs(pairs); err != nil {
+		return err
+	}
+	return bf.unmarshal(f)
+}
+
+func (bf *BindFrame) unmarshal(payload []byte) error {
+	if len(payload) != len(magic)+4 || string(payload[:len(magic)]) != magic {
+		return ErrNotBind
+	}
+	bf.ID = ID(binary.LittleEndian.Uint32(payload[len(magic):]))
+	return nil
+}
+
+const (
+	// BindPower is the transmit power, in percent, Advertise uses to keep
+	// binding to close range.
+	BindPower = 10
+	// BindInterval is the time from the start of one bind frame to the
+	// start of the next while advertising.
+	BindInterval = 150 * time.Millisecond
+)
+
+// Advertise sends bind frames for id for d. If tx has a SetPower method,
+// as irtrx.TxDevice does, power is turned down to BindPower meanwhile so
+// only a receiver held close by binds.
+func Advertise(tx irtrx.Transmitter, id ID, d time.Duration) {
+	AdvertiseClock(irtrx.RealClock, tx, id, d)
+}
+
+// AdvertiseClock is Advertise timed by c.
+func AdvertiseClock(c irtrx.Clock, tx irtrx.Transmitter, id ID, d time.Duration) {
+	type powered interface {
+		SetPower(percent uint8)
+		Power() uint8
+	}
+	if pt, ok := tx.(powered); ok {
+		defer pt.SetPower(pt.Power())
+		pt.SetPower(BindPower)
+	}
+	bf := &BindFrame{ID: id}
+	start := c.Now()
+	for next := start; next.Sub(start) < d; next = next.Add(BindInterval) {
+		if wait := next.Sub(c.Now()); wait > 0 {
+			c.Sleep(wait)
+		}
+		tx.SendFrame(bf)
+	}
+}
+
+// Pairing is the receiving end of a session. It implements
+// irtrx.RxStateMachine, listening for bind frames while in bind mode; run it
+// alongside the protocol's decoder with irtrx.MultiRxStateMachine.
+//
+// To keep a session across power cycles, set Save to write the ID to flash
+// and Load to read it back, then call Restore at startup.
+type Pairing struct {
+	// Save, if not nil, is called with the ID once bound. It is called
+	// from interrupt context, so it should hand the work off rather than
+	// write to flash itself.
+	Save func(id ID)
+	// Load, if not nil, is used by Restore to read back a saved ID.
+	Load func() (id ID, ok bool)
+	// OnBind, if not nil, is called with the ID whenever it changes, by
+	// binding, SetID or Restore, e.g. to pass ModelID to
+	// ppm.StateMachine.SetModelID. It is called from interrupt context when
+	// binding.
+	OnBind func(id ID)
+
+	// sm decodes pairs as delivered and flipped re-pairs them, for
+	// whichever of Start and StartInverted wasn't used; see HandleTimePair
+	sm, flipped stream.StateMachine
+	mark        time.Duration
+	id          atomic.Uint32
+	binding     atomic.Bool
+}
+
+// NewPairing returns an unpaired Pairing.
+func NewPairing() *Pairing {
+	p := &Pairing{}
+	p.sm.PayloadHandler = p.received
+	p.flipped.PayloadHandler = p.received
+	return p
+}
+
+// HandleTimePair implements irtrx.RxStateMachine. Bind frames are decoded
+// whether the RxDevice was started with Start or StartInverted: pairs are
+// decoded as they come, and also re-paired with the mark of each pair going
+// with the space of the next.
+func (p *Pairing) HandleTimePair(pair irtrx.TimePair) {
+	if !p.binding.Load() {
+		return
+	}
+	p.sm.HandleTimePair(pair)
+	p.flipped.HandleTimePair(irtrx.TimePair{p.mark, pair[0]})
+	p.mark = pair[1]
+}
+
+func (p *Pairing) received(payload []byte) {
+	var bf BindFrame
+	if bf.unmarshal(payload) != nil || bf.ID == 0 || !p.binding.Load() {
+		return
+	}
+	p.binding.Store(false)
+	p.setID(bf.ID)
+	if p.Save != nil {
+		p.Save(bf.ID)
+	}
+}
+
+func (p *Pairing) setID(id ID) {
+	p.id.Store(uint32(id))
+	if p.OnBind != nil {
+		p.OnBind(id)
+	}
+}
+
+// Bind puts p in bind mode: the next bind frame heard sets the session,
+// replacing any existing one.
+func (p *Pairing) Bind() {
+	p.binding.Store(true)
+}
+
+// Binding reports whether p is in bind mode.
+func (p *Pairing) Binding() bool {
+	return p.binding.Load()
+}
+
+// SetID pairs with id without waiting to hear it, ending bind mode.
+func (p *Pairing) SetID(id ID) {
+	p.binding.Store(false)
+	p.setID(id)
+}
+
+// Unbind forgets the session; until the next bind, Filter drops everything.
+func (p *Pairing) Unbind() {
+	p.binding.Store(false)
+	p.id.Store(0)
+}
+
+// ID returns the session ID and whether there is one.
+func (p *Pairing) ID() (ID, bool) {
+	id := ID(p.id.Load())
+	return id, id != 0
+}
+
+// Restore pairs with the ID returned by Load, if any. It returns whether an
+// ID was restored.
+func (p *Pairing) Restore() bool {
+	if p.Load == nil {
+		return false
+	}
+	id, ok := p.Load()
+	if ok && id != 0 {
+		p.SetID(id)
+		return true
+	}
+	return false
+}
+
+var (
+	_ irtrx.FrameMarshaller   = (*BindFrame)(nil)
+	_ irtrx.FrameUnmarshaller = (*BindFrame)(nil)
+	_ irtrx.RxStateMachine    = (*Pairing)(nil)
+)
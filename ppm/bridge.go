@@ -0,0 +1,122 @@
+package ppm
+
+import (
+	"io"
+	"time"
+)
+
+// BridgeProtocol selects the serial protocol a Bridge outputs.
+type BridgeProtocol uint8
+
+const (
+	// SBUS is Futaba's serial bus: 100000 baud, 8E2, inverted. Most MCUs need
+	// an external inverter (or a UART with configurable inversion).
+	SBUS BridgeProtocol = iota
+	// IBus is FlySky's serial bus: 115200 baud, 8N1.
+	IBus
+)
+
+// Bridge republishes decoded channels as SBUS or iBus frames so IR PPM can
+// feed flight controllers and servo boards that don't take PPM input.
+//
+// Example:
+//
+//	machine.UART1.Configure(machine.UARTConfig{BaudRate: 115200})
+//	br := ppm.NewBridge(psm, machine.UART1, ppm.IBus)
+//	for {
+//	    br.Update()
+//	    time.Sleep(7 * time.Millisecond)
+//	}
+type Bridge struct {
+	sm    *StateMachine
+	w     io.Writer
+	proto BridgeProtocol
+}
+
+// NewBridge returns a Bridge writing frames in protocol proto to w, which
+// will usually be a UART configured appropriately for proto.
+func NewBridge(sm *StateMachine, w io.Writer, proto BridgeProtocol) *Bridge {
+	return &Bridge{sm: sm, w: w, proto: proto}
+}
+
+// Update writes a single frame with the current channel values. Call it
+// periodically from your main loop--every 14ms for SBUS or 7ms for iBus is
+// typical--rather than from a frame handler, as it blocks on the UART.
+func (b *Bridge) Update() error {
+	chs := b.sm.Channels()
+	var err error
+	switch b.proto {
+	case IBus:
+		f := IBusFrame(chs)
+		_, err = b.w.Write(f[:])
+	default:
+		f := SBUSFrame(chs, b.sm.IsSafe())
+		_, err = b.w.Write(f[:])
+	}
+	return err
+}
+
+// SBUSFrame encodes chs as a 25 byte SBUS frame. If failsafe is true, the
+// frame's failsafe and frame-lost flags are set.
+func SBUSFrame(chs [MaxChannels]time.Duration, failsafe bool) [25]byte {
+	var out [25]byte
+	out[0] = 0x0F
+
+	var bits uint32
+	var nbits uint
+	idx := 1
+	for _, ch := range chs {
+		bits |= uint32(sbusValue(ch)) << nbits
+		nbits += 11
+		for nbits >= 8 {
+			out[idx] = byte(bits)
+			idx++
+			bits >>= 8
+			nbits -= 8
+		}
+	}
+
+	if failsafe {
+		out[23] = 0b1100
+	}
+	out[24] = 0x00
+	return out
+}
+
+// sbusValue maps 1000us..2000us onto the conventional SBUS range of 192..1792.
+func sbusValue(d time.Duration) uint16 {
+	us := int(d / time.Microsecond)
+	v := (us - 880) * 8 / 5
+	if v < 0 {
+		return 0
+	}
+	if v > 2047 {
+		return 2047
+	}
+	return uint16(v)
+}
+
+// IBusFrame encodes the first 14 channels of chs as a 32 byte iBus frame.
+// Invalid channels are sent as Center.
+func IBusFrame(chs [MaxChannels]time.Duration) [32]byte {
+	var out [32]byte
+	out[0] = 0x20
+	out[1] = 0x40
+	for i := 0; i < 14; i++ {
+		ch := chs[i]
+		if ch == Invalid {
+			ch = Center
+		}
+		us := uint16(ch / time.Microsecond)
+		out[2+2*i] = byte(us)
+		out[3+2*i] = byte(us >> 8)
+	}
+
+	sum := uint16(0xFFFF)
+	for _, b := range out[:30] {
+		sum -= uint16(b)
+	}
+	out[30] = byte(sum)
+	out[31] = byte(sum >> 8)
+	return out
+}
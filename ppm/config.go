@@ -0,0 +1,86 @@
+package ppm
+
+import (
+	"errors"
+	"time"
+)
+
+// OutOfRange determines what the decoder does with channel pulses outside
+// Config.MinPulse..Config.MaxPulse.
+type OutOfRange uint8
+
+const (
+	// OutOfRangeReject rejects the whole frame.
+	OutOfRangeReject OutOfRange = iota
+	// OutOfRangeClamp clamps the pulse to the nearest limit.
+	OutOfRangeClamp
+	// OutOfRangeAccept passes the pulse through unchanged.
+	OutOfRangeAccept
+)
+
+var (
+	// ErrChannelCount is returned for a Config with a channel count outside
+	// 0..MaxChannels.
+	ErrChannelCount = errors.New("ppm: channel count out of range")
+	// ErrPulseRange is returned for a Config whose MinPulse is not less than
+	// its MaxPulse.
+	ErrPulseRange = errors.New("ppm: MinPulse must be less than MaxPulse")
+	// ErrSyncGap is returned for a Config whose SyncGap could be mistaken
+	// for a channel pulse.
+	ErrSyncGap = errors.New("ppm: SyncGap must be longer than MaxPulse")
+)
+
+// Config holds the frame timing parameters of a StateMachine.
+type Config struct {
+	// Channels is the number of channels expected per frame; zero accepts
+	// any number up to MaxChannels.
+	Channels int
+	// SyncGap is the threshold above which a gap is taken as the sync
+	// between frames.
+	SyncGap time.Duration
+	// MinPulse and MaxPulse bound a valid channel pulse. Either may be
+	// zero for no limit.
+	MinPulse, MaxPulse time.Duration
+	// OutOfRange is what to do with pulses outside MinPulse..MaxPulse.
+	OutOfRange OutOfRange
+}
+
+// DefaultConfig returns the Config used by NewStateMachine: any number of
+// channels, a 6ms sync gap and no limits on pulse width.
+func DefaultConfig() Config {
+	return Config{
+		SyncGap: minimumTimeBetweenFrames,
+	}
+}
+
+// Validate checks that cfg makes sense.
+func (cfg Config) Validate() error {
+	if cfg.Channels < 0 || cfg.Channels > MaxChannels {
+		return ErrChannelCount
+	}
+	if cfg.MinPulse != 0 && cfg.MaxPulse != 0 && cfg.MinPulse >= cfg.MaxPulse {
+		return ErrPulseRange
+	}
+	if cfg.MaxPulse != 0 && cfg.SyncGap <= cfg.MaxPulse {
+		return ErrSyncGap
+	}
+	return nil
+}
+
+// checkPulse applies the out of range policy to d. It returns false if the
+// frame should be rejected.
+func (cfg *Config) checkPulse(d time.Duration) (time.Duration, bool) {
+	switch {
+	case cfg.MinPulse != 0 && d < cfg.MinPulse:
+		if cfg.OutOfRange == OutOfRangeClamp {
+			return cfg.MinPulse, true
+		}
+	case cfg.MaxPulse != 0 && d > cfg.MaxPulse:
+		if cfg.OutOfRange == OutOfRangeClamp {
+			return cfg.MaxPulse, true
+		}
+	default:
+		return d, true
+	}
+	return d, cfg.OutOfRange == OutOfRangeAccept
+}
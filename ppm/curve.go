@@ -0,0 +1,46 @@
+package ppm
+
+// Curve shapes a channel's normalized value, as returned by ChannelF32 or
+// ChannelUnipolar. Toy-grade IR control only has about 38 steps of
+// resolution, so softening the response around center makes a big
+// difference. The zero Curve is linear.
+type Curve struct {
+	// Expo blends between linear (0) and cubic (1) response, softening the
+	// center while keeping full travel at the ends.
+	Expo float32
+	// Points, if set, defines a piecewise linear curve applied after Expo.
+	// The points are evenly spaced over the input range (-1..1 for
+	// ChannelF32, 0..1 for ChannelUnipolar) and give the output at each.
+	// At least two points are needed.
+	Points []float32
+}
+
+// apply shapes x, which lies within lo..1.
+func (c *Curve) apply(x, lo float32) float32 {
+	if c.Expo != 0 {
+		x = (1-c.Expo)*x + c.Expo*x*x*x
+	}
+	if len(c.Points) < 2 {
+		return x
+	}
+	pos := (x - lo) / (1 - lo) * float32(len(c.Points)-1)
+	i := int(pos)
+	if i >= len(c.Points)-1 {
+		return c.Points[len(c.Points)-1]
+	}
+	if i < 0 {
+		return c.Points[0]
+	}
+	frac := pos - float32(i)
+	return c.Points[i] + (c.Points[i+1]-c.Points[i])*frac
+}
+
+// SetCurve sets the Curve applied to channel ch's normalized values.
+func (sm *StateMachine) SetCurve(ch int, c Curve) {
+	sm.curves[ch] = c
+}
+
+// SetExpo is shorthand for setting a Curve with just Expo.
+func (sm *StateMachine) SetExpo(ch int, expo float32) {
+	sm.curves[ch] = Curve{Expo: expo}
+}
@@ -0,0 +1,55 @@
+package ppm
+
+import "sync/atomic"
+
+// failsafeWatch tracks failsafe transitions for SetFailsafeHandler.
+type failsafeWatch struct {
+	handler func(failsafe bool)
+	// in failsafe as far as the handler knows; a StateMachine starts out in
+	// failsafe, having received nothing yet
+	active  atomic.Bool
+	running bool
+}
+
+// SetFailsafeHandler sets a callback that is called exactly once with true
+// when the StateMachine enters failsafe (Timeout passes without a good
+// frame) and exactly once with false when a good frame arrives again. The
+// StateMachine starts out in failsafe, so the first good frame produces a
+// call with false.
+//
+// Leaving failsafe is reported from the interrupt handler, so handler must be
+// quick. Entering failsafe is detected by a goroutine started by the first
+// call to SetFailsafeHandler.
+func (sm *StateMachine) SetFailsafeHandler(handler func(failsafe bool)) {
+	sm.watch.handler = handler
+	if sm.watch.running {
+		return
+	}
+	sm.watch.active.Store(true)
+	sm.watch.running = true
+	go sm.watchFailsafe()
+}
+
+func (sm *StateMachine) watchFailsafe() {
+	for {
+		if sm.watch.active.Load() {
+			// the interrupt handler will take us out of failsafe
+			sm.clock.Sleep(sm.Timeout)
+			continue
+		}
+		if d := sm.Timeout - sm.clock.Now().Sub(sm.last); d > 0 {
+			sm.clock.Sleep(d)
+			continue
+		}
+		if sm.watch.active.CompareAndSwap(false, true) && sm.watch.handler != nil {
+			sm.watch.handler(true)
+		}
+	}
+}
+
+// frameReceived is called from endFrame for each good frame.
+func (sm *StateMachine) frameReceived() {
+	if sm.watch.running && sm.watch.active.CompareAndSwap(true, false) && sm.watch.handler != nil {
+		sm.watch.handler(false)
+	}
+}
@@ -0,0 +1,71 @@
+package ppm
+
+import "time"
+
+// MaxMedian is the largest window Filter.Median supports.
+const MaxMedian = 7
+
+// Filter configures smoothing of a channel's received values. A 38kHz
+// carrier quantizes pulses to about 26us, which makes servos chatter; a
+// little filtering goes a long way. The zero Filter does nothing.
+type Filter struct {
+	// Median, if greater than 1, replaces each value with the median of the
+	// last Median values received, which rejects the odd glitch outright.
+	// Values over MaxMedian are treated as MaxMedian.
+	Median int
+	// Alpha, if greater than 0, applies a first-order low-pass filter after
+	// the median filter: each new value moves the output Alpha of the way
+	// from where it was. Smaller is smoother but laggier. Values over 1 are
+	// treated as 1.
+	Alpha float32
+}
+
+type channelFilter struct {
+	median  int
+	history [MaxMedian]time.Duration
+	n, idx  int
+	// alpha in 1/256ths; integer math keeps the interrupt handler quick on
+	// MCUs without an FPU
+	alpha int64
+}
+
+// SetFilter sets the smoothing applied to channel ch as frames are received.
+func (sm *StateMachine) SetFilter(ch int, f Filter) {
+	cf := channelFilter{median: f.Median}
+	if cf.median > MaxMedian {
+		cf.median = MaxMedian
+	}
+	if f.Alpha > 0 && f.Alpha < 1 {
+		cf.alpha = int64(f.Alpha * 256)
+	}
+	sm.filters[ch] = cf
+}
+
+// apply returns the filtered output given the previous output and the newly
+// received value.
+func (cf *channelFilter) apply(prev, v time.Duration) time.Duration {
+	if cf.median > 1 {
+		cf.history[cf.idx] = v
+		cf.idx = (cf.idx + 1) % cf.median
+		if cf.n < cf.median {
+			cf.n++
+		}
+		v = median(cf.history[:cf.n])
+	}
+	if cf.alpha != 0 && prev != 0 {
+		v = prev + time.Duration(int64(v-prev)*cf.alpha/256)
+	}
+	return v
+}
+
+// median returns the median of vals without modifying or allocating.
+func median(vals []time.Duration) time.Duration {
+	var sorted [MaxMedian]time.Duration
+	n := copy(sorted[:], vals)
+	for i := 1; i < n; i++ {
+		for j := i; j > 0 && sorted[j] < sorted[j-1]; j-- {
+			sorted[j], sorted[j-1] = sorted[j-1], sorted[j]
+		}
+	}
+	return sorted[n/2]
+}
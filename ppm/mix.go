@@ -0,0 +1,44 @@
+package ppm
+
+// Mixers combine normalized channel values, as returned by ChannelF32, into
+// outputs for airframes and drivetrains that need more than one actuator to
+// move on a single axis. All inputs and outputs are in -1..1; outputs are
+// clamped.
+
+// Elevon mixes pitch and roll for a flying wing, where each wing has a single
+// control surface doing the job of both elevator and aileron.
+func Elevon(pitch, roll float32) (left, right float32) {
+	return clamp(pitch+roll, -1, 1), clamp(pitch-roll, -1, 1)
+}
+
+// VTail mixes pitch and yaw for a V-tail, where the two ruddervators do the
+// job of both elevator and rudder.
+func VTail(pitch, yaw float32) (left, right float32) {
+	return clamp(pitch+yaw, -1, 1), clamp(pitch-yaw, -1, 1)
+}
+
+// Differential mixes throttle and steering into left and right motor
+// outputs for tank-style (skid steer) robots. Positive steering turns right.
+//
+// When throttle and steering together would exceed full power, both outputs
+// are scaled down so that the turn is preserved rather than clipped.
+func Differential(throttle, steering float32) (left, right float32) {
+	left = throttle + steering
+	right = throttle - steering
+	max := abs(left)
+	if a := abs(right); a > max {
+		max = a
+	}
+	if max > 1 {
+		left /= max
+		right /= max
+	}
+	return left, right
+}
+
+func abs(f float32) float32 {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
@@ -19,7 +19,7 @@ If using to drive a 180 degree servo, that means each graduation is 4.7 degrees
 	rx.Start()
 
 	for {
-	    X := float32(ppm.Channel(0)-1500)/500
+	    X := psm.ChannelF32(0)
 	    // X ranges from -1 to 1
 	    servo.Set(X)
 	    time.Sleep(100 * time.Millisecond)
@@ -28,11 +28,16 @@ If using to drive a 180 degree servo, that means each graduation is 4.7 degrees
 package ppm
 
 import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/sparques/irtrx"
 )
 
+// minimumTimeBetweenFrames is the default sync gap threshold.
 const minimumTimeBetweenFrames = 6 * time.Millisecond
 
 var (
@@ -40,87 +45,507 @@ var (
 	SafeChannelsBottom = [16]time.Duration{1000 * time.Microsecond, 1000 * time.Microsecond, 1000 * time.Microsecond, 1000 * time.Microsecond, 1000 * time.Microsecond, 1000 * time.Microsecond, 1000 * time.Microsecond, 1000 * time.Microsecond, 1000 * time.Microsecond, 1000 * time.Microsecond, 1000 * time.Microsecond, 1000 * time.Microsecond, 1000 * time.Microsecond, 1000 * time.Microsecond, 1000 * time.Microsecond, 1000 * time.Microsecond}
 )
 
+// MaxChannels is the most channels a StateMachine can decode.
+const MaxChannels = 16
+
+// Failsafe is the policy for what a channel reports once Timeout has been
+// exceeded.
+type Failsafe uint8
+
+const (
+	// FailsafePreset reports the channel's value from SetSafeChannels. This
+	// is the default for all channels.
+	FailsafePreset Failsafe = iota
+	// FailsafeHold reports the last value received.
+	FailsafeHold
+	// FailsafeInvalid reports Invalid.
+	FailsafeInvalid
+)
+
+// Invalid is reported for channels using FailsafeInvalid once Timeout has
+// been exceeded. No valid channel is ever zero length.
+const Invalid time.Duration = 0
+
 type StateMachine struct {
 	// where we store the values we've decoded
-	channels [16]time.Duration
+	channels [MaxChannels]time.Duration
+	// the frame currently being received; only copied to channels once the
+	// whole frame has arrived and checks out
+	pending [MaxChannels]time.Duration
 	// safeChannels are what we set channels to if we exceed Timeout
-	safeChannels [16]time.Duration
+	safeChannels [MaxChannels]time.Duration
+	// failsafe is the policy applied to each channel if we exceed Timeout
+	failsafe [MaxChannels]Failsafe
+	// calibrated endpoints for each channel; zero means the nominal 1ms/2ms
+	calMin, calMax [MaxChannels]time.Duration
+	// transforms applied to received values
+	transforms [MaxChannels]Transform
+	// smoothing applied as frames are received
+	filters [MaxChannels]channelFilter
+	// shaping applied to normalized values
+	curves [MaxChannels]Curve
 
 	// if we haven't received a frame in Timeout amount of time, we return
 	// values from safeChannels
 	Timeout time.Duration
 
+	// number of channels expected per frame; zero accepts any number
+	numChannels int
+	cfg         Config
+
+	// failsafe notification; see failsafe.go
+	watch failsafeWatch
+
+	clock irtrx.Clock
+
+	// addressing; see modelid.go
+	modelID int
+	binding bool
+
+	// called, if set, each time a complete frame has been received
+	frameHandler func([MaxChannels]time.Duration)
+
+	// link statistics
+	frames, malformed int
+	avgInterval       time.Duration
+	longestGap        time.Duration
+
 	currentCh int
-	last      time.Time
+	synced    bool
+	// the frame currently being received is to be rejected
+	bad  bool
+	last time.Time
 }
 
+// NewStateMachine returns a StateMachine that accepts frames with any
+// number of channels, up to MaxChannels.
 func NewStateMachine() *StateMachine {
+	return NewStateMachineChannels(0)
+}
+
+// NewStateMachineChannels returns a StateMachine that expects exactly
+// numChannels channels per frame. Frames with any other number of pulses are
+// rejected. Most RC gear sends 6 to 8 channels. A numChannels of zero accepts
+// any number of channels; values over MaxChannels are treated as
+// MaxChannels.
+func NewStateMachineChannels(numChannels int) *StateMachine {
+	if numChannels > MaxChannels {
+		numChannels = MaxChannels
+	}
+	cfg := DefaultConfig()
+	cfg.Channels = numChannels
+	sm, _ := NewStateMachineConfig(cfg)
+	return sm
+}
+
+// NewStateMachineConfig returns a StateMachine configured by cfg, or an error
+// if cfg doesn't make sense.
+func NewStateMachineConfig(cfg Config) (*StateMachine, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
 	def := &StateMachine{
-		Timeout:      100 * minimumTimeBetweenFrames,
+		Timeout:      100 * cfg.SyncGap,
 		safeChannels: SafeChannelsMid,
 		channels:     SafeChannelsMid,
+		numChannels:  cfg.Channels,
+		cfg:          cfg,
+		modelID:      NoModelID,
+		clock:        irtrx.RealClock,
 	}
-	return def
+	return def, nil
+}
+
+// NumChannels returns the number of channels expected per frame, or zero if
+// any number is accepted.
+func (sm *StateMachine) NumChannels() int {
+	return sm.numChannels
 }
 
 func (sm *StateMachine) HandleTimePair(pair irtrx.TimePair) {
 	on, off := pair[0], pair[1]
-	if on > minimumTimeBetweenFrames {
-		sm.last = time.Now()
+	if on > sm.cfg.SyncGap {
+		sm.endFrame()
+		sm.synced = true
+		sm.bad = false
 		sm.currentCh = 0
 		return
 	}
 	// prevent starting up mid from from causing us to dump wrong values
 	// onto channels. The effect of this is the robot momentarily freaking
 	// out / running away from you.
-	if sm.last.IsZero() {
+	if !sm.synced || sm.bad {
 		return
 	}
 
-	// prevent out-of-spec signals from panicking us.
-	if sm.currentCh >= len(sm.channels) {
+	// prevent out-of-spec signals from panicking us; the frame is rejected
+	// when the sync arrives.
+	if sm.currentCh >= len(sm.pending) {
+		sm.bad = true
 		return
 	}
 
 	on += off
-	sm.channels[sm.currentCh] = on.Round(10 * time.Microsecond)
+	// a model ID header is longer than any channel; it is checked by
+	// endFrame.
+	if sm.currentCh == 0 && sm.addressed() && decodeModelID(on) != NoModelID {
+		sm.pending[0] = on
+		sm.currentCh++
+		return
+	}
+	on, ok := sm.cfg.checkPulse(on)
+	if !ok {
+		sm.bad = true
+		return
+	}
+	sm.pending[sm.currentCh] = on.Round(10 * time.Microsecond)
 
 	sm.currentCh++
 }
 
+// endFrame commits the pending frame if it is complete.
+func (sm *StateMachine) endFrame() {
+	if !sm.synced || (sm.currentCh == 0 && !sm.bad) {
+		return
+	}
+	pending := sm.pending[:sm.currentCh]
+	if !sm.bad && sm.addressed() {
+		var ok bool
+		pending, ok = sm.checkModelID(pending)
+		if !ok {
+			// someone else's transmitter; not our problem
+			return
+		}
+	}
+	if sm.bad || len(pending) == 0 || (sm.numChannels != 0 && len(pending) != sm.numChannels) {
+		sm.malformed++
+		return
+	}
+	for ch := range pending {
+		sm.channels[ch] = sm.filters[ch].apply(sm.channels[ch], pending[ch])
+	}
+	now := sm.clock.Now()
+	if !sm.last.IsZero() {
+		gap := now.Sub(sm.last)
+		if gap > sm.longestGap {
+			sm.longestGap = gap
+		}
+		if sm.avgInterval == 0 {
+			sm.avgInterval = gap
+		} else {
+			sm.avgInterval += (gap - sm.avgInterval) / 8
+		}
+	}
+	sm.last = now
+	sm.frames++
+	sm.frameReceived()
+	if sm.frameHandler != nil {
+		sm.frameHandler(sm.channels)
+	}
+}
+
+// SetFrameHandler sets a callback that is called once per complete frame
+// with all the channels, so control loops can update outputs exactly once
+// per frame rather than polling Channel. Like all RxStateMachine callbacks,
+// frameHandler is called from an interrupt handler and must be quick. Pass
+// nil to remove the callback.
+func (sm *StateMachine) SetFrameHandler(frameHandler func([MaxChannels]time.Duration)) {
+	sm.frameHandler = frameHandler
+}
+
+// Center is the nominal center value of a channel.
+const Center = 1500 * time.Microsecond
+
+// Transform is applied to a channel's received values before they are
+// returned, doing the job of transmitter-side setup on the receiving end.
+// The zero Transform passes values through unchanged.
+type Transform struct {
+	// Trim is added to every value, shifting the center.
+	Trim time.Duration
+	// Reverse mirrors values about Center.
+	Reverse bool
+	// Values within Deadband of Center are reported as Center.
+	Deadband time.Duration
+	// Min and Max are the endpoints; values are clamped to them. Zero means
+	// no limit.
+	Min, Max time.Duration
+}
+
+// Apply returns d with the Transform applied.
+func (t Transform) Apply(d time.Duration) time.Duration {
+	d += t.Trim
+	if t.Reverse {
+		d = 2*Center - d
+	}
+	if t.Deadband != 0 && d > Center-t.Deadband && d < Center+t.Deadband {
+		d = Center
+	}
+	if t.Min != 0 && d < t.Min {
+		d = t.Min
+	}
+	if t.Max != 0 && d > t.Max {
+		d = t.Max
+	}
+	return d
+}
+
+// SetTransform sets the Transform applied to channel ch. Failsafe preset
+// values are not transformed.
+func (sm *StateMachine) SetTransform(ch int, t Transform) {
+	sm.transforms[ch] = t
+}
+
+func (sm *StateMachine) received(ch int) time.Duration {
+	return sm.transforms[ch].Apply(sm.channels[ch])
+}
+
+// Stats holds PPM link quality statistics.
+type Stats struct {
+	// Frames is the number of good frames received.
+	Frames int
+	// Malformed is the number of frames rejected for having the wrong
+	// number of channels or out of range pulses.
+	Malformed int
+	// FrameRate is a running average of good frames per second.
+	FrameRate float32
+	// LongestGap is the longest time between two good frames.
+	LongestGap time.Duration
+	// SinceLast is the time since the last good frame.
+	SinceLast time.Duration
+}
+
+// Stats returns link statistics accumulated since the StateMachine was
+// created or ResetStats was called. Use them to display link quality or to
+// pick a Timeout based on real data.
+func (sm *StateMachine) Stats() Stats {
+	st := Stats{
+		Frames:     sm.frames,
+		Malformed:  sm.malformed,
+		LongestGap: sm.longestGap,
+	}
+	if sm.avgInterval != 0 {
+		st.FrameRate = float32(time.Second) / float32(sm.avgInterval)
+	}
+	if !sm.last.IsZero() {
+		st.SinceLast = sm.clock.Now().Sub(sm.last)
+	}
+	return st
+}
+
+// ResetStats zeroes the link statistics.
+func (sm *StateMachine) ResetStats() {
+	sm.frames = 0
+	sm.malformed = 0
+	sm.avgInterval = 0
+	sm.longestGap = 0
+}
+
 func (sm *StateMachine) SetSafeChannels(sc [16]time.Duration) {
 	sm.safeChannels = sc
 }
 
 func (sm *StateMachine) IsSafe() bool {
-	return time.Since(sm.last) > sm.Timeout
+	return sm.clock.Now().Sub(sm.last) > sm.Timeout
+}
+
+// SetClock replaces the clock used for Timeout, link statistics and the
+// failsafe watcher. Set it before SetFailsafeHandler.
+func (sm *StateMachine) SetClock(c irtrx.Clock) {
+	sm.clock = c
+}
+
+// SetFailsafe sets the policy for channel ch once Timeout is exceeded. For
+// example, throttle might use FailsafePreset with a safe value of zero
+// throttle while steering uses FailsafeHold.
+func (sm *StateMachine) SetFailsafe(ch int, f Failsafe) {
+	sm.failsafe[ch] = f
+}
+
+func (sm *StateMachine) safeValue(ch int) time.Duration {
+	switch sm.failsafe[ch] {
+	case FailsafeHold:
+		return sm.received(ch)
+	case FailsafeInvalid:
+		return Invalid
+	}
+	return sm.safeChannels[ch]
 }
 
 // Channel returns the duration of the the pulse for the given channel.
 // Converting the time.Duration value into something more useful is left
 // to the caller.
-// If ppm.Timeout has been exceeded, the safe value for the channel is
-// returned.
+// If ppm.Timeout has been exceeded, the channel's Failsafe policy determines
+// what is returned.
 func (sm *StateMachine) Channel(ch int) time.Duration {
 	if sm.IsSafe() {
-		return sm.safeChannels[ch]
+		return sm.safeValue(ch)
 	}
-	return sm.channels[ch]
+	return sm.received(ch)
 }
 
 // Channels returns all the channels.
-// If ppm.Timeout has been exceeded, the safe values are returned
+// If ppm.Timeout has been exceeded, each channel's Failsafe policy
+// determines what is returned.
 func (sm *StateMachine) Channels() [16]time.Duration {
-	if sm.IsSafe() {
-		return sm.safeChannels
+	var out [MaxChannels]time.Duration
+	safe := sm.IsSafe()
+	for ch := range out {
+		if safe {
+			out[ch] = sm.safeValue(ch)
+		} else {
+			out[ch] = sm.received(ch)
+		}
+	}
+	return out
+}
+
+// SetCalibration sets the pulse widths that correspond to the extremes of
+// channel ch for ChannelF32 and ChannelUnipolar. Uncalibrated channels use
+// the nominal 1ms and 2ms.
+func (sm *StateMachine) SetCalibration(ch int, min, max time.Duration) {
+	sm.calMin[ch] = min
+	sm.calMax[ch] = max
+}
+
+func (sm *StateMachine) endpoints(ch int) (min, max time.Duration) {
+	min, max = sm.calMin[ch], sm.calMax[ch]
+	if min == 0 && max == 0 {
+		return time.Millisecond, 2 * time.Millisecond
+	}
+	return
+}
+
+// ChannelF32 returns channel ch normalized to -1..1, with 0 at center,
+// using calibration data if it has been set and shaped by the channel's
+// Curve. Values are clamped to the range. An Invalid channel reads as 0.
+func (sm *StateMachine) ChannelF32(ch int) float32 {
+	d := sm.Channel(ch)
+	if d == Invalid {
+		return 0
+	}
+	min, max := sm.endpoints(ch)
+	return sm.curves[ch].apply(clamp(float32(2*d-(max+min))/float32(max-min), -1, 1), -1)
+}
+
+// ChannelUnipolar returns channel ch normalized to 0..1, which suits
+// throttle channels, using calibration data if it has been set and shaped by
+// the channel's Curve. Values are clamped to the range. An Invalid channel
+// reads as 0.
+func (sm *StateMachine) ChannelUnipolar(ch int) float32 {
+	d := sm.Channel(ch)
+	if d == Invalid {
+		return 0
 	}
-	return sm.channels
+	min, max := sm.endpoints(ch)
+	return sm.curves[ch].apply(clamp(float32(d-min)/float32(max-min), 0, 1), 0)
+}
+
+func clamp(f, min, max float32) float32 {
+	if f < min {
+		return min
+	}
+	if f > max {
+		return max
+	}
+	return f
 }
 
 func DurationToFloat32(d time.Duration) float32 {
 	return float32(2*d-3*time.Millisecond) / float32(time.Millisecond)
 }
 
+const (
+	// MarkWidth is the width of the pulse separating channels when transmitting.
+	MarkWidth = 400 * time.Microsecond
+	// FramePeriod is the standard PPM frame period. Frames with too many
+	// channels to fit are stretched.
+	FramePeriod = 22500 * time.Microsecond
+	// minimumSync is the shortest sync gap we'll transmit; it leaves some
+	// margin over what the decoder requires.
+	minimumSync = minimumTimeBetweenFrames + time.Millisecond
+)
+
+// Frame holds channel values, each 1ms to 2ms, for transmitting over IR.
+// Frame implements irtrx.FrameMarshaller; the result is decoded by
+// StateMachine on the receiving end, making for a complete IR RC link.
+//
+// Each channel is sent as a mark of MarkWidth followed by a space making up
+// the rest of the channel's time; the frame ends with a final mark and a
+// sync gap that pads the frame out to FramePeriod.
+type Frame []time.Duration
+
+// MarshalFrame implements irtrx.FrameMarshaller.
+func (f Frame) MarshalFrame() []irtrx.TimePair {
+	out := make([]irtrx.TimePair, len(f)+1)
+	var total time.Duration
+	for i, ch := range f {
+		if ch < MarkWidth {
+			ch = MarkWidth
+		}
+		out[i] = irtrx.TimePair{MarkWidth, ch - MarkWidth}
+		total += ch
+	}
+
+	sync := FramePeriod - total - MarkWidth
+	if sync < minimumSync {
+		sync = minimumSync
+	}
+	out[len(f)] = irtrx.TimePair{MarkWidth, sync}
+
+	return out
+}
+
+func (f Frame) String() string {
+	return fmt.Sprint([]time.Duration(f))
+}
+
+// Protocol implements irtrx.Frame.
+func (f Frame) Protocol() string { return "ppm" }
+
+// Bits implements irtrx.Frame. PPM has no bits as such, so each channel is
+// given as its value in microseconds, two bytes little endian.
+func (f Frame) Bits() []byte {
+	b := make([]byte, 0, 2*len(f))
+	for _, ch := range f {
+		b = binary.LittleEndian.AppendUint16(b, uint16(ch.Microseconds()))
+	}
+	return b
+}
+
+// ErrNoSync is returned by Frame.UnmarshalTimePairs when pairs don't contain
+// a complete frame.
+var ErrNoSync = errors.New("ppm: no sync gap")
+
+// UnmarshalTimePairs implements irtrx.FrameUnmarshaller, decoding the first
+// complete frame in pairs: the channels preceding the first sync gap, with a
+// leading sync gap skipped.
+func (f *Frame) UnmarshalTimePairs(pairs []irtrx.TimePair) error {
+	var out Frame
+	for _, p := range pairs {
+		if p[1] < minimumTimeBetweenFrames {
+			out = append(out, p[0]+p[1])
+			continue
+		}
+		if len(out) != 0 {
+			*f = out
+			return nil
+		}
+	}
+	return ErrNoSync
+}
+
+// Float32ToDuration is the inverse of DurationToFloat32, converting -1..1
+// into a 1ms to 2ms channel value. Values outside -1..1 are clamped.
+func Float32ToDuration(f float32) time.Duration {
+	if f < -1 {
+		f = -1
+	}
+	if f > 1 {
+		f = 1
+	}
+	return time.Duration((f*float32(time.Millisecond) + 3*float32(time.Millisecond)) / 2)
+}
+
 /*
 type PPMCalibrator struct {
 	PPM *PPM
@@ -160,3 +585,58 @@ func (pc *PPMCalibrator) Channel(ch int) float32 {
 	return float32(2*pc.PPM.Channel(ch)-(pc.max[ch]+pc.min[ch])) / float32(pc.max[ch]-pc.min[ch])
 }
 */
+
+// MarshalJSON encodes f as its channel values in microseconds.
+func (f Frame) MarshalJSON() ([]byte, error) {
+	us := make([]int64, len(f))
+	for i, ch := range f {
+		us[i] = ch.Microseconds()
+	}
+	return json.Marshal(us)
+}
+
+// UnmarshalJSON decodes the form written by MarshalJSON.
+func (f *Frame) UnmarshalJSON(b []byte) error {
+	var us []int64
+	if err := json.Unmarshal(b, &us); err != nil {
+		return err
+	}
+	*f = make(Frame, len(us))
+	for i, v := range us {
+		(*f)[i] = time.Duration(v) * time.Microsecond
+	}
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler: the number of channels
+// followed by each value in microseconds, as uvarints.
+func (f Frame) MarshalBinary() ([]byte, error) {
+	b := binary.AppendUvarint(nil, uint64(len(f)))
+	for _, ch := range f {
+		b = binary.AppendUvarint(b, uint64(ch.Microseconds()))
+	}
+	return b, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. As with
+// irtrx.Recording, trailing bytes are an error.
+func (f *Frame) UnmarshalBinary(b []byte) error {
+	n, off := binary.Uvarint(b)
+	if off <= 0 || n > MaxChannels {
+		return irtrx.ErrBinary
+	}
+	out := make(Frame, n)
+	for i := range out {
+		us, m := binary.Uvarint(b[off:])
+		if m <= 0 {
+			return irtrx.ErrBinary
+		}
+		out[i] = time.Duration(us) * time.Microsecond
+		off += m
+	}
+	if off != len(b) {
+		return irtrx.ErrBinary
+	}
+	*f = out
+	return nil
+}
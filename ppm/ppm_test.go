@@ -0,0 +1,52 @@
+package ppm
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/irtest"
+)
+
+func TestFrameBinary(t *testing.T) {
+	f := Frame{1000 * time.Microsecond, 1500 * time.Microsecond, 2000 * time.Microsecond}
+	b, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 3 channels, then 1000, 1500 and 2000 as uvarints
+	want := []byte{3, 0xE8, 0x07, 0xDC, 0x0B, 0xD0, 0x0F}
+	if string(b) != string(want) {
+		t.Fatalf("MarshalBinary: % x, want % x", b, want)
+	}
+	var got Frame
+	if err := got.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != f.String() {
+		t.Errorf("UnmarshalBinary: %v, want %v", got, f)
+	}
+
+	for name, bad := range map[string][]byte{
+		"empty":     nil,
+		"truncated": b[:len(b)-1],
+		"trailing":  append(b[:len(b):len(b)], 0),
+		"too many":  {MaxChannels + 1},
+	} {
+		if err := got.UnmarshalBinary(bad); !errors.Is(err, irtrx.ErrBinary) {
+			t.Errorf("%s: err %v, want %v", name, err, irtrx.ErrBinary)
+		}
+	}
+}
+
+func BenchmarkStateMachine(b *testing.B) {
+	irtest.BenchmarkDecoder(b, irtest.Bench{
+		Name: "ppm",
+		New:  func() irtrx.RxStateMachine { return NewStateMachineChannels(8) },
+		Frame: Frame{
+			1000 * time.Microsecond, 1250 * time.Microsecond, 1500 * time.Microsecond, 1750 * time.Microsecond,
+			2000 * time.Microsecond, 1500 * time.Microsecond, 1500 * time.Microsecond, 1500 * time.Microsecond,
+		},
+	})
+}
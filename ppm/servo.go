@@ -0,0 +1,81 @@
+package ppm
+
+import (
+	"time"
+
+	"github.com/sparques/irtrx/internal/hal"
+)
+
+// ServoPeriod is the frame period of servo PWM outputs (50Hz).
+const ServoPeriod = 20 * time.Millisecond
+
+type servoOut struct {
+	ch     int
+	pgroup hal.PWMGroup
+	pch    uint8
+}
+
+// ServoOutputs drives standard servo PWM outputs directly from decoded
+// channels, updated on every received frame. Together with an IR receiver
+// this makes the MCU a complete multi-channel IR RC receiver.
+//
+// Example:
+//
+//	psm := ppm.NewStateMachineChannels(4)
+//	servos := ppm.NewServoOutputs(psm, machine.GPIO2, machine.GPIO3, machine.NoPin, machine.GPIO5)
+//	servos.Start()
+//	rx := irtrx.NewRxDevice(irPin, psm)
+//	rx.Start()
+type ServoOutputs struct {
+	sm   *StateMachine
+	outs []servoOut
+}
+
+// NewServoOutputs configures pins as 50Hz servo outputs, with pins[i]
+// driving channel i. Pass machine.NoPin for channels that shouldn't drive an
+// output. Under standard Go the pins are simulated, as for irtrx.TxDevice,
+// and each is high while its output pulses. Pins sharing a PWM slice share its period, so they all must be
+// servos.
+func NewServoOutputs(sm *StateMachine, pins ...hal.Pin) *ServoOutputs {
+	so := &ServoOutputs{sm: sm}
+	for ch, pin := range pins {
+		if pin == hal.NoPin || ch >= MaxChannels {
+			continue
+		}
+		pin.Configure(hal.PinConfig{Mode: hal.PinPWM})
+		pgroup := hal.GetPWM(pin)
+		pgroup.Configure(hal.PWMConfig{Period: uint64(ServoPeriod)})
+		pch, err := pgroup.Channel(pin)
+		if err != nil {
+			continue
+		}
+		so.outs = append(so.outs, servoOut{ch: ch, pgroup: pgroup, pch: pch})
+	}
+	return so
+}
+
+// Start hooks the outputs up to the StateMachine's frame handler, so they
+// are updated as each frame arrives. Any existing frame handler is still
+// called.
+//
+// While in failsafe no frames arrive, so call Update periodically from your
+// main loop if the outputs should move to their failsafe values.
+func (so *ServoOutputs) Start() {
+	prev := so.sm.frameHandler
+	so.sm.SetFrameHandler(func(chs [MaxChannels]time.Duration) {
+		so.Update()
+		if prev != nil {
+			prev(chs)
+		}
+	})
+}
+
+// Update sets every output from the StateMachine's current channel values,
+// honoring transforms and failsafe policies. Invalid channels stop
+// outputting pulses.
+func (so *ServoOutputs) Update() {
+	for _, out := range so.outs {
+		d := so.sm.Channel(out.ch)
+		out.pgroup.Set(out.pch, uint32(uint64(out.pgroup.Top())*uint64(d)/uint64(ServoPeriod)))
+	}
+}
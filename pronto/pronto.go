@@ -0,0 +1,184 @@
+// pronto parses and writes Pronto Hex, the format most IR code databases and
+// universal remote forums share codes in, e.g.
+//
+//	0000 006D 0022 0002 0155 00AA 0015 0040 ... 0015 0F25
+//
+// The first word is the code type, 0000 for a learned, modulated code. The
+// second sets the carrier: its period is the word times 0.241246µs. The
+// third and fourth are the number of pairs in the once sequence, sent when
+// a button is pressed, and the repeat sequence, sent while it is held. The
+// pairs follow as mark and space words, counted in carrier periods.
+package pronto
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sparques/irtrx"
+)
+
+// unit is the length, in microseconds, of one unit of the frequency word.
+const unit = 0.241246
+
+var (
+	// ErrFormat is returned by Parse for text that isn't Pronto Hex.
+	ErrFormat = errors.New("pronto: malformed code")
+	// ErrUnsupported is returned by Parse for codes other than learned,
+	// modulated ones, e.g. the rarely used predefined protocol codes.
+	ErrUnsupported = errors.New("pronto: unsupported code type")
+	// ErrFreq is returned by MarshalText for a Code whose Freq can't be
+	// written as a frequency word, e.g. zero.
+	ErrFreq = errors.New("pronto: carrier frequency out of range")
+)
+
+// Code is a Pronto Hex code. It implements irtrx.RepeatMarshaller and
+// irtrx.CarrierHinter.
+type Code struct {
+	// Freq is the carrier frequency in Hz.
+	Freq   uint32
+	Once   []irtrx.TimePair
+	Repeat []irtrx.TimePair
+}
+
+// Parse parses a Pronto Hex code. Words may be separated by any white space.
+func Parse(s string) (Code, error) {
+	fields := strings.Fields(s)
+	if len(fields) < 4 {
+		return Code{}, ErrFormat
+	}
+	words := make([]uint16, len(fields))
+	for i, f := range fields {
+		w, err := strconv.ParseUint(f, 16, 16)
+		if err != nil {
+			return Code{}, ErrFormat
+		}
+		words[i] = uint16(w)
+	}
+	if words[0] != 0 {
+		return Code{}, ErrUnsupported
+	}
+	if words[1] == 0 {
+		return Code{}, ErrFormat
+	}
+	once, repeat := int(words[2]), int(words[3])
+	if len(words) != 4+2*(once+repeat) {
+		return Code{}, ErrFormat
+	}
+	period := float64(words[1]) * unit
+	pairs := make([]irtrx.TimePair, once+repeat)
+	for i := range pairs {
+		pairs[i] = irtrx.TimePair{
+			periods(words[4+2*i], period),
+			periods(words[5+2*i], period),
+		}
+	}
+	return Code{
+		Freq:   uint32(math.Round(1e6 / period)),
+		Once:   pairs[:once:once],
+		Repeat: pairs[once:],
+	}, nil
+}
+
+func periods(w uint16, period float64) time.Duration {
+	return time.Duration(math.Round(float64(w)*period)) * time.Microsecond
+}
+
+// FromRecording returns a Code sending rec as its once sequence. A Recording
+// without a carrier frequency is given 38kHz.
+func FromRecording(rec irtrx.Recording) Code {
+	freq := rec.Freq
+	if freq == 0 {
+		freq = irtrx.Freq38Khz
+	}
+	return Code{Freq: freq, Once: rec.Pairs}
+}
+
+// String returns c in Pronto Hex, or the error from MarshalText.
+func (c Code) String() string {
+	b, err := c.MarshalText()
+	if err != nil {
+		return err.Error()
+	}
+	return string(b)
+}
+
+// MarshalText implements encoding.TextMarshaler, writing c in Pronto Hex.
+// Durations too long for a word are written as FFFF.
+func (c Code) MarshalText() ([]byte, error) {
+	if c.Freq == 0 {
+		return nil, ErrFreq
+	}
+	fw := math.Round(1e6 / (float64(c.Freq) * unit))
+	if fw < 1 || fw > 0xFFFF {
+		return nil, ErrFreq
+	}
+	period := fw * unit
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "0000 %04X %04X %04X", uint16(fw), len(c.Once), len(c.Repeat))
+	for _, seq := range [][]irtrx.TimePair{c.Once, c.Repeat} {
+		for _, p := range seq {
+			for _, d := range p {
+				w := math.Round(float64(d.Microseconds()) / period)
+				if w > 0xFFFF {
+					w = 0xFFFF
+				}
+				fmt.Fprintf(&sb, " %04X", uint16(w))
+			}
+		}
+	}
+	return []byte(sb.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, parsing Pronto Hex as
+// Parse does.
+func (c *Code) UnmarshalText(b []byte) error {
+	code, err := Parse(string(b))
+	if err != nil {
+		return err
+	}
+	*c = code
+	return nil
+}
+
+// MarshalFrame implements irtrx.FrameMarshaller, returning the once
+// sequence, or the repeat sequence if there is none.
+func (c *Code) MarshalFrame() []irtrx.TimePair {
+	if len(c.Once) == 0 {
+		return c.Repeat
+	}
+	return c.Once
+}
+
+// MarshalRepeat implements irtrx.RepeatMarshaller, returning the repeat
+// sequence, or the once sequence if there is none.
+func (c *Code) MarshalRepeat() []irtrx.TimePair {
+	if len(c.Repeat) == 0 {
+		return c.Once
+	}
+	return c.Repeat
+}
+
+// RepeatPeriod implements irtrx.RepeatMarshaller. Repeat sequences end with
+// their gap, so the period is just their length.
+func (c *Code) RepeatPeriod() time.Duration {
+	var d time.Duration
+	for _, p := range c.MarshalRepeat() {
+		d += p[0] + p[1]
+	}
+	return d
+}
+
+// Carrier implements irtrx.CarrierHinter.
+func (c *Code) Carrier() uint32 {
+	return c.Freq
+}
+
+// Recording returns the once sequence, or the repeat sequence if there is
+// none, as a Recording.
+func (c *Code) Recording() irtrx.Recording {
+	return irtrx.Recording{Freq: c.Freq, Pairs: c.MarshalFrame()}
+}
@@ -0,0 +1,130 @@
+package pronto
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sparques/irtrx"
+)
+
+const us = time.Microsecond
+
+// code is an NEC style header as its once sequence and a mark and gap as its
+// repeat sequence, at 38kHz: 0x6D * 0.241246us = 26.2958us per period.
+const code = "0000 006D 0001 0001 0155 00AA 0015 0F25"
+
+func TestParse(t *testing.T) {
+	want := Code{
+		Freq:   38029,
+		Once:   []irtrx.TimePair{{8967 * us, 4470 * us}},
+		Repeat: []irtrx.TimePair{{552 * us, 101949 * us}},
+	}
+	for _, s := range []string{
+		code,
+		"0000 006d 0001 0001 0155 00aa 0015 0f25",
+		"\t0000 006D\n0001 0001\r\n0155 00AA  0015 0F25\n",
+	} {
+		c, err := Parse(s)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", s, err)
+		}
+		if !reflect.DeepEqual(c, want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", s, c, want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for s, want := range map[string]error{
+		"":                                   ErrFormat,
+		"0000 006D 0001":                     ErrFormat,
+		"0000 006D 0001 0000 0155":           ErrFormat,
+		"0000 006D 0001 0000 0155 00AA 15":   ErrFormat,
+		"0000 006D 0000 0001 0155 00AA 0F":   ErrFormat,
+		"0000 0000 0001 0000 0155 00AA":      ErrFormat,
+		"0000 006D 0001 0000 0155 00AG":      ErrFormat,
+		"0000 006D 0001 0000 10155 00AA":     ErrFormat,
+		"0000 006D 0001 0000 0155 -0AA":      ErrFormat,
+		"0100 006D 0001 0000 0155 00AA":      ErrUnsupported,
+		"5000 006D 0000 0000":                ErrUnsupported,
+		"0000 006D FFFF FFFF 0155 00AA 0015": ErrFormat,
+	} {
+		if _, err := Parse(s); !errors.Is(err, want) {
+			t.Errorf("Parse(%q): %v, want %v", s, err, want)
+		}
+	}
+}
+
+func TestSequences(t *testing.T) {
+	c, _ := Parse(code)
+	if got := c.MarshalFrame(); !reflect.DeepEqual(got, c.Once) {
+		t.Errorf("MarshalFrame = %v, want the once sequence", got)
+	}
+	if got := c.MarshalRepeat(); !reflect.DeepEqual(got, c.Repeat) {
+		t.Errorf("MarshalRepeat = %v, want the repeat sequence", got)
+	}
+	if got, want := c.RepeatPeriod(), (552+101949)*us; got != want {
+		t.Errorf("RepeatPeriod = %v, want %v", got, want)
+	}
+
+	// each falls back on the other sequence when its own is empty
+	once := Code{Freq: 38029, Once: c.Once}
+	if got := once.MarshalRepeat(); !reflect.DeepEqual(got, c.Once) {
+		t.Errorf("once only: MarshalRepeat = %v, want %v", got, c.Once)
+	}
+	repeat := Code{Freq: 38029, Repeat: c.Repeat}
+	if got := repeat.MarshalFrame(); !reflect.DeepEqual(got, c.Repeat) {
+		t.Errorf("repeat only: MarshalFrame = %v, want %v", got, c.Repeat)
+	}
+	if rec := repeat.Recording(); rec.Freq != 38029 || !reflect.DeepEqual(rec.Pairs, c.Repeat) {
+		t.Errorf("repeat only: Recording = %+v", rec)
+	}
+}
+
+func TestString(t *testing.T) {
+	c, _ := Parse("0000 006d 0001 0001 0155 00aa 0015 0f25")
+	if s := c.String(); s != code {
+		t.Errorf("String = %q, want %q", s, code)
+	}
+
+	// 56kHz is a frequency word of 0x4A, 17.85us a period; 2s overflows a word
+	c = Code{Freq: 56000, Once: []irtrx.TimePair{{500 * us, 2 * time.Second}}}
+	if s, want := c.String(), "0000 004A 0001 0000 001C FFFF"; s != want {
+		t.Errorf("String = %q, want %q", s, want)
+	}
+
+	rec := FromRecording(irtrx.Recording{Pairs: []irtrx.TimePair{{9 * time.Millisecond, 4500 * us}}})
+	if rec.Freq != irtrx.Freq38Khz {
+		t.Errorf("FromRecording: Freq %d, want %d", rec.Freq, irtrx.Freq38Khz)
+	}
+}
+
+func TestStringFreq(t *testing.T) {
+	for _, freq := range []uint32{0, 1, 10_000_000} {
+		c := Code{Freq: freq, Once: []irtrx.TimePair{{500 * us, 500 * us}}}
+		if _, err := c.MarshalText(); !errors.Is(err, ErrFreq) {
+			t.Errorf("Freq %d: MarshalText: %v, want %v", freq, err, ErrFreq)
+		}
+		if s := c.String(); s != ErrFreq.Error() {
+			t.Errorf("Freq %d: String = %q", freq, s)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	c, _ := Parse(code)
+	b, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `"` + code + `"`; string(b) != want {
+		t.Errorf("Marshal = %s, want %s", b, want)
+	}
+	var got Code
+	if err := json.Unmarshal(b, &got); err != nil || !reflect.DeepEqual(got, c) {
+		t.Errorf("Unmarshal = %+v, %v; want %+v", got, err, c)
+	}
+}
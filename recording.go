@@ -0,0 +1,105 @@
+package irtrx
+
+import "time"
+
+// Recording is a raw capture of an IR signal: the mark/space pairs exactly as
+// they were received, plus the carrier frequency they should be replayed at.
+// Recordings let you learn and replay remotes for which there is no protocol
+// package.
+type Recording struct {
+	// Freq is the carrier frequency in Hz. Zero means use whatever carrier
+	// the TxDevice is currently configured for.
+	Freq  uint32     `json:"freq"`
+	Pairs []TimePair `json:"pairs"`
+}
+
+// MarshalFrame implements FrameMarshaller.
+func (r Recording) MarshalFrame() []TimePair {
+	return r.Pairs
+}
+
+// Carrier implements CarrierHinter.
+func (r Recording) Carrier() uint32 {
+	return r.Freq
+}
+
+// Recorder implements RxStateMachine by capturing raw TimePairs. A capture
+// ends when a space longer than Gap is seen or the buffer fills up, at which
+// point the handler is called with the captured Recording.
+//
+// Recorder expects mark-space pairs, so use it with StartInverted() on a
+// typical demodulating receiver.
+type Recorder struct {
+	// Gap is the space that marks the end of a capture.
+	Gap time.Duration
+	// Freq is stamped onto every Recording produced; the receiver has already
+	// stripped the carrier so there is no way to measure it.
+	Freq uint32
+
+	handler func(Recording)
+	buf     []TimePair
+	n       int
+}
+
+// NewRecorder returns a Recorder that can capture up to size pairs per
+// Recording. The buffer is allocated once, up front, so that nothing is
+// allocated from the interrupt handler. The Recording passed to handler
+// shares this buffer; copy Pairs if you need to keep it past the call. size
+// is at least 1, and a nil handler discards Recordings.
+func NewRecorder(size int, handler func(Recording)) *Recorder {
+	r := &Recorder{
+		Gap:  20 * time.Millisecond,
+		Freq: Freq38Khz,
+		buf:  make([]TimePair, max(size, 1)),
+	}
+	r.SetHandler(handler)
+	return r
+}
+
+// SetHandler lets you change the callback for when a Recording is complete.
+// A nil handler discards Recordings.
+func (r *Recorder) SetHandler(handler func(Recording)) {
+	if handler == nil {
+		handler = func(Recording) {}
+	}
+	r.handler = handler
+}
+
+// HandleTimePair implements the RxStateMachine interface.
+func (r *Recorder) HandleTimePair(pair TimePair) {
+	end := pair[1] > r.Gap
+	if end {
+		if r.n == 0 {
+			// idle line; nothing captured yet
+			return
+		}
+		// the trailing space is the inter-frame gap; no need to keep all of it
+		pair[1] = r.Gap
+	}
+
+	r.buf[r.n] = pair
+	r.n++
+
+	if !end && r.n < len(r.buf) {
+		return
+	}
+
+	r.handler(Recording{Freq: r.Freq, Pairs: r.buf[:r.n]})
+	r.n = 0
+}
+
+// Flush ends the capture in progress, if any, delivering it without
+// waiting for the end-of-capture gap; like a gap, it reports the last pair's
+// space as Gap. The last pair of a frame only reaches the Recorder when the
+// next edge arrives, so call the RxDevice's Flush instead, which passes that
+// pair on and then calls Flush with interrupts disabled.
+func (r *Recorder) Flush() {
+	if r.n == 0 {
+		return
+	}
+	r.buf[r.n-1][1] = r.Gap
+	r.handler(Recording{Freq: r.Freq, Pairs: r.buf[:r.n]})
+	r.n = 0
+}
+
+var _ Flusher = (*Recorder)(nil)
@@ -0,0 +1,62 @@
+package irtrx
+
+import (
+	"errors"
+	"sort"
+)
+
+var (
+	// ErrProtocol is returned by Encode for a protocol that isn't
+	// registered.
+	ErrProtocol = errors.New("irtrx: unknown protocol")
+	// ErrArgs is returned by a Protocol's Encode for the wrong number of
+	// arguments, or arguments out of range.
+	ErrArgs = errors.New("irtrx: bad arguments for protocol")
+)
+
+// Protocol describes how to build frames of a protocol from numbers, so that
+// tools can send codes of any protocol by name, e.g. "send nec 0x04 0x08".
+// Protocol packages register themselves with RegisterProtocol when imported.
+type Protocol struct {
+	// Name is the protocol's name, as returned by its frames' Protocol
+	// method.
+	Name string
+	// Usage describes the arguments Encode accepts, e.g. "addr cmd | code".
+	Usage string
+	// Encode returns the frame for args.
+	Encode func(args ...uint64) (FrameMarshaller, error)
+}
+
+var protocols = map[string]Protocol{}
+
+// RegisterProtocol adds p to the registry, replacing any protocol of the
+// same name. It is meant to be called from init functions and is not safe
+// for concurrent use.
+func RegisterProtocol(p Protocol) {
+	protocols[p.Name] = p
+}
+
+// LookupProtocol returns the registered protocol called name.
+func LookupProtocol(name string) (Protocol, bool) {
+	p, ok := protocols[name]
+	return p, ok
+}
+
+// Protocols returns every registered protocol, sorted by name.
+func Protocols() []Protocol {
+	out := make([]Protocol, 0, len(protocols))
+	for _, p := range protocols {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Encode returns the frame of the registered protocol for args.
+func Encode(protocol string, args ...uint64) (FrameMarshaller, error) {
+	p, ok := protocols[protocol]
+	if !ok {
+		return nil, ErrProtocol
+	}
+	return p.Encode(args...)
+}
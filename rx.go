@@ -1,8 +1,10 @@
 package irtrx
 
 import (
-	. "machine"
+	"sync/atomic"
 	"time"
+
+	. "github.com/sparques/irtrx/internal/hal"
 )
 
 type RxDevice struct {
@@ -11,12 +13,35 @@ type RxDevice struct {
 	lastPulse    time.Time
 	lastHigh     time.Duration
 	stateMachine RxStateMachine
+	// pending is set while lastHigh holds the first half of a pair not yet
+	// delivered, and flushed once Flush has delivered it early
+	pending, flushed bool
+
+	// while muted, or until muteUntil, edges are tracked but not decoded
+	muted     bool
+	muteUntil time.Time
+	// the last edge seen while not muted, i.e. not of our own making
+	lastForeign time.Time
+
+	// baseband swaps the sense of the pin; see SetBaseband
+	baseband bool
+	clock    Clock
+
+	// see SetAutoFlush
+	autoFlush    atomic.Int64
+	autoFlushing atomic.Bool
 }
 
 type RxStateMachine interface {
 	HandleTimePair(TimePair)
 }
 
+// Flusher is implemented by RxStateMachines, such as Recorder, that hold
+// on to what they have received until told the signal is over.
+type Flusher interface {
+	Flush()
+}
+
 type multiRxStateMachine []RxStateMachine
 
 func (mrsm multiRxStateMachine) HandleTimePair(pair TimePair) {
@@ -25,6 +50,15 @@ func (mrsm multiRxStateMachine) HandleTimePair(pair TimePair) {
 	}
 }
 
+// Flush implements Flusher, flushing each RxStateMachine that is one.
+func (mrsm multiRxStateMachine) Flush() {
+	for i := range mrsm {
+		if f, ok := mrsm[i].(Flusher); ok {
+			f.Flush()
+		}
+	}
+}
+
 // MultiRxStateMachine accepts a list of RxStateMachines and returns an object
 // that also implements RxStateMachine. When HandleTimePair is called against it,
 // it calls HandleTimePair against all the RxStateMachines used to define it.
@@ -45,40 +79,167 @@ func NewRxDevice(pin Pin, rsm RxStateMachine) *RxDevice {
 	return &RxDevice{
 		pin:          pin,
 		stateMachine: rsm,
+		clock:        RealClock,
 	}
 }
 
+// SetClock replaces the clock used to time edges and muting, which a
+// Transceiver or LinkManager built on the RxDevice also uses. Set it before
+// Start or StartInverted.
+func (rx *RxDevice) SetClock(c Clock) {
+	rx.clock = c
+}
+
 func (rx *RxDevice) interruptHandler(interruptPin Pin) {
-	ptime := time.Now()
-	if interruptPin.Get() {
-		// pin high
-		rx.stateMachine.HandleTimePair(TimePair{rx.lastHigh, time.Since(rx.lastPulse)})
-	} else {
-		rx.lastHigh = time.Since(rx.lastPulse)
-	}
-	rx.lastPulse = ptime
+	rx.edge(!interruptPin.Get())
 }
 
 func (rx *RxDevice) invertedInterruptHandler(interruptPin Pin) {
-	ptime := time.Now()
-	if interruptPin.Get() {
-		// pin high
-		rx.lastHigh = time.Since(rx.lastPulse)
-	} else {
-		rx.stateMachine.HandleTimePair(TimePair{rx.lastHigh, time.Since(rx.lastPulse)})
+	rx.edge(interruptPin.Get())
+}
+
+// edge handles an edge ending the first half of a pair if first is set, or
+// the second half otherwise.
+func (rx *RxDevice) edge(first bool) {
+	ptime := rx.clock.Now()
+	if rx.isMuted(ptime) {
+		rx.lastPulse = ptime
+		rx.pending, rx.flushed = false, false
+		return
+	}
+	rx.lastForeign = ptime
+	switch {
+	case first:
+		rx.lastHigh = ptime.Sub(rx.lastPulse)
+		rx.pending = true
+	case rx.flushed:
+		// Flush has delivered this pair already
+		rx.flushed = false
+	default:
+		rx.stateMachine.HandleTimePair(TimePair{rx.lastHigh, ptime.Sub(rx.lastPulse)})
+		rx.pending = false
 	}
 	rx.lastPulse = ptime
 }
 
+// Flush delivers the pair in progress, with its second half as long as it
+// has been so far, and then flushes the RxStateMachine if it is a Flusher.
+// A pair is only delivered when the edge ending it arrives, so the last
+// pair of a signal, e.g. a frame's final mark and the gap after it, would
+// otherwise wait for the next signal. Call Flush once the line has been
+// quiet for longer than any space within a frame. It runs with interrupts
+// disabled, so it is safe to call from any goroutine but not from an
+// interrupt handler.
+func (rx *RxDevice) Flush() {
+	state := DisableInterrupts()
+	defer RestoreInterrupts(state)
+	if now := rx.clock.Now(); rx.pending && !rx.isMuted(now) {
+		rx.stateMachine.HandleTimePair(TimePair{rx.lastHigh, now.Sub(rx.lastPulse)})
+		rx.pending = false
+		rx.flushed = true
+	}
+	if f, ok := rx.stateMachine.(Flusher); ok {
+		f.Flush()
+	}
+}
+
+// SetAutoFlush makes the RxDevice Flush itself once the line has been quiet
+// for quiet after an edge, so the last pair of a signal is delivered without
+// waiting for the next signal. quiet must be longer than any space within a
+// frame. Flushing is done by a goroutine started by the first call to
+// SetAutoFlush; a quiet of zero turns it off.
+func (rx *RxDevice) SetAutoFlush(quiet time.Duration) {
+	rx.autoFlush.Store(int64(quiet))
+	if quiet == 0 || rx.autoFlushing.Swap(true) {
+		return
+	}
+	go rx.watchQuiet()
+}
+
+func (rx *RxDevice) watchQuiet() {
+	// the edge last flushed after
+	var flushed time.Time
+	for {
+		quiet := time.Duration(rx.autoFlush.Load())
+		if quiet == 0 {
+			rx.clock.Sleep(time.Second)
+			continue
+		}
+		state := DisableInterrupts()
+		last := rx.lastPulse
+		RestoreInterrupts(state)
+		since := rx.clock.Now().Sub(last)
+		switch {
+		case since < quiet:
+			rx.clock.Sleep(quiet - since)
+		case !last.Equal(flushed):
+			rx.Flush()
+			flushed = last
+		default:
+			rx.clock.Sleep(quiet)
+		}
+	}
+}
+
+// SetStateMachine replaces the RxStateMachine, with interrupts disabled so
+// no pair is delivered half way through.
+func (rx *RxDevice) SetStateMachine(rsm RxStateMachine) {
+	state := DisableInterrupts()
+	rx.stateMachine = rsm
+	RestoreInterrupts(state)
+}
+
+func (rx *RxDevice) isMuted(now time.Time) bool {
+	return rx.muted || now.Before(rx.muteUntil)
+}
+
+// Mute causes received signals to be ignored until Unmute is called.
+func (rx *RxDevice) Mute() {
+	rx.muted = true
+}
+
+// Unmute resumes decoding received signals. Reception stays muted for an
+// additional settle duration, which may be zero.
+func (rx *RxDevice) Unmute(settle time.Duration) {
+	rx.muteUntil = rx.clock.Now().Add(settle)
+	// don't pair the first edge heard with our own transmission's last
+	rx.lastHigh = 0
+	rx.muted = false
+}
+
+// LastActivity returns the time of the most recent edge seen on the pin
+// while not muted, so a board's own transmissions don't count.
+func (rx *RxDevice) LastActivity() time.Time {
+	return rx.lastForeign
+}
+
+// SetBaseband tells the RxDevice the pin carries the raw mark/space envelope,
+// active high, rather than the active low output of a demodulating IR
+// receiver. This is what a TxDevice in baseband mode produces when wired
+// directly (or through an opto-isolator) to the pin. Call SetBaseband before
+// Start or StartInverted; those continue to select the pair ordering your
+// RxStateMachine expects.
+func (rx *RxDevice) SetBaseband(baseband bool) {
+	rx.baseband = baseband
+}
+
 // Start sets the interrupt handler and thus starts processing signals.
 // Use Start() if your RxStateMachine uses on-off pairs, e.g. Hexbug or PPM.
 func (rx *RxDevice) Start() {
+	if rx.baseband {
+		rx.pin.SetInterrupt(PinFalling|PinRising, rx.invertedInterruptHandler)
+		return
+	}
 	rx.pin.SetInterrupt(PinFalling|PinRising, rx.interruptHandler)
 }
 
 // StartInverted sets the interrupt handler and thus starts processing signals.
 // Use StartInverted if your RxStatemachine uses off-on pairs, e.g. NEC.
 func (rx *RxDevice) StartInverted() {
+	if rx.baseband {
+		rx.pin.SetInterrupt(PinFalling|PinRising, rx.interruptHandler)
+		return
+	}
 	rx.pin.SetInterrupt(PinFalling|PinRising, rx.invertedInterruptHandler)
 }
 
@@ -0,0 +1,35 @@
+//go:build !tinygo
+
+package irtrx_test
+
+import (
+	"testing"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/internal/hal"
+	"github.com/sparques/irtrx/irtest"
+)
+
+// discard is an RxStateMachine doing nothing, so only the interrupt handler
+// is measured.
+type discard struct{}
+
+func (discard) HandleTimePair(irtrx.TimePair) {}
+
+func BenchmarkRxDevice(b *testing.B) {
+	irtest.BenchmarkRxDevice(b, 4, discard{}, false)
+}
+
+func TestRxDeviceAllocs(t *testing.T) {
+	defer hal.Reset()
+	pin := hal.Pin(4)
+	rx := irtrx.NewRxDevice(pin, discard{})
+	rx.Start()
+	level := pin.Get()
+	if n := testing.AllocsPerRun(100, func() {
+		level = !level
+		pin.Set(level)
+	}); n > 0 {
+		t.Errorf("%v allocations per edge", n)
+	}
+}
@@ -0,0 +1,145 @@
+package samsung
+
+import "github.com/sparques/irtrx"
+
+// TVAddr is the address used by Samsung TV remotes.
+const TVAddr = 0x0707
+
+// Key is a button on a typical Samsung TV remote. Its value is the command
+// byte sent; on the wire it is followed by its complement.
+//
+// Key implements irtrx.FrameMarshaller, sending the key to TVAddr, so you
+// can do tx.SendFrame(samsung.KeyVolumeUp).
+type Key uint8
+
+const (
+	KeySource      Key = 0x01
+	KeyPower       Key = 0x02
+	Key1           Key = 0x04
+	Key2           Key = 0x05
+	Key3           Key = 0x06
+	KeyVolumeUp    Key = 0x07
+	Key4           Key = 0x08
+	Key5           Key = 0x09
+	Key6           Key = 0x0A
+	KeyVolumeDown  Key = 0x0B
+	Key7           Key = 0x0C
+	Key8           Key = 0x0D
+	Key9           Key = 0x0E
+	KeyMute        Key = 0x0F
+	KeyChannelDown Key = 0x10
+	Key0           Key = 0x11
+	KeyChannelUp   Key = 0x12
+	KeyPrevChannel Key = 0x13
+	KeyGreen       Key = 0x14
+	KeyYellow      Key = 0x15
+	KeyBlue        Key = 0x16
+	KeyMenu        Key = 0x1A
+	KeyInfo        Key = 0x1F
+	KeyExit        Key = 0x2D
+	KeyRewind      Key = 0x45
+	KeyStop        Key = 0x46
+	KeyPlay        Key = 0x47
+	KeyFastForward Key = 0x48
+	KeyRecord      Key = 0x49
+	KeyPause       Key = 0x4A
+	KeyTools       Key = 0x4B
+	KeyGuide       Key = 0x4F
+	KeyReturn      Key = 0x58
+	KeyUp          Key = 0x60
+	KeyDown        Key = 0x61
+	KeyRight       Key = 0x62
+	KeyLeft        Key = 0x65
+	KeyEnter       Key = 0x68
+	KeyChannelList Key = 0x6B
+	KeyRed         Key = 0x6C
+	KeySmartHub    Key = 0x79
+)
+
+var keyNames = map[Key]string{
+	KeySource:      "Source",
+	KeyPower:       "Power",
+	Key1:           "1",
+	Key2:           "2",
+	Key3:           "3",
+	KeyVolumeUp:    "VolumeUp",
+	Key4:           "4",
+	Key5:           "5",
+	Key6:           "6",
+	KeyVolumeDown:  "VolumeDown",
+	Key7:           "7",
+	Key8:           "8",
+	Key9:           "9",
+	KeyMute:        "Mute",
+	KeyChannelDown: "ChannelDown",
+	Key0:           "0",
+	KeyChannelUp:   "ChannelUp",
+	KeyPrevChannel: "PrevChannel",
+	KeyGreen:       "Green",
+	KeyYellow:      "Yellow",
+	KeyBlue:        "Blue",
+	KeyMenu:        "Menu",
+	KeyInfo:        "Info",
+	KeyExit:        "Exit",
+	KeyRewind:      "Rewind",
+	KeyStop:        "Stop",
+	KeyPlay:        "Play",
+	KeyFastForward: "FastForward",
+	KeyRecord:      "Record",
+	KeyPause:       "Pause",
+	KeyTools:       "Tools",
+	KeyGuide:       "Guide",
+	KeyReturn:      "Return",
+	KeyUp:          "Up",
+	KeyDown:        "Down",
+	KeyRight:       "Right",
+	KeyLeft:        "Left",
+	KeyEnter:       "Enter",
+	KeyChannelList: "ChannelList",
+	KeyRed:         "Red",
+	KeySmartHub:    "SmartHub",
+}
+
+// Digits maps 0 to 9 onto their number keys.
+var Digits = [10]Key{Key0, Key1, Key2, Key3, Key4, Key5, Key6, Key7, Key8, Key9}
+
+func (k Key) String() string {
+	if name, ok := keyNames[k]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// KeyByName returns the Key with the given name, as returned by String.
+func KeyByName(name string) (Key, bool) {
+	for k, n := range keyNames {
+		if n == name {
+			return k, true
+		}
+	}
+	return 0, false
+}
+
+// Cmd returns the 16 bit Cmd field of a Frame carrying k: the command byte
+// followed by its complement.
+func (k Key) Cmd() uint16 {
+	return uint16(^k)<<8 | uint16(k)
+}
+
+// Frame returns a Frame sending k to TVAddr.
+func (k Key) Frame() Frame {
+	return Frame{Addr: TVAddr, Cmd: k.Cmd()}
+}
+
+// MarshalFrame implements irtrx.FrameMarshaller.
+func (k Key) MarshalFrame() []irtrx.TimePair {
+	f := k.Frame()
+	return f.MarshalFrame()
+}
+
+// Key returns the Key f carries. ok is false if the command's complement
+// doesn't check out.
+func (f Frame) Key() (k Key, ok bool) {
+	k = Key(f.Cmd)
+	return k, f.Cmd == k.Cmd()
+}
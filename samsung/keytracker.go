@@ -0,0 +1,113 @@
+package samsung
+
+import (
+	"time"
+
+	"github.com/sparques/irtrx"
+)
+
+// EventType is the kind of KeyEvent.
+type EventType uint8
+
+const (
+	// Pressed is sent when a new frame arrives.
+	Pressed EventType = iota
+	// Held is sent for each repeat of the frame while the key stays down.
+	Held
+	// Released is sent when repeats stop arriving, or a different frame
+	// arrives.
+	Released
+)
+
+func (et EventType) String() string {
+	switch et {
+	case Pressed:
+		return "Pressed"
+	case Held:
+		return "Held"
+	case Released:
+		return "Released"
+	}
+	return "Unknown"
+}
+
+// KeyEvent describes a change in the state of a key.
+type KeyEvent struct {
+	Type  EventType
+	Frame Frame
+	// Duration is how long the key has been down, for Held and Released.
+	Duration time.Duration
+}
+
+// KeyTracker turns the stream of frames and repeats from a Samsung remote
+// into Pressed, Held and Released events, much like LIRC does. Samsung
+// remotes just keep resending the frame while a key is held, so a release is
+// detected when ReleaseTimeout passes without a repeat. Use its HandleFrame
+// method as the StateMachine's CmdHandler:
+//
+//	kt := samsung.NewKeyTracker(onKey)
+//	sm := samsung.NewStateMachine(kt.HandleFrame)
+type KeyTracker struct {
+	// ReleaseTimeout is how long after the last frame the key is considered
+	// released. It should be comfortably longer than RepeatPeriod. Timeouts
+	// are only detected when Poll is called.
+	ReleaseTimeout time.Duration
+
+	handler   func(KeyEvent)
+	current   Frame
+	down      bool
+	pressedAt time.Time
+	last      time.Time
+	clock     irtrx.Clock
+}
+
+// NewKeyTracker returns a KeyTracker calling handler for every event.
+func NewKeyTracker(handler func(KeyEvent)) *KeyTracker {
+	return &KeyTracker{
+		ReleaseTimeout: 2*RepeatPeriod + RepeatPeriod/2,
+		handler:        handler,
+		clock:          irtrx.RealClock,
+	}
+}
+
+// SetClock replaces the clock used to time key presses and releases.
+func (kt *KeyTracker) SetClock(c irtrx.Clock) {
+	kt.clock = c
+}
+
+// HandleFrame processes a decoded frame. Events are sent from here, so when
+// called from the StateMachine, handler runs in interrupt context.
+func (kt *KeyTracker) HandleFrame(f Frame) {
+	now := kt.clock.Now()
+	if kt.down && (f != kt.current || now.Sub(kt.last) > kt.ReleaseTimeout) {
+		kt.release(now)
+	}
+	kt.last = now
+	if kt.down {
+		kt.handler(KeyEvent{Type: Held, Frame: f, Duration: now.Sub(kt.pressedAt)})
+		return
+	}
+	kt.current = f
+	kt.down = true
+	kt.pressedAt = now
+	kt.handler(KeyEvent{Type: Pressed, Frame: f})
+}
+
+// Poll releases the current key if it has timed out. Call it periodically
+// from your main loop.
+func (kt *KeyTracker) Poll() {
+	now := kt.clock.Now()
+	if kt.down && now.Sub(kt.last) > kt.ReleaseTimeout {
+		kt.release(kt.last.Add(kt.ReleaseTimeout))
+	}
+}
+
+// Down returns the frame of the key currently held, if any.
+func (kt *KeyTracker) Down() (Frame, bool) {
+	return kt.current, kt.down
+}
+
+func (kt *KeyTracker) release(at time.Time) {
+	kt.down = false
+	kt.handler(KeyEvent{Type: Released, Frame: kt.current, Duration: at.Sub(kt.pressedAt)})
+}
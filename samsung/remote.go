@@ -0,0 +1,94 @@
+package samsung
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/sparques/irtrx"
+)
+
+// Remote emulates a Samsung remote control via an irtrx.Transmitter, taking
+// care of frame timing so TVs see clean key presses.
+type Remote struct {
+	tx irtrx.Transmitter
+
+	// Addr is the device address frames are sent to.
+	Addr uint16
+	// KeyGap is the pause between separate key presses, long enough that
+	// the TV doesn't mistake two presses of the same key for a hold.
+	KeyGap time.Duration
+
+	clock irtrx.Clock
+}
+
+// NewRemote returns a Remote sending to TVAddr via tx.
+func NewRemote(tx irtrx.Transmitter) *Remote {
+	return &Remote{
+		tx:     tx,
+		Addr:   TVAddr,
+		KeyGap: 150 * time.Millisecond,
+		clock:  irtrx.RealClock,
+	}
+}
+
+// SetClock replaces the clock used to time repeats and gaps.
+func (r *Remote) SetClock(c irtrx.Clock) {
+	r.clock = c
+}
+
+func (r *Remote) frame(k Key) *Frame {
+	return &Frame{Addr: r.Addr, Cmd: k.Cmd()}
+}
+
+// Press presses and releases k. It returns once the frame has been sent and
+// the frame period has passed, so presses can be sent back to back.
+func (r *Remote) Press(k Key) {
+	start := r.clock.Now()
+	r.tx.SendFrame(r.frame(k))
+	r.sleepUntil(start.Add(RepeatPeriod))
+}
+
+// Hold holds k down for d, repeating the frame every RepeatPeriod.
+func (r *Remote) Hold(k Key, d time.Duration) {
+	f := r.frame(k)
+	start := r.clock.Now()
+	next := start
+	for {
+		r.tx.SendFrame(f)
+		next = next.Add(RepeatPeriod)
+		r.sleepUntil(next)
+		if next.Sub(start) >= d {
+			return
+		}
+	}
+}
+
+// Sequence presses each key in turn, with KeyGap between them.
+func (r *Remote) Sequence(keys ...Key) {
+	for i, k := range keys {
+		if i != 0 {
+			r.clock.Sleep(r.KeyGap)
+		}
+		r.Press(k)
+	}
+}
+
+// EnterNumber types n on the number keys, e.g. to change channel. Negative
+// numbers are ignored.
+func (r *Remote) EnterNumber(n int) {
+	if n < 0 {
+		return
+	}
+	digits := strconv.Itoa(n)
+	keys := make([]Key, len(digits))
+	for i := range digits {
+		keys[i] = Digits[digits[i]-'0']
+	}
+	r.Sequence(keys...)
+}
+
+func (r *Remote) sleepUntil(t time.Time) {
+	if d := t.Sub(r.clock.Now()); d > 0 {
+		r.clock.Sleep(d)
+	}
+}
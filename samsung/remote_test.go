@@ -0,0 +1,69 @@
+package samsung_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sparques/irtrx/irtest"
+	"github.com/sparques/irtrx/samsung"
+)
+
+// run calls f in a goroutine, advancing clk by step whenever f sleeps, and
+// returns how far clk moved before f returned.
+func run(clk *irtest.Clock, step time.Duration, f func()) time.Duration {
+	start := clk.Now()
+	done := make(chan struct{})
+	go func() {
+		f()
+		close(done)
+	}()
+	for {
+		select {
+		case <-done:
+			return clk.Now().Sub(start)
+		default:
+		}
+		if clk.Sleepers() > 0 {
+			clk.Advance(step)
+		} else {
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+func TestRemoteHold(t *testing.T) {
+	tx := irtest.NewTransmitter()
+	clk := irtest.NewClock()
+	r := samsung.NewRemote(tx)
+	r.SetClock(clk)
+
+	elapsed := run(clk, time.Millisecond, func() { r.Hold(samsung.KeyPower, time.Second) })
+	// sent at 0, 108ms, ... 972ms, returning a period after the last
+	if len(tx.Frames) != 10 {
+		t.Errorf("sent %d frames, want 10", len(tx.Frames))
+	}
+	if want := 10 * samsung.RepeatPeriod; elapsed != want {
+		t.Errorf("took %v, want %v", elapsed, want)
+	}
+}
+
+func TestRemoteEnterNumber(t *testing.T) {
+	tx := irtest.NewTransmitter()
+	clk := irtest.NewClock()
+	r := samsung.NewRemote(tx)
+	r.SetClock(clk)
+
+	elapsed := run(clk, time.Millisecond, func() { r.EnterNumber(42) })
+	if len(tx.Frames) != 2 {
+		t.Fatalf("sent %d frames, want 2", len(tx.Frames))
+	}
+	for i, k := range []samsung.Key{samsung.Digits[4], samsung.Digits[2]} {
+		var f samsung.Frame
+		if err := f.UnmarshalTimePairs(tx.Frames[i]); err != nil || f.Cmd != k.Cmd() {
+			t.Errorf("frame %d: %v (%v), want %v", i, f, err, k)
+		}
+	}
+	if want := 2*samsung.RepeatPeriod + r.KeyGap; elapsed != want {
+		t.Errorf("took %v, want %v", elapsed, want)
+	}
+}
@@ -1,8 +1,12 @@
 // samsung implements an irtrx.RxStateMachine that can decode Samsung IR signals.
 // This requires StartInverted() and not Start()
+//
+// Encoding "samsung" by name with irtrx.Encode, an 8 bit addr is repeated,
+// as TVs use, and an 8 bit cmd is followed by its complement, as for a Key.
 package samsung
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"time"
@@ -12,14 +16,40 @@ import (
 
 type StateMachine struct {
 	CmdHandler func(Frame)
+	// Ext48Handler, if set, turns on Samsung48 support; see ExtFrame.
+	Ext48Handler func(ExtFrame)
+	// Validation selects which checks a frame must pass to be delivered.
+	// The zero value accepts every frame.
+	Validation Validation
+	// RawHandler, if set, is called with the raw bits of every 32 bit frame
+	// along with the error from Validation.Check (nil if it passed), so
+	// malformed frames can be observed instead of silently dropped. It is
+	// called before, and regardless of, the address filter and CmdHandler.
+	RawHandler func(raw uint32, err error)
+	// RawExtHandler is RawHandler for 48 bit frames, called with the error
+	// from Validation.CheckExt.
+	RawExtHandler func(raw uint64, err error)
 
-	buf      uint32
+	buf      uint64
 	bitcount int
+	// inFrame is set by a start of frame and cleared once a frame is
+	// delivered, so stray pairs between frames are ignored.
+	inFrame bool
+	// held is set while a 32 bit frame waits to see whether it is the start
+	// of a 48 bit one.
+	held bool
+
+	addrFilter    uint16
+	filterEnabled bool
 }
 
 var (
 	// ErrFrameAlloc is returned when an attempt to unmarshal to a nil Frame is done--the frame must be allocated ahead of time
 	ErrFrameAlloc = errors.New("tried to unmarshal to unallocated frame")
+	// ErrNoStart is returned when unmarshalling TimePairs that don't contain a start of frame
+	ErrNoStart = errors.New("no start of frame found")
+	// ErrShortFrame is returned when unmarshalling TimePairs that end before all the bits of a frame
+	ErrShortFrame = errors.New("frame too short")
 )
 
 type Frame struct {
@@ -27,21 +57,68 @@ type Frame struct {
 	Cmd  uint16
 }
 
+func init() {
+	irtrx.RegisterProtocol(irtrx.Protocol{
+		Name:  "samsung",
+		Usage: "addr cmd | code",
+		Encode: func(args ...uint64) (irtrx.FrameMarshaller, error) {
+			switch {
+			case len(args) == 1 && args[0] <= 0xFFFFFFFF:
+				var f Frame
+				f.UnmarshalFrame(uint32(args[0]))
+				return &f, nil
+			case len(args) == 2 && args[0] <= 0xFFFF && args[1] <= 0xFFFF:
+				f := Frame{Addr: uint16(args[0]), Cmd: uint16(args[1])}
+				if args[0] <= 0xFF {
+					f.Addr |= f.Addr << 8
+				}
+				if args[1] <= 0xFF {
+					f.Cmd = Key(args[1]).Cmd()
+				}
+				return &f, nil
+			}
+			return nil, irtrx.ErrArgs
+		},
+	})
+	irtrx.RegisterProtocol(irtrx.Protocol{
+		Name:  "samsung48",
+		Usage: "addr cmd | code",
+		Encode: func(args ...uint64) (irtrx.FrameMarshaller, error) {
+			switch {
+			case len(args) == 1 && args[0] <= 0xFFFFFFFFFFFF:
+				var f ExtFrame
+				f.UnmarshalFrame(args[0])
+				return &f, nil
+			case len(args) == 2 && args[0] <= 0xFFFF && args[1] <= 0xFFFFFFFF:
+				return &ExtFrame{Addr: uint16(args[0]), Cmd: uint32(args[1])}, nil
+			}
+			return nil, irtrx.ErrArgs
+		},
+	})
+}
+
 func NewStateMachine(cmdHandler func(Frame)) *StateMachine {
 	return &StateMachine{CmdHandler: cmdHandler}
 }
 
 func (sm *StateMachine) HandleTimePair(pair irtrx.TimePair) {
 	off, on := pair[0], pair[1]
+	if sm.held && (on > 3*time.Millisecond || off > 3*time.Millisecond) {
+		// the gap after the stop bit, or the next frame straight after
+		// it: the held frame was a 32 bit frame after all
+		sm.Flush()
+	}
+
 	switch {
-	case on > 200*time.Millisecond:
-		return
-	case off > 3*time.Millisecond:
+	case off > 3*time.Millisecond && on > 3*time.Millisecond:
 		//start of frame
-		if on > 3*time.Millisecond {
-			sm.buf = 0
-			sm.bitcount = 0
-		}
+		sm.buf, sm.bitcount, sm.inFrame = 0, 0, true
+		return
+	case !sm.inFrame:
+		return
+	case on > 3*time.Millisecond || off > 3*time.Millisecond:
+		// a gap or a glitch; the frame ended early
+		sm.inFrame = false
 		return
 	}
 
@@ -50,13 +127,61 @@ func (sm *StateMachine) HandleTimePair(pair irtrx.TimePair) {
 	}
 	sm.bitcount++
 
-	if sm.bitcount != 32 {
+	switch {
+	case sm.bitcount == 32 && sm.Ext48Handler == nil:
+		sm.inFrame = false
+		sm.deliver()
+	case sm.bitcount == 32:
+		sm.held = true
+	case sm.bitcount == 34:
+		// too many bits for the 33rd to have been a stop bit
+		sm.held = false
+	case sm.bitcount == 48:
+		sm.inFrame = false
+		sm.deliverExt()
+	}
+}
+
+// Flush implements irtrx.Flusher, delivering a 32 bit frame held back in
+// case it was the start of a 48 bit one; see ExtFrame.
+func (sm *StateMachine) Flush() {
+	if !sm.held {
 		return
 	}
+	sm.held, sm.inFrame = false, false
+	sm.deliver()
+}
 
+// deliver hands the 32 bit frame in buf to CmdHandler.
+func (sm *StateMachine) deliver() {
 	var f Frame
-	f.UnmarshalFrame(sm.buf)
-	sm.CmdHandler(f)
+	f.UnmarshalFrame(uint32(sm.buf))
+	err := sm.Validation.Check(f)
+	if sm.RawHandler != nil {
+		sm.RawHandler(uint32(sm.buf), err)
+	}
+	if err != nil {
+		return
+	}
+	if sm.filterEnabled && f.Addr != sm.addrFilter {
+		return
+	}
+	if sm.CmdHandler != nil {
+		sm.CmdHandler(f)
+	}
+}
+
+// SetAddressFilter makes the StateMachine ignore frames not addressed to
+// addr, so CmdHandler only sees frames for the device you care about.
+func (sm *StateMachine) SetAddressFilter(addr uint16) {
+	sm.addrFilter = addr
+	sm.filterEnabled = true
+}
+
+// ClearAddressFilter goes back to passing frames with any address to
+// CmdHandler.
+func (sm *StateMachine) ClearAddressFilter() {
+	sm.filterEnabled = false
 }
 
 var (
@@ -65,6 +190,10 @@ var (
 	OnePair   = irtrx.TimePair{567 * time.Microsecond, 1650 * time.Microsecond}
 )
 
+// RepeatPeriod is the time from the start of one frame to the start of the
+// next while a button is held.
+const RepeatPeriod = 108 * time.Millisecond
+
 func (f *Frame) MarshalFrame() []irtrx.TimePair {
 	out := make([]irtrx.TimePair, 34)
 
@@ -87,6 +216,17 @@ func (f *Frame) MarshalFrame() []irtrx.TimePair {
 	return out
 }
 
+// MarshalRepeat implements irtrx.RepeatMarshaller. Samsung remotes repeat
+// the whole frame while a button is held.
+func (f *Frame) MarshalRepeat() []irtrx.TimePair {
+	return f.MarshalFrame()
+}
+
+// RepeatPeriod implements irtrx.RepeatMarshaller.
+func (f *Frame) RepeatPeriod() time.Duration {
+	return RepeatPeriod
+}
+
 func (f *Frame) UnmarshalFrame(buf uint32) error {
 	if f == nil {
 		return ErrFrameAlloc
@@ -96,6 +236,68 @@ func (f *Frame) UnmarshalFrame(buf uint32) error {
 	return nil
 }
 
+// UnmarshalTimePairs implements irtrx.FrameUnmarshaller. Anything before the
+// start of frame is skipped.
+func (f *Frame) UnmarshalTimePairs(pairs []irtrx.TimePair) error {
+	if f == nil {
+		return ErrFrameAlloc
+	}
+	buf, err := bitsFromPairs(pairs, 32)
+	if err != nil {
+		return err
+	}
+	return f.UnmarshalFrame(uint32(buf))
+}
+
+// bitsFromPairs decodes n bits, LSB first, following the first start of
+// frame in pairs.
+func bitsFromPairs(pairs []irtrx.TimePair, n int) (uint64, error) {
+	start := -1
+	for i, p := range pairs {
+		if p[0] > 3*time.Millisecond && p[1] > 3*time.Millisecond {
+			start = i
+			break
+		}
+	}
+	if start == -1 {
+		return 0, ErrNoStart
+	}
+	pairs = pairs[start+1:]
+	if len(pairs) < n {
+		return 0, ErrShortFrame
+	}
+	var buf uint64
+	for bit := 0; bit < n; bit++ {
+		if pairs[bit][1] > time.Millisecond {
+			buf |= 1 << bit
+		}
+	}
+	return buf, nil
+}
+
 func (f Frame) String() string {
 	return fmt.Sprintf("{Addr: %04X, Cmd: %04X}", f.Addr, f.Cmd)
 }
+
+// Protocol implements irtrx.Frame.
+func (f Frame) Protocol() string { return "samsung" }
+
+// Bits implements irtrx.Frame, returning the 32 bits of the frame.
+func (f Frame) Bits() []byte {
+	b := binary.LittleEndian.AppendUint16(nil, f.Addr)
+	return binary.LittleEndian.AppendUint16(b, f.Cmd)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler. The frame is stored as
+// its 32 bits, little endian, i.e. in the order the bytes are sent.
+func (f Frame) MarshalBinary() ([]byte, error) {
+	return binary.LittleEndian.AppendUint32(nil, uint32(f.Cmd)<<16|uint32(f.Addr)), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (f *Frame) UnmarshalBinary(b []byte) error {
+	if len(b) != 4 {
+		return irtrx.ErrBinary
+	}
+	return f.UnmarshalFrame(binary.LittleEndian.Uint32(b))
+}
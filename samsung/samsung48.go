@@ -0,0 +1,121 @@
+package samsung
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/sparques/irtrx"
+)
+
+// ExtFrame is a Samsung48 frame, as used by some soundbars and newer TVs. It
+// has the same timings as a regular frame but 32 bits of command after the
+// 16 bit address, typically two command bytes each followed by its
+// complement.
+//
+// To receive ExtFrames, set the StateMachine's Ext48Handler. Regular 32 bit
+// frames still go to CmdHandler, but the decoder can't tell a 32 bit frame
+// from the start of a 48 bit one until the gap after the stop bit, which an
+// RxDevice only delivers when the next signal starts. Have the RxDevice flush
+// once no more bits can follow, so they aren't held back until then:
+//
+//	rx.SetAutoFlush(5 * time.Millisecond)
+type ExtFrame struct {
+	Addr uint16
+	Cmd  uint32
+}
+
+// deliverExt hands the 48 bit frame in buf to Ext48Handler.
+func (sm *StateMachine) deliverExt() {
+	var f ExtFrame
+	f.UnmarshalFrame(sm.buf)
+	err := sm.Validation.CheckExt(f)
+	if sm.RawExtHandler != nil {
+		sm.RawExtHandler(sm.buf, err)
+	}
+	if err != nil {
+		return
+	}
+	if sm.filterEnabled && f.Addr != sm.addrFilter {
+		return
+	}
+	sm.Ext48Handler(f)
+}
+
+// MarshalFrame implements irtrx.FrameMarshaller.
+func (f *ExtFrame) MarshalFrame() []irtrx.TimePair {
+	out := make([]irtrx.TimePair, 50)
+
+	// start of frame
+	out[0] = StartPair
+
+	buf := uint64(f.Cmd)<<16 | uint64(f.Addr)
+
+	for bit := 0; bit < 48; bit++ {
+		if (buf>>bit)&1 == 1 {
+			out[bit+1] = OnePair
+		} else {
+			out[bit+1] = ZeroPair
+		}
+	}
+
+	// Stop Bit is a Zero
+	out[49] = ZeroPair
+
+	return out
+}
+
+// UnmarshalFrame decodes the low 48 bits of buf, LSB first as received.
+func (f *ExtFrame) UnmarshalFrame(buf uint64) error {
+	if f == nil {
+		return ErrFrameAlloc
+	}
+	f.Addr = uint16(buf & 0xFFFF)
+	f.Cmd = uint32(buf >> 16)
+	return nil
+}
+
+// UnmarshalTimePairs implements irtrx.FrameUnmarshaller; see
+// Frame.UnmarshalTimePairs.
+func (f *ExtFrame) UnmarshalTimePairs(pairs []irtrx.TimePair) error {
+	if f == nil {
+		return ErrFrameAlloc
+	}
+	buf, err := bitsFromPairs(pairs, 48)
+	if err != nil {
+		return err
+	}
+	return f.UnmarshalFrame(buf)
+}
+
+func (f ExtFrame) String() string {
+	return fmt.Sprintf("{Addr: %04X, Cmd: %08X}", f.Addr, f.Cmd)
+}
+
+// Protocol implements irtrx.Frame.
+func (f ExtFrame) Protocol() string { return "samsung48" }
+
+// Bits implements irtrx.Frame, returning the 48 bits of the frame.
+func (f ExtFrame) Bits() []byte {
+	b, _ := f.MarshalBinary()
+	return b
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, storing the 48 bits of
+// the frame in six bytes, little endian.
+func (f ExtFrame) MarshalBinary() ([]byte, error) {
+	b := binary.LittleEndian.AppendUint16(nil, f.Addr)
+	return binary.LittleEndian.AppendUint32(b, f.Cmd), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (f *ExtFrame) UnmarshalBinary(b []byte) error {
+	if f == nil {
+		return ErrFrameAlloc
+	}
+	if len(b) != 6 {
+		return irtrx.ErrBinary
+	}
+	f.Addr = binary.LittleEndian.Uint16(b)
+	f.Cmd = binary.LittleEndian.Uint32(b[2:])
+	return nil
+}
@@ -0,0 +1,279 @@
+package samsung_test
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/irtest"
+	"github.com/sparques/irtrx/samsung"
+)
+
+// protocol round trips arbitrary 32 bit samsung.Frames.
+var protocol = irtest.Protocol[samsung.Frame]{
+	Name: "samsung",
+	Random: func(r *rand.Rand) samsung.Frame {
+		return samsung.Frame{Addr: uint16(r.Uint32()), Cmd: uint16(r.Uint32())}
+	},
+	Marshal: func(f samsung.Frame) irtrx.FrameMarshaller { return &f },
+	NewDecoder: func(h func(samsung.Frame)) irtrx.RxStateMachine {
+		return samsung.NewStateMachine(h)
+	},
+	Inverted: true,
+}
+
+// pairs returns a start of frame, a pair for each of bits, '0' or '1', and
+// the stop bit.
+func pairs(bits string) []irtrx.TimePair {
+	out := []irtrx.TimePair{samsung.StartPair}
+	for _, b := range bits {
+		if b == '1' {
+			out = append(out, samsung.OnePair)
+		} else {
+			out = append(out, samsung.ZeroPair)
+		}
+	}
+	return append(out, samsung.ZeroPair)
+}
+
+// power is KeyPower sent to TVAddr: 0x07, 0x07, 0x02, 0xFD, LSB first.
+const power = "11100000" + "11100000" + "01000000" + "10111111"
+
+func TestRoundTrip(t *testing.T) {
+	irtest.RoundTrip(t, protocol, 1000, 1)
+}
+
+func TestMarshalFrame(t *testing.T) {
+	if got, want := samsung.KeyPower.MarshalFrame(), pairs(power); !reflect.DeepEqual(got, want) {
+		t.Errorf("KeyPower: got %v, want %v", got, want)
+	}
+	for _, f := range []samsung.Frame{{}, {Addr: 0xFFFF, Cmd: 0xFFFF}} {
+		want := pairs("00000000000000000000000000000000")
+		if f.Addr != 0 {
+			want = pairs("11111111111111111111111111111111")
+		}
+		if got := f.MarshalFrame(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got %v, want %v", f, got, want)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	// what every decoder must do, on random frames
+	irtest.Conform(t, protocol)
+
+	for _, want := range []samsung.Frame{
+		samsung.KeyPower.Frame(),
+		{},
+		{Addr: 0xFFFF, Cmd: 0xFFFF},
+	} {
+		var got []samsung.Frame
+		w := irtest.NewWire(samsung.NewStateMachine(func(f samsung.Frame) { got = append(got, f) }), true)
+		w.SendFrame(&want)
+		if len(got) != 1 || got[0] != want {
+			t.Errorf("sent %v, decoded %v", want, got)
+		}
+	}
+
+	var got []samsung.Frame
+	w := irtest.NewWire(samsung.NewStateMachine(func(f samsung.Frame) { got = append(got, f) }), true)
+	w.SendPairs(pairs(power)...)
+	if want := (samsung.Frame{Addr: samsung.TVAddr, Cmd: 0xFD02}); len(got) != 1 || got[0] != want {
+		t.Fatalf("decoded %v, want %v", got, want)
+	}
+	if k, ok := got[0].Key(); !ok || k != samsung.KeyPower {
+		t.Errorf("Key() = %v, %v; want %v, true", k, ok, samsung.KeyPower)
+	}
+}
+
+func TestDecodeShort(t *testing.T) {
+	var got []samsung.Frame
+	w := irtest.NewWire(samsung.NewStateMachine(func(f samsung.Frame) { got = append(got, f) }), true)
+	// a frame cut short by a gap, then stray bits which mustn't complete it
+	short := pairs(power)[:20]
+	short[19][1] = irtest.DefaultIdle
+	w.SendPairs(short...)
+	w.SendPairs(pairs(power)[1:14]...)
+	if len(got) != 0 {
+		t.Errorf("decoded %v from a broken frame", got)
+	}
+}
+
+func TestValidation(t *testing.T) {
+	for _, tc := range []struct {
+		v     samsung.Validation
+		frame samsung.Frame
+		err   error
+	}{
+		{samsung.ValidateNone, samsung.Frame{Addr: 0x0102, Cmd: 0x0304}, nil},
+		{samsung.ValidateStrict, samsung.KeyPower.Frame(), nil},
+		{samsung.ValidateStrict, samsung.Frame{Addr: samsung.TVAddr, Cmd: 0xFC02}, samsung.ErrComplement},
+		{samsung.ValidateStrict, samsung.Frame{Addr: 0x0708, Cmd: 0xFD02}, samsung.ErrAddress},
+		{samsung.ValidateComplement, samsung.Frame{Addr: 0x0708, Cmd: 0xFD02}, nil},
+		{samsung.ValidateAddress, samsung.Frame{Addr: samsung.TVAddr, Cmd: 0xFC02}, nil},
+	} {
+		var got []samsung.Frame
+		var raw []uint32
+		var errs []error
+		sm := samsung.NewStateMachine(func(f samsung.Frame) { got = append(got, f) })
+		sm.Validation = tc.v
+		sm.RawHandler = func(r uint32, err error) {
+			raw = append(raw, r)
+			errs = append(errs, err)
+		}
+		irtest.NewWire(sm, true).SendFrame(&tc.frame)
+
+		wantRaw := uint32(tc.frame.Cmd)<<16 | uint32(tc.frame.Addr)
+		if len(raw) != 1 || raw[0] != wantRaw || errs[0] != tc.err {
+			t.Errorf("%v with %v: RawHandler got %x, %v; want %x, %v", tc.frame, tc.v, raw, errs, wantRaw, tc.err)
+		}
+		if delivered := len(got) == 1; delivered != (tc.err == nil) {
+			t.Errorf("%v with %v: delivered %v", tc.frame, tc.v, got)
+		}
+	}
+}
+
+func TestAddressFilter(t *testing.T) {
+	var got []samsung.Frame
+	sm := samsung.NewStateMachine(func(f samsung.Frame) { got = append(got, f) })
+	w := irtest.NewWire(sm, true)
+	other := samsung.Frame{Addr: 0x0E0E, Cmd: samsung.KeyPower.Cmd()}
+
+	sm.SetAddressFilter(samsung.TVAddr)
+	w.SendFrame(&other)
+	w.SendFrame(samsung.KeyMute)
+	sm.ClearAddressFilter()
+	w.SendFrame(&other)
+
+	want := []samsung.Frame{samsung.KeyMute.Frame(), other}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestUnmarshalTimePairs(t *testing.T) {
+	var f samsung.Frame
+	noise := []irtrx.TimePair{samsung.OnePair, samsung.ZeroPair}
+	if err := f.UnmarshalTimePairs(append(noise, pairs(power)...)); err != nil || f != samsung.KeyPower.Frame() {
+		t.Errorf("got %v, %v; want %v", f, err, samsung.KeyPower.Frame())
+	}
+	for _, tc := range []struct {
+		name  string
+		pairs []irtrx.TimePair
+		err   error
+	}{
+		{"Empty", nil, samsung.ErrNoStart},
+		{"NoStart", pairs(power)[1:], samsung.ErrNoStart},
+		{"Short", pairs(power)[:32], samsung.ErrShortFrame},
+	} {
+		if err := f.UnmarshalTimePairs(tc.pairs); err != tc.err {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.err)
+		}
+	}
+	var nilFrame *samsung.Frame
+	if err := nilFrame.UnmarshalTimePairs(pairs(power)); err != samsung.ErrFrameAlloc {
+		t.Errorf("nil Frame: got %v, want %v", err, samsung.ErrFrameAlloc)
+	}
+}
+
+func TestExtFrame(t *testing.T) {
+	ext := samsung.ExtFrame{Addr: 0x0707, Cmd: 0x00FD0203}
+	var got []samsung.Frame
+	var gotExt []samsung.ExtFrame
+	sm := samsung.NewStateMachine(func(f samsung.Frame) { got = append(got, f) })
+	sm.Ext48Handler = func(f samsung.ExtFrame) { gotExt = append(gotExt, f) }
+	w := irtest.NewWire(sm, true)
+
+	w.SendFrame(&ext)
+	w.SendFrame(samsung.KeyPower)
+	if len(gotExt) != 1 || gotExt[0] != ext {
+		t.Errorf("decoded %v, want [%v]", gotExt, ext)
+	}
+	// the 32 bit frame waits for the gap after it
+	if len(got) != 0 {
+		t.Errorf("32 bit frame delivered before Flush: %v", got)
+	}
+	sm.Flush()
+	if len(got) != 1 || got[0] != samsung.KeyPower.Frame() {
+		t.Errorf("after Flush: decoded %v, want [%v]", got, samsung.KeyPower.Frame())
+	}
+
+	var f samsung.ExtFrame
+	if err := f.UnmarshalTimePairs(ext.MarshalFrame()); err != nil || f != ext {
+		t.Errorf("UnmarshalTimePairs: got %v, %v; want %v", f, err, ext)
+	}
+	if err := f.UnmarshalTimePairs(pairs(power)); err != samsung.ErrShortFrame {
+		t.Errorf("UnmarshalTimePairs of a 32 bit frame: got %v, want %v", err, samsung.ErrShortFrame)
+	}
+}
+
+func TestBinary(t *testing.T) {
+	f := samsung.KeyPower.Frame()
+	b, _ := f.MarshalBinary()
+	if want := []byte{0x07, 0x07, 0x02, 0xFD}; !reflect.DeepEqual(b, want) {
+		t.Errorf("MarshalBinary() = %x, want %x", b, want)
+	}
+	if !reflect.DeepEqual(f.Bits(), b) {
+		t.Errorf("Bits() = %x, want %x", f.Bits(), b)
+	}
+	var got samsung.Frame
+	if err := got.UnmarshalBinary(b); err != nil || got != f {
+		t.Errorf("UnmarshalBinary(%x) = %v, %v", b, got, err)
+	}
+	if err := got.UnmarshalBinary(b[:3]); err != irtrx.ErrBinary {
+		t.Errorf("UnmarshalBinary(%x) = %v, want %v", b[:3], err, irtrx.ErrBinary)
+	}
+
+	ext := samsung.ExtFrame{Addr: 0x0707, Cmd: 0x00FD0203}
+	b, _ = ext.MarshalBinary()
+	if want := []byte{0x07, 0x07, 0x03, 0x02, 0xFD, 0x00}; !reflect.DeepEqual(b, want) {
+		t.Errorf("ExtFrame.MarshalBinary() = %x, want %x", b, want)
+	}
+	var gotExt samsung.ExtFrame
+	if err := gotExt.UnmarshalBinary(b); err != nil || gotExt != ext {
+		t.Errorf("ExtFrame.UnmarshalBinary(%x) = %v, %v", b, gotExt, err)
+	}
+	if err := gotExt.UnmarshalBinary(b[:4]); err != irtrx.ErrBinary {
+		t.Errorf("ExtFrame.UnmarshalBinary(%x) = %v, want %v", b[:4], err, irtrx.ErrBinary)
+	}
+}
+
+// TestCorpus replays the captures in testdata, each of which holds the
+// value it should decode to.
+func TestCorpus(t *testing.T) {
+	caps, err := irtest.LoadCorpus("testdata/captures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	irtest.ReplayCorpus(t, caps, "samsung", protocol.NewDecoder)
+	irtest.ReplayCorpus(t, caps, "samsung48", func(h func(samsung.ExtFrame)) irtrx.RxStateMachine {
+		sm := samsung.NewStateMachine(nil)
+		sm.Ext48Handler = h
+		return sm
+	})
+}
+
+func BenchmarkStateMachine(b *testing.B) {
+	b.Run("32", func(b *testing.B) {
+		irtest.BenchmarkDecoder(b, irtest.Bench{
+			Name:     "samsung",
+			New:      func() irtrx.RxStateMachine { return samsung.NewStateMachine(func(samsung.Frame) {}) },
+			Frame:    &samsung.Frame{Addr: samsung.TVAddr, Cmd: samsung.KeyPower.Cmd()},
+			Inverted: true,
+		})
+	})
+	// a 32 bit frame is held back until the 48 bit one is known to be done
+	b.Run("48", func(b *testing.B) {
+		irtest.BenchmarkDecoder(b, irtest.Bench{
+			Name: "samsung48",
+			New: func() irtrx.RxStateMachine {
+				sm := samsung.NewStateMachine(nil)
+				sm.Ext48Handler = func(samsung.ExtFrame) {}
+				return sm
+			},
+			Frame:    &samsung.ExtFrame{Addr: 0x0707, Cmd: 0x00FD0203},
+			Inverted: true,
+		})
+	})
+}
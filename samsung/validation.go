@@ -0,0 +1,54 @@
+package samsung
+
+import "errors"
+
+// Validation is a set of checks a Frame must pass before the StateMachine
+// delivers it.
+type Validation uint8
+
+const (
+	// ValidateNone accepts any 32 bits as a frame.
+	ValidateNone Validation = 0
+	// ValidateComplement requires the second command byte to be the
+	// complement of the first, as it is for Samsung TV remotes.
+	ValidateComplement Validation = 1 << iota
+	// ValidateAddress requires the two address bytes to be equal, as
+	// Samsung's repeated custom code is (e.g. TVAddr, 0x0707).
+	ValidateAddress
+
+	// ValidateStrict applies every check.
+	ValidateStrict = ValidateComplement | ValidateAddress
+)
+
+var (
+	// ErrComplement is returned by Validation.Check for a frame whose
+	// command isn't followed by its complement.
+	ErrComplement = errors.New("command complement mismatch")
+	// ErrAddress is returned by Validation.Check for a frame whose address
+	// bytes differ.
+	ErrAddress = errors.New("address bytes mismatch")
+)
+
+// Check returns nil if f passes the checks in v, or an error describing the
+// first it fails.
+func (v Validation) Check(f Frame) error {
+	if v&ValidateComplement != 0 && byte(f.Cmd) != ^byte(f.Cmd>>8) {
+		return ErrComplement
+	}
+	if v&ValidateAddress != 0 && byte(f.Addr) != byte(f.Addr>>8) {
+		return ErrAddress
+	}
+	return nil
+}
+
+// CheckExt is Check for a 48 bit frame, whose two command bytes must each
+// be followed by its complement to pass ValidateComplement.
+func (v Validation) CheckExt(f ExtFrame) error {
+	if v&ValidateComplement != 0 && (byte(f.Cmd) != ^byte(f.Cmd>>8) || byte(f.Cmd>>16) != ^byte(f.Cmd>>24)) {
+		return ErrComplement
+	}
+	if v&ValidateAddress != 0 && byte(f.Addr) != byte(f.Addr>>8) {
+		return ErrAddress
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package irtrx
+
+import (
+	"sync"
+	"time"
+)
+
+// Job is a frame queued on a Scheduler.
+type Job struct {
+	Frame FrameMarshaller
+	// Priority determines send order; higher goes first. Jobs of equal
+	// priority are sent in the order they were enqueued.
+	Priority int
+	// MinInterval is the minimum time between sends of jobs sharing the
+	// same Key. A job enqueued before the interval has passed waits in the
+	// queue (without blocking lower priority jobs).
+	MinInterval time.Duration
+	// Key groups jobs for MinInterval. Jobs with an empty Key are not rate
+	// limited.
+	Key string
+}
+
+// Scheduler serializes access to a single Transmitter for multiple producers.
+// Producers call Enqueue from any goroutine; Run sends the queued frames in
+// priority order. For example, a failsafe "stop" frame enqueued at a high
+// priority goes out ahead of any queued telemetry.
+type Scheduler struct {
+	tx Transmitter
+
+	mu       sync.Mutex
+	queue    []Job
+	lastSent map[string]time.Time
+	wake     chan struct{}
+	clock    Clock
+}
+
+// NewScheduler returns a Scheduler that sends via tx.
+func NewScheduler(tx Transmitter) *Scheduler {
+	return &Scheduler{
+		tx:       tx,
+		lastSent: make(map[string]time.Time),
+		wake:     make(chan struct{}, 1),
+		clock:    RealClock,
+	}
+}
+
+// SetClock replaces the clock used for MinInterval. Set it before Run.
+func (s *Scheduler) SetClock(c Clock) {
+	s.clock = c
+}
+
+// Enqueue adds job to the queue. It never blocks.
+func (s *Scheduler) Enqueue(job Job) {
+	s.mu.Lock()
+	s.queue = append(s.queue, job)
+	s.mu.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Send is shorthand for enqueueing fm at priority with no rate limiting.
+func (s *Scheduler) Send(fm FrameMarshaller, priority int) {
+	s.Enqueue(Job{Frame: fm, Priority: priority})
+}
+
+// Pending returns the number of jobs waiting to be sent.
+func (s *Scheduler) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.queue)
+}
+
+// Flush discards all queued jobs.
+func (s *Scheduler) Flush() {
+	s.mu.Lock()
+	s.queue = s.queue[:0]
+	s.mu.Unlock()
+}
+
+// next removes and returns the highest priority job that is ready to send.
+// If nothing is ready, it returns false along with how long until the
+// earliest rate-limited job becomes ready (zero if the queue is empty).
+func (s *Scheduler) next(now time.Time) (Job, bool, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	best := -1
+	var wait time.Duration
+	for i, job := range s.queue {
+		if job.Key != "" && job.MinInterval != 0 {
+			if d := s.lastSent[job.Key].Add(job.MinInterval).Sub(now); d > 0 {
+				if wait == 0 || d < wait {
+					wait = d
+				}
+				continue
+			}
+		}
+		if best == -1 || job.Priority > s.queue[best].Priority {
+			best = i
+		}
+	}
+	if best == -1 {
+		return Job{}, false, wait
+	}
+
+	job := s.queue[best]
+	s.queue = append(s.queue[:best], s.queue[best+1:]...)
+	if job.Key != "" {
+		s.lastSent[job.Key] = now
+	}
+	return job, true, 0
+}
+
+// Run sends queued jobs until done is closed. It is meant to be run in its
+// own goroutine.
+func (s *Scheduler) Run(done <-chan struct{}) {
+	for {
+		job, ok, wait := s.next(s.clock.Now())
+		if ok {
+			s.tx.SendFrame(job.Frame)
+			continue
+		}
+
+		var timeout <-chan time.Time
+		if wait != 0 {
+			timeout = After(s.clock, wait)
+		}
+		select {
+		case <-done:
+			return
+		case <-s.wake:
+		case <-timeout:
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package irtrx_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/irtest"
+)
+
+// countTx counts frames sent, safe to read while a Scheduler runs.
+type countTx struct {
+	mu sync.Mutex
+	n  int
+}
+
+func (c *countTx) SendPair(irtrx.TimePair)     {}
+func (c *countTx) SendPairs(...irtrx.TimePair) {}
+func (c *countTx) SendFrame(irtrx.FrameMarshaller) {
+	c.mu.Lock()
+	c.n++
+	c.mu.Unlock()
+}
+
+func (c *countTx) sent() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.n
+}
+
+// waitFor polls cond for up to a second of real time.
+func waitFor(cond func() bool) bool {
+	for i := 0; i < 1000; i++ {
+		if cond() {
+			return true
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return false
+}
+
+func TestSchedulerMinInterval(t *testing.T) {
+	tx := &countTx{}
+	clk := irtest.NewClock()
+	s := irtrx.NewScheduler(tx)
+	s.SetClock(clk)
+	done := make(chan struct{})
+	defer close(done)
+
+	job := irtrx.Job{Frame: irtrx.Recording{}, Key: "k", MinInterval: 100 * time.Millisecond}
+	s.Enqueue(job)
+	s.Enqueue(job)
+	go s.Run(done)
+
+	if !waitFor(func() bool { return tx.sent() == 1 && clk.Sleepers() > 0 }) {
+		t.Fatalf("sent %d frames, want 1 before MinInterval", tx.sent())
+	}
+	clk.Advance(99 * time.Millisecond)
+	if tx.sent() != 1 {
+		t.Fatalf("second frame sent after 99ms")
+	}
+	clk.Advance(time.Millisecond)
+	if !waitFor(func() bool { return tx.sent() == 2 }) {
+		t.Errorf("second frame not sent after MinInterval")
+	}
+}
@@ -0,0 +1,93 @@
+package irtrx
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrSelfTestNoSignal is returned by SelfTest when nothing was received.
+	ErrSelfTestNoSignal = errors.New("self test: nothing received")
+	// ErrSelfTestShort is returned by SelfTest when fewer pairs were received
+	// than were sent.
+	ErrSelfTestShort = errors.New("self test: frame truncated")
+	// ErrSelfTestNotDecoded is returned by SelfTest when the frame was
+	// received but the verify func reported it was not decoded.
+	ErrSelfTestNotDecoded = errors.New("self test: frame not decoded")
+)
+
+// SelfTestResult holds the timing statistics from a SelfTest.
+type SelfTestResult struct {
+	// Sent and Received are the number of pairs sent and received.
+	Sent, Received int
+	// MeanError and MaxError are the mean and maximum absolute difference
+	// between the sent and received marks and spaces.
+	MeanError, MaxError time.Duration
+}
+
+// SelfTest transmits fm with tx and checks that it arrives intact at rx,
+// which must be physically adjacent (or wired, see SetBaseband) and already
+// started with StartInverted. It is intended for end-of-line testing of
+// assembled boards.
+//
+// rx's own RxStateMachine, if it has one, keeps running during the test.
+// If verify is not nil, it is called once the frame has been received and
+// should report whether that state machine decoded it, e.g. by checking a
+// flag set by its handler.
+func SelfTest(tx Transmitter, rx *RxDevice, fm FrameMarshaller, verify func() bool) (SelfTestResult, error) {
+	sent := fm.MarshalFrame()
+	res := SelfTestResult{Sent: len(sent)}
+
+	var got []TimePair
+	rec := NewRecorder(len(sent)+1, func(r Recording) {
+		got = append(got[:0], r.Pairs...)
+	})
+
+	orig := rx.stateMachine
+	var sm RxStateMachine = rec
+	if orig != nil {
+		sm = MultiRxStateMachine(rec, orig)
+	}
+	rx.SetStateMachine(sm)
+	tx.SendPairs(sent...)
+	rx.clock.Sleep(rec.Gap)
+	// the final pair is only delivered at the next edge
+	rx.Flush()
+	rx.SetStateMachine(orig)
+
+	res.Received = len(got)
+	if res.Received == 0 {
+		return res, ErrSelfTestNoSignal
+	}
+
+	var total time.Duration
+	var edges int
+	for i := 0; i < len(got) && i < len(sent); i++ {
+		for j := range sent[i] {
+			if i == len(sent)-1 && j == 1 {
+				// the trailing space can't be measured
+				continue
+			}
+			e := got[i][j] - sent[i][j]
+			if e < 0 {
+				e = -e
+			}
+			total += e
+			edges++
+			if e > res.MaxError {
+				res.MaxError = e
+			}
+		}
+	}
+	if edges > 0 {
+		res.MeanError = total / time.Duration(edges)
+	}
+
+	if res.Received < res.Sent {
+		return res, ErrSelfTestShort
+	}
+	if verify != nil && !verify() {
+		return res, ErrSelfTestNotDecoded
+	}
+	return res, nil
+}
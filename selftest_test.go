@@ -0,0 +1,33 @@
+//go:build !tinygo
+
+package irtrx_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/internal/hal"
+	"github.com/sparques/irtrx/irtest"
+)
+
+func TestSelfTestNoStateMachine(t *testing.T) {
+	defer hal.Reset()
+	const txPin, rxPin hal.Pin = 2, 5
+	irtest.Connect(txPin, rxPin, 0)
+	rx := irtrx.NewRxDevice(rxPin, nil)
+	rx.StartInverted()
+	defer rx.Stop()
+	tx := irtrx.NewTxDevice(txPin)
+	tx.Configure(irtrx.TxConfig{BusyWait: time.Second})
+
+	frame := irtrx.Recording{Pairs: []irtrx.TimePair{
+		{2 * time.Millisecond, time.Millisecond},
+		{time.Millisecond, time.Millisecond},
+		{time.Millisecond, 5 * time.Millisecond},
+	}}
+	res, err := irtrx.SelfTest(tx, rx, frame, nil)
+	if err != nil || res.Received != res.Sent {
+		t.Errorf("SelfTest: %+v, %v", res, err)
+	}
+}
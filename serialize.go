@@ -0,0 +1,108 @@
+package irtrx
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrBinary is returned when unmarshalling malformed or truncated binary
+// data, by this package and the protocol packages.
+var ErrBinary = errors.New("irtrx: malformed binary data")
+
+// MarshalJSON encodes p as [mark, space] in microseconds, which is how
+// timings are written everywhere else (LIRC, IRremote dumps, datasheets).
+func (p TimePair) MarshalJSON() ([]byte, error) {
+	return json.Marshal([2]int64{p[0].Microseconds(), p[1].Microseconds()})
+}
+
+// UnmarshalJSON decodes the form written by MarshalJSON.
+func (p *TimePair) UnmarshalJSON(b []byte) error {
+	var us [2]int64
+	if err := json.Unmarshal(b, &us); err != nil {
+		return err
+	}
+	p[0] = time.Duration(us[0]) * time.Microsecond
+	p[1] = time.Duration(us[1]) * time.Microsecond
+	return nil
+}
+
+// AppendPairs appends the compact binary form of pairs to b: the number of
+// pairs followed by each mark and space in microseconds, all as uvarints.
+// Most timings fit in two bytes, so a typical frame takes about four bytes
+// per pair.
+func AppendPairs(b []byte, pairs []TimePair) []byte {
+	b = binary.AppendUvarint(b, uint64(len(pairs)))
+	for _, p := range pairs {
+		b = binary.AppendUvarint(b, uint64(p[0].Microseconds()))
+		b = binary.AppendUvarint(b, uint64(p[1].Microseconds()))
+	}
+	return b
+}
+
+// ReadPairs decodes pairs written by AppendPairs from the start of b and
+// returns them with the number of bytes read.
+func ReadPairs(b []byte) ([]TimePair, int, error) {
+	n, off := binary.Uvarint(b)
+	if off <= 0 || n > uint64(len(b)) {
+		return nil, 0, ErrBinary
+	}
+	pairs := make([]TimePair, n)
+	for i := range pairs {
+		for j := range pairs[i] {
+			us, m := binary.Uvarint(b[off:])
+			if m <= 0 {
+				return nil, 0, ErrBinary
+			}
+			pairs[i][j] = time.Duration(us) * time.Microsecond
+			off += m
+		}
+	}
+	return pairs, off, nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler: the carrier frequency as
+// a uvarint followed by the pairs as written by AppendPairs.
+func (r Recording) MarshalBinary() ([]byte, error) {
+	b := binary.AppendUvarint(nil, uint64(r.Freq))
+	return AppendPairs(b, r.Pairs), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. b must hold
+// exactly one Recording.
+func (r *Recording) UnmarshalBinary(b []byte) error {
+	freq, off := binary.Uvarint(b)
+	if off <= 0 {
+		return ErrBinary
+	}
+	pairs, n, err := ReadPairs(b[off:])
+	if err != nil {
+		return err
+	}
+	if off+n != len(b) {
+		return ErrBinary
+	}
+	r.Freq = uint32(freq)
+	r.Pairs = pairs
+	return nil
+}
+
+// GoString implements fmt.GoStringer, so %#v prints r as a Go composite
+// literal ready to paste into source, two pairs to a line.
+func (r Recording) GoString() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "irtrx.Recording{Freq: %d, Pairs: []irtrx.TimePair{", r.Freq)
+	for i, p := range r.Pairs {
+		if i%2 == 0 {
+			sb.WriteString("\n\t")
+		} else {
+			sb.WriteByte(' ')
+		}
+		fmt.Fprintf(&sb, "{%d * time.Microsecond, %d * time.Microsecond},", p[0].Microseconds(), p[1].Microseconds())
+	}
+	sb.WriteString("\n}}")
+	return sb.String()
+}
@@ -0,0 +1,263 @@
+// smartir imports the climate device files of the SmartIR Home Assistant
+// integration (https://github.com/smartHomeHub/SmartIR), which hold a code
+// for every combination of mode, fan speed and temperature of hundreds of air
+// conditioners. This makes AC control possible for models without a
+// protocol package, e.g.
+//
+//	dev, err := smartir.Parse(file)
+//	ac := smartir.NewController(dev, tx)
+//	ac.SetMode("cool")
+//	ac.SetTemperature(24)
+//
+// Files with Broadlink (Base64) and raw (ESPHome style signed microseconds)
+// codes are supported.
+package smartir
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/broadlink"
+)
+
+var (
+	// ErrEncoding is returned for files whose codes aren't Broadlink or raw.
+	ErrEncoding = errors.New("smartir: unsupported commands encoding")
+	// ErrNoCommand is returned when the file has no code for a state.
+	ErrNoCommand = errors.New("smartir: no command for state")
+	// ErrMode is returned for modes the device doesn't support.
+	ErrMode = errors.New("smartir: unsupported mode")
+	// ErrTemp is returned for temperatures out of the device's range.
+	ErrTemp = errors.New("smartir: temperature out of range")
+)
+
+// Off is the operation mode that turns the device off.
+const Off = "off"
+
+// Device is a SmartIR climate device file.
+type Device struct {
+	Manufacturer     string   `json:"manufacturer"`
+	SupportedModels  []string `json:"supportedModels"`
+	Controller       string   `json:"supportedController"`
+	CommandsEncoding string   `json:"commandsEncoding"`
+	MinTemperature   float64  `json:"minTemperature"`
+	MaxTemperature   float64  `json:"maxTemperature"`
+	Precision        float64  `json:"precision"`
+	OperationModes   []string `json:"operationModes"`
+	FanModes         []string `json:"fanModes"`
+	SwingModes       []string `json:"swingModes"`
+
+	// Commands is nested by mode, fan mode, swing mode (if the device has
+	// any) and temperature; see Command.
+	Commands map[string]json.RawMessage `json:"commands"`
+}
+
+// Parse reads a device file.
+func Parse(r io.Reader) (*Device, error) {
+	var d Device
+	if err := json.NewDecoder(r).Decode(&d); err != nil {
+		return nil, err
+	}
+	switch d.CommandsEncoding {
+	case "Base64", "Raw":
+	default:
+		return nil, ErrEncoding
+	}
+	return &d, nil
+}
+
+// Command returns the frame for a state. swing is ignored for devices
+// without swing modes; temp is ignored for mode Off.
+func (d *Device) Command(mode, fan, swing string, temp float64) (irtrx.FrameMarshaller, error) {
+	if mode == Off {
+		return d.decode(d.Commands[Off])
+	}
+	path := []string{fan}
+	if len(d.SwingModes) > 0 {
+		path = append(path, swing)
+	}
+	path = append(path, strconv.FormatFloat(temp, 'f', -1, 64))
+
+	raw, ok := d.Commands[mode]
+	if !ok {
+		return nil, ErrNoCommand
+	}
+	for _, key := range path {
+		var level map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &level); err != nil {
+			return nil, ErrNoCommand
+		}
+		if raw, ok = level[key]; !ok {
+			return nil, ErrNoCommand
+		}
+	}
+	return d.decode(raw)
+}
+
+// decode turns a single code into a frame.
+func (d *Device) decode(raw json.RawMessage) (irtrx.FrameMarshaller, error) {
+	if raw == nil {
+		return nil, ErrNoCommand
+	}
+	switch d.CommandsEncoding {
+	case "Base64":
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, err
+		}
+		p, err := broadlink.DecodeBase64(s)
+		if err != nil {
+			return nil, err
+		}
+		return &p, nil
+	case "Raw":
+		var us []int64
+		if err := json.Unmarshal(raw, &us); err != nil {
+			return nil, err
+		}
+		return rawRecording(us), nil
+	}
+	return nil, ErrEncoding
+}
+
+// rawRecording converts ESPHome style timings, positive for marks and
+// negative for spaces, in microseconds.
+func rawRecording(us []int64) irtrx.Recording {
+	r := irtrx.Recording{Freq: irtrx.Freq38Khz}
+	var p irtrx.TimePair
+	haveMark := false
+	for _, v := range us {
+		switch {
+		case v > 0 && haveMark:
+			// two marks in a row; treat as one
+			p[0] += time.Duration(v) * time.Microsecond
+		case v > 0:
+			p[0] = time.Duration(v) * time.Microsecond
+			haveMark = true
+		case haveMark:
+			p[1] = time.Duration(-v) * time.Microsecond
+			r.Pairs = append(r.Pairs, p)
+			haveMark = false
+		}
+	}
+	if haveMark {
+		p[1] = 100 * time.Millisecond
+		r.Pairs = append(r.Pairs, p)
+	}
+	return r
+}
+
+// Controller keeps track of a device's state and sends the code for the
+// whole state on every change, like the original remote.
+type Controller struct {
+	dev *Device
+	tx  irtrx.Transmitter
+
+	mode  string
+	fan   string
+	swing string
+	temp  float64
+}
+
+// NewController returns a Controller sending dev's codes with tx. It starts
+// off, in the device's first mode, fan and swing modes and at its minimum
+// temperature; nothing is sent until a setter is called.
+func NewController(dev *Device, tx irtrx.Transmitter) *Controller {
+	c := &Controller{dev: dev, tx: tx, mode: Off, temp: dev.MinTemperature}
+	if len(dev.FanModes) > 0 {
+		c.fan = dev.FanModes[0]
+	}
+	if len(dev.SwingModes) > 0 {
+		c.swing = dev.SwingModes[0]
+	}
+	return c
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// send sends the code for the given state and adopts it if that worked.
+func (c *Controller) send(mode, fan, swing string, temp float64) error {
+	fm, err := c.dev.Command(mode, fan, swing, temp)
+	if err != nil {
+		return err
+	}
+	c.tx.SendFrame(fm)
+	c.mode, c.fan, c.swing, c.temp = mode, fan, swing, temp
+	return nil
+}
+
+// SetMode sets the operation mode, e.g. "cool", or Off.
+func (c *Controller) SetMode(mode string) error {
+	if mode != Off && !contains(c.dev.OperationModes, mode) {
+		return ErrMode
+	}
+	return c.send(mode, c.fan, c.swing, c.temp)
+}
+
+// SetTemperature sets the target temperature, rounded to the device's
+// precision. While off, it is remembered for the next SetMode.
+func (c *Controller) SetTemperature(temp float64) error {
+	if temp < c.dev.MinTemperature || temp > c.dev.MaxTemperature {
+		return ErrTemp
+	}
+	if p := c.dev.Precision; p > 0 {
+		temp = float64(int64(temp/p+0.5)) * p
+	}
+	if c.mode == Off {
+		c.temp = temp
+		return nil
+	}
+	return c.send(c.mode, c.fan, c.swing, temp)
+}
+
+// SetFanMode sets the fan mode, e.g. "auto".
+func (c *Controller) SetFanMode(fan string) error {
+	if !contains(c.dev.FanModes, fan) {
+		return ErrMode
+	}
+	if c.mode == Off {
+		c.fan = fan
+		return nil
+	}
+	return c.send(c.mode, fan, c.swing, c.temp)
+}
+
+// SetSwingMode sets the swing mode, for devices that have them.
+func (c *Controller) SetSwingMode(swing string) error {
+	if !contains(c.dev.SwingModes, swing) {
+		return ErrMode
+	}
+	if c.mode == Off {
+		c.swing = swing
+		return nil
+	}
+	return c.send(c.mode, c.fan, swing, c.temp)
+}
+
+// TurnOff sends the off code.
+func (c *Controller) TurnOff() error {
+	return c.SetMode(Off)
+}
+
+// Mode returns the current operation mode.
+func (c *Controller) Mode() string { return c.mode }
+
+// Temperature returns the target temperature.
+func (c *Controller) Temperature() float64 { return c.temp }
+
+// FanMode returns the current fan mode.
+func (c *Controller) FanMode() string { return c.fan }
+
+// SwingMode returns the current swing mode.
+func (c *Controller) SwingMode() string { return c.swing }
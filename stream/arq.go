@@ -0,0 +1,241 @@
+package stream
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sparques/irtrx"
+)
+
+// MaxMessage is the largest message a Link sends; each frame carries a one
+// byte header.
+const MaxMessage = MaxPayload - 1
+
+const (
+	// set in the header of an ack; the rest is the sequence number
+	ackFlag = 0x80
+	seqMask = 0x7F
+)
+
+// ErrNoAck is returned by Send when a message goes unacknowledged after
+// every retry.
+var ErrNoAck = errors.New("stream: no ack")
+
+// Muter is implemented by irtrx.RxDevice.
+type Muter interface {
+	Mute()
+	Unmute(settle time.Duration)
+}
+
+// Link adds reliable delivery to frames, for short control messages that
+// must get through occlusions and noise. It is a stop-and-wait ARQ: each
+// message carries a sequence number and is retransmitted, with exponential
+// backoff, until the other end acknowledges it. Duplicates caused by lost
+// acks are acknowledged again but delivered only once.
+//
+// A Link implements irtrx.RxStateMachine for the receiving side:
+//
+//	link := stream.NewLink(tx)
+//	link.MessageHandler = handle
+//	rx := irtrx.NewRxDevice(rxPin, link)
+//	rx.StartInverted()
+//	link.Muter = rx
+//	link.Start()
+//	err := link.Send([]byte("go"))
+//
+// Both ends must use a Link. Given an irtrx.LinkManager to transmit with,
+// acks are sent with its Reply.
+type Link struct {
+	// MessageHandler is called with every new message, from the Link's
+	// goroutine rather than the interrupt handler. The slice is only valid
+	// for the duration of the call.
+	MessageHandler func(msg []byte)
+	// Retries is how many times Send retransmits before giving up.
+	Retries int
+	// AckTimeout is how long Send waits for an ack after the first
+	// transmission; it doubles with each retry, plus up to AckTimeout of
+	// random jitter so two ends sending at once don't keep colliding.
+	AckTimeout time.Duration
+	// Muter, if set, is muted while transmitting, so a board that sees its
+	// own emitter doesn't take its frames for the other end's. It is
+	// ignored when transmitting with an irtrx.LinkManager, which mutes its
+	// own receiver.
+	Muter Muter
+	// Settle is how long reception stays muted after transmitting.
+	Settle time.Duration
+
+	sm    StateMachine
+	tx    irtrx.Transmitter
+	clock irtrx.Clock
+
+	txMu   sync.Mutex
+	sendMu sync.Mutex
+	seq    uint8
+	// the sequence number Send is waiting on, or -1, and whether its ack
+	// arrived
+	awaiting atomic.Int32
+	acked    atomic.Bool
+
+	// single slot mailbox from the interrupt handler to the goroutine
+	inbox    [MaxPayload]byte
+	inboxLen int
+	inboxSet atomic.Bool
+	// sequence number of the last message delivered, or -1
+	lastSeq int
+}
+
+// NewLink returns a Link transmitting with tx. Call Start to have it
+// receive messages as well as send them.
+func NewLink(tx irtrx.Transmitter) *Link {
+	l := &Link{
+		Retries:    5,
+		AckTimeout: 150 * time.Millisecond,
+		Settle:     2 * time.Millisecond,
+		tx:         tx,
+		clock:      irtrx.RealClock,
+		lastSeq:    -1,
+	}
+	// start somewhere arbitrary, so a rebooted sender's first message is
+	// unlikely to be mistaken for a duplicate
+	l.seq = uint8(l.clock.Now().UnixNano()) & seqMask
+	l.awaiting.Store(-1)
+	l.sm.PayloadHandler = l.received
+	return l
+}
+
+// SetClock replaces the clock used for ack timeouts and polling. Set it
+// before Start.
+func (l *Link) SetClock(c irtrx.Clock) {
+	l.clock = c
+}
+
+// Start starts the goroutine that acknowledges received messages and passes
+// them to MessageHandler.
+func (l *Link) Start() {
+	go l.run()
+}
+
+// HandleTimePair implements irtrx.RxStateMachine.
+func (l *Link) HandleTimePair(pair irtrx.TimePair) {
+	l.sm.HandleTimePair(pair)
+}
+
+// received is called from the interrupt handler for every good frame.
+func (l *Link) received(payload []byte) {
+	if len(payload) == 0 {
+		return
+	}
+	hdr := payload[0]
+	if hdr&ackFlag != 0 {
+		if int32(hdr&seqMask) == l.awaiting.Load() {
+			l.acked.Store(true)
+		}
+		return
+	}
+	// drop data while the goroutine is still busy with the last message;
+	// the sender will retransmit it
+	if l.inboxSet.Load() {
+		return
+	}
+	l.inboxLen = copy(l.inbox[:], payload)
+	l.inboxSet.Store(true)
+}
+
+func (l *Link) run() {
+	for {
+		if !l.inboxSet.Load() {
+			l.clock.Sleep(PollInterval)
+			continue
+		}
+		seq := l.inbox[0] & seqMask
+		l.transmit(Frame{ackFlag | seq}, true)
+		if int(seq) != l.lastSeq {
+			l.lastSeq = int(seq)
+			if l.MessageHandler != nil {
+				l.MessageHandler(l.inbox[1:l.inboxLen])
+			}
+		}
+		l.inboxSet.Store(false)
+	}
+}
+
+// replier is implemented by irtrx.LinkManager, which gives replies
+// priority over new traffic.
+type replier interface {
+	Reply(fm irtrx.FrameMarshaller)
+}
+
+// transmit sends f, muting the receiver meanwhile. Given a replier, which
+// mutes its own receiver, acks are sent as replies and Muter is left alone.
+func (l *Link) transmit(f Frame, ack bool) {
+	l.txMu.Lock()
+	defer l.txMu.Unlock()
+	if r, ok := l.tx.(replier); ok {
+		if ack {
+			r.Reply(f)
+		} else {
+			l.tx.SendFrame(f)
+		}
+		return
+	}
+	if l.Muter != nil {
+		l.Muter.Mute()
+		defer l.Muter.Unmute(l.Settle)
+	}
+	l.tx.SendFrame(f)
+}
+
+// Send transmits msg and blocks until it is acknowledged, retransmitting as
+// needed. It returns ErrNoAck if every retry goes unacknowledged, in which
+// case msg may or may not have been delivered. Concurrent calls are sent
+// one at a time.
+func (l *Link) Send(msg []byte) error {
+	if len(msg) > MaxMessage {
+		return ErrTooLong
+	}
+	l.sendMu.Lock()
+	defer l.sendMu.Unlock()
+
+	seq := l.seq
+	l.seq = (seq + 1) & seqMask
+	var buf [MaxPayload]byte
+	buf[0] = seq
+	f := Frame(buf[:copy(buf[1:], msg)+1])
+
+	l.acked.Store(false)
+	l.awaiting.Store(int32(seq))
+	defer l.awaiting.Store(-1)
+	timeout := l.AckTimeout
+	for try := 0; try <= l.Retries; try++ {
+		l.transmit(f, false)
+		deadline := l.clock.Now().Add(timeout + time.Duration(rand.Int63n(int64(l.AckTimeout)+1)))
+		for l.clock.Now().Before(deadline) {
+			if l.acked.Load() {
+				return nil
+			}
+			l.clock.Sleep(PollInterval)
+		}
+		timeout *= 2
+	}
+	if l.acked.Load() {
+		return nil
+	}
+	return ErrNoAck
+}
+
+// SendAsync sends msg in the background, calling done, if not nil, with the
+// result of Send.
+func (l *Link) SendAsync(msg []byte, done func(error)) {
+	msg = append([]byte(nil), msg...)
+	go func() {
+		err := l.Send(msg)
+		if done != nil {
+			done(err)
+		}
+	}()
+}
+
+var _ irtrx.RxStateMachine = (*Link)(nil)
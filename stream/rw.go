@@ -0,0 +1,134 @@
+package stream
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/sparques/irtrx"
+)
+
+// Writer is an io.Writer sending everything written to it over IR.
+type Writer struct {
+	tx irtrx.Transmitter
+}
+
+// NewWriter returns a Writer sending frames with tx.
+func NewWriter(tx irtrx.Transmitter) *Writer {
+	return &Writer{tx: tx}
+}
+
+// Write sends p as one or more frames, blocking until they have been
+// transmitted. Delivery isn't confirmed, so the only error is none.
+func (w *Writer) Write(p []byte) (int, error) {
+	for off := 0; off < len(p); off += MaxPayload {
+		w.tx.SendFrame(Frame(p[off:min(off+MaxPayload, len(p))]))
+	}
+	return len(p), nil
+}
+
+// PollInterval is how often a blocked Read checks for new data.
+const PollInterval = time.Millisecond
+
+// Reader implements irtrx.RxStateMachine, decoding frames into a buffer that
+// is drained through its Read method. Frames arriving while the buffer
+// doesn't have room for them are dropped whole.
+type Reader struct {
+	sm  StateMachine
+	buf []byte
+	// head is only advanced by the interrupt handler and tail only by Read,
+	// so neither needs a lock; both run freely, and as len(buf) is a power of
+	// two they stay consistent when they wrap
+	head, tail atomic.Uint32
+
+	timeout time.Duration
+	clock   irtrx.Clock
+	// ErrorHandler, if set, is called with the reason for every dropped
+	// frame. It is called from the interrupt handler.
+	ErrorHandler func(error)
+}
+
+// NewReader returns a Reader buffering up to size bytes, rounded up to a
+// power of two. size should be at least MaxPayload, or full frames will
+// never fit.
+func NewReader(size int) *Reader {
+	n := 1
+	for n < size {
+		n <<= 1
+	}
+	r := &Reader{buf: make([]byte, n), clock: irtrx.RealClock}
+	r.sm.PayloadHandler = r.push
+	r.sm.ErrorHandler = r.fail
+	return r
+}
+
+// HandleTimePair implements irtrx.RxStateMachine.
+func (r *Reader) HandleTimePair(pair irtrx.TimePair) {
+	r.sm.HandleTimePair(pair)
+}
+
+func (r *Reader) fail(err error) {
+	if r.ErrorHandler != nil {
+		r.ErrorHandler(err)
+	}
+}
+
+// push appends a frame's payload to buf.
+func (r *Reader) push(payload []byte) {
+	head, tail := r.head.Load(), r.tail.Load()
+	if len(payload) > len(r.buf)-int(head-tail) {
+		r.fail(ErrOverflow)
+		return
+	}
+	for _, b := range payload {
+		r.buf[head&uint32(len(r.buf)-1)] = b
+		head++
+	}
+	r.head.Store(head)
+}
+
+// Buffered returns the number of bytes waiting to be read.
+func (r *Reader) Buffered() int {
+	return int(r.head.Load() - r.tail.Load())
+}
+
+// SetClock replaces the clock used for read timeouts and polling.
+func (r *Reader) SetClock(c irtrx.Clock) {
+	r.clock = c
+}
+
+// SetReadTimeout makes Read give up with ErrTimeout when nothing arrives
+// within d. Zero, the default, waits forever.
+func (r *Reader) SetReadTimeout(d time.Duration) {
+	r.timeout = d
+}
+
+// Read implements io.Reader, blocking until at least one byte is available.
+func (r *Reader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	deadline := r.clock.Now().Add(r.timeout)
+	for {
+		if n := r.read(p); n > 0 {
+			return n, nil
+		}
+		if r.timeout > 0 && !r.clock.Now().Before(deadline) {
+			return 0, ErrTimeout
+		}
+		r.clock.Sleep(PollInterval)
+	}
+}
+
+// read copies out as much buffered data as fits in p.
+func (r *Reader) read(p []byte) int {
+	head, tail := r.head.Load(), r.tail.Load()
+	n := min(int(head-tail), len(p))
+	for i := 0; i < n; i++ {
+		p[i] = r.buf[tail&uint32(len(r.buf)-1)]
+		tail++
+	}
+	r.tail.Store(tail)
+	return n
+}
+
+var _ irtrx.RxStateMachine = (*Reader)(nil)
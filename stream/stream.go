@@ -0,0 +1,237 @@
+// stream carries arbitrary bytes over IR, so two boards can exchange sensor
+// readings, configuration and the like without inventing a frame format for
+// each. Writes are split into frames of at most MaxPayload bytes, each sent
+// as
+//
+//	preamble, length (1 byte), payload, CRC-16 (2 bytes, little endian)
+//
+// with the CRC (CCITT: polynomial 0x1021, initial value 0xFFFF) taken over
+// the length and payload. Bytes are sent LSB first as pulse distance bits,
+// like NEC. Frames that don't check out are dropped, and nothing is
+// retransmitted, so a Writer and Reader are lossy; a Link adds
+// acknowledgements and retransmission for messages that must get through.
+//
+// Wiring up both ends:
+//
+//	w := stream.NewWriter(irtrx.NewTxDevice(txPin))
+//	fmt.Fprintf(w, "temp=%d\n", temp)
+//
+//	r := stream.NewReader(256)
+//	rx := irtrx.NewRxDevice(rxPin, r)
+//	rx.StartInverted()
+//	line, err := bufio.NewReader(r).ReadString('\n')
+//
+// The decoder requires StartInverted() and not Start(). A board that can see
+// its own emitter should Mute its RxDevice while writing.
+package stream
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sparques/irtrx"
+)
+
+// MaxPayload is the largest payload a single frame carries.
+const MaxPayload = 64
+
+var (
+	Preamble = irtrx.TimePair{3000 * time.Microsecond, 6000 * time.Microsecond}
+	ZeroPair = irtrx.TimePair{500 * time.Microsecond, 500 * time.Microsecond}
+	OnePair  = irtrx.TimePair{500 * time.Microsecond, 1500 * time.Microsecond}
+	// StopPair ends the last bit's space and leaves Gap before the next
+	// frame.
+	StopPair = irtrx.TimePair{500 * time.Microsecond, Gap}
+)
+
+// Gap is the space left after every frame.
+const Gap = 10 * time.Millisecond
+
+const (
+	// marks and spaces longer than these start a frame
+	preambleMark  = 2000 * time.Microsecond
+	preambleSpace = 4000 * time.Microsecond
+	// spaces longer than oneSpace are a one; marks longer than maxBitMark
+	// and spaces longer than maxBitSpace end the frame early
+	oneSpace    = 1000 * time.Microsecond
+	maxBitMark  = 1000 * time.Microsecond
+	maxBitSpace = 2500 * time.Microsecond
+)
+
+var (
+	// ErrTooLong is returned when a frame's length exceeds MaxPayload.
+	ErrTooLong = errors.New("stream: frame too long")
+	// ErrChecksum is returned when a frame's CRC doesn't match.
+	ErrChecksum = errors.New("stream: bad checksum")
+	// ErrTruncated is returned when a frame ends before all its bytes
+	// arrive.
+	ErrTruncated = errors.New("stream: frame truncated")
+	// ErrOverflow is reported by a Reader that drops a frame because its
+	// buffer is full.
+	ErrOverflow = errors.New("stream: reader buffer full")
+	// ErrTimeout is returned by Read when the read timeout passes without
+	// any data arriving.
+	ErrTimeout = errors.New("stream: read timeout")
+)
+
+// crc16 updates crc with b, CCITT polynomial 0x1021.
+func crc16(crc uint16, b []byte) uint16 {
+	for _, c := range b {
+		crc ^= uint16(c) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// Frame is a single frame's payload, at most MaxPayload bytes.
+type Frame []byte
+
+// Bits implements irtrx.Frame, returning the length, payload and CRC. A
+// payload longer than MaxPayload is truncated.
+func (f Frame) Bits() []byte {
+	if len(f) > MaxPayload {
+		f = f[:MaxPayload]
+	}
+	b := make([]byte, 0, len(f)+3)
+	b = append(b, byte(len(f)))
+	b = append(b, f...)
+	return binary.LittleEndian.AppendUint16(b, crc16(0xFFFF, b))
+}
+
+// Protocol implements irtrx.Frame.
+func (f Frame) Protocol() string { return "stream" }
+
+func (f Frame) String() string {
+	return fmt.Sprintf("% X", []byte(f))
+}
+
+// MarshalFrame implements irtrx.FrameMarshaller. A payload longer than
+// MaxPayload is truncated; Writer splits long writes into frames.
+func (f Frame) MarshalFrame() []irtrx.TimePair {
+	bits := f.Bits()
+	out := make([]irtrx.TimePair, 0, len(bits)*8+2)
+	out = append(out, Preamble)
+	for _, b := range bits {
+		for bit := 0; bit < 8; bit++ {
+			if b>>bit&1 == 1 {
+				out = append(out, OnePair)
+			} else {
+				out = append(out, ZeroPair)
+			}
+		}
+	}
+	return append(out, StopPair)
+}
+
+// UnmarshalTimePairs implements irtrx.FrameUnmarshaller. Anything before
+// the preamble is skipped.
+func (f *Frame) UnmarshalTimePairs(pairs []irtrx.TimePair) error {
+	var got bool
+	err := ErrTruncated
+	sm := NewStateMachine(func(payload []byte) {
+		*f = append((*f)[:0], payload...)
+		got = true
+	})
+	sm.ErrorHandler = func(e error) {
+		err = e
+	}
+	for _, p := range pairs {
+		sm.HandleTimePair(p)
+		if got {
+			return nil
+		}
+	}
+	return err
+}
+
+// StateMachine implements irtrx.RxStateMachine, decoding frames. Reader
+// wraps one to provide an io.Reader; use a StateMachine directly to handle
+// each frame's payload as it arrives.
+type StateMachine struct {
+	// PayloadHandler is called with the payload of every good frame. The
+	// slice is only valid for the duration of the call.
+	PayloadHandler func(payload []byte)
+	// ErrorHandler, if set, is called with the reason for every dropped
+	// frame.
+	ErrorHandler func(error)
+
+	buf [MaxPayload + 3]byte
+	// complete bytes in buf, and the bits of the next
+	n       int
+	cur     byte
+	bit     int
+	inFrame bool
+}
+
+// NewStateMachine returns a StateMachine calling payloadHandler for every
+// good frame.
+func NewStateMachine(payloadHandler func(payload []byte)) *StateMachine {
+	return &StateMachine{PayloadHandler: payloadHandler}
+}
+
+func (sm *StateMachine) HandleTimePair(pair irtrx.TimePair) {
+	mark, space := pair[0], pair[1]
+	if mark > preambleMark && space > preambleSpace {
+		if sm.inFrame {
+			sm.fail(ErrTruncated)
+		}
+		sm.n, sm.cur, sm.bit = 0, 0, 0
+		sm.inFrame = true
+		return
+	}
+	if !sm.inFrame {
+		return
+	}
+	if mark > maxBitMark || space > maxBitSpace {
+		sm.fail(ErrTruncated)
+		return
+	}
+
+	if space > oneSpace {
+		sm.cur |= 1 << sm.bit
+	}
+	sm.bit++
+	if sm.bit < 8 {
+		return
+	}
+	sm.buf[sm.n] = sm.cur
+	sm.n++
+	sm.cur, sm.bit = 0, 0
+
+	length := int(sm.buf[0])
+	switch {
+	case length > MaxPayload:
+		sm.fail(ErrTooLong)
+	case sm.n == length+3:
+		sm.inFrame = false
+		if crc16(0xFFFF, sm.buf[:length+1]) != binary.LittleEndian.Uint16(sm.buf[length+1:]) {
+			sm.fail(ErrChecksum)
+			return
+		}
+		if sm.PayloadHandler != nil {
+			sm.PayloadHandler(sm.buf[1 : length+1])
+		}
+	}
+}
+
+// fail drops the frame in progress.
+func (sm *StateMachine) fail(err error) {
+	sm.inFrame = false
+	if sm.ErrorHandler != nil {
+		sm.ErrorHandler(err)
+	}
+}
+
+var (
+	_ irtrx.Frame             = Frame(nil)
+	_ irtrx.FrameUnmarshaller = (*Frame)(nil)
+	_ irtrx.RxStateMachine    = (*StateMachine)(nil)
+)
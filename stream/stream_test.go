@@ -0,0 +1,18 @@
+package stream_test
+
+import (
+	"testing"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/irtest"
+	"github.com/sparques/irtrx/stream"
+)
+
+func BenchmarkStateMachine(b *testing.B) {
+	irtest.BenchmarkDecoder(b, irtest.Bench{
+		Name:     "stream",
+		New:      func() irtrx.RxStateMachine { return stream.NewStateMachine(func([]byte) {}) },
+		Frame:    stream.Frame("temp=21.5 humidity=40"),
+		Inverted: true,
+	})
+}
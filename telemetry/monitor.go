@@ -0,0 +1,117 @@
+package telemetry
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/sparques/irtrx"
+)
+
+// Report is the latest value of every field a Monitor has received.
+type Report struct {
+	Battery     uint16
+	LinkQuality uint16
+	Errors      uint16
+	User        [NumUser]uint16
+	// Received has bit i set if field i has been received.
+	Received uint16
+	// LastSeen is when the last field was received.
+	LastSeen time.Time
+}
+
+// Monitor implements irtrx.RxStateMachine, keeping the latest value of each
+// field a robot's Reporter sends. Its receiver must be started with
+// StartInverted; it ignores the base station's own control frames.
+//
+//	m := telemetry.NewMonitor()
+//	rx := irtrx.NewRxDevice(rxPin, m)
+//	rx.StartInverted()
+//
+//	if mv, _, ok := m.Value(telemetry.Battery); ok && mv < 3300 {
+//	    warnLowBattery()
+//	}
+type Monitor struct {
+	// FieldHandler, if set, is called with every field received. It is
+	// called from the interrupt handler.
+	FieldHandler func(Field)
+
+	sm     *StateMachine
+	values [NumFields]atomic.Uint32
+	// when each field was received, in Unix nanoseconds
+	seen  [NumFields]atomic.Int64
+	clock irtrx.Clock
+}
+
+// NewMonitor returns a Monitor that has received nothing.
+func NewMonitor() *Monitor {
+	m := &Monitor{clock: irtrx.RealClock}
+	m.sm = NewStateMachine(m.received)
+	return m
+}
+
+// SetClock replaces the clock used to time fields.
+func (m *Monitor) SetClock(c irtrx.Clock) {
+	m.clock = c
+}
+
+// SetErrorHandler sets a handler called with every frame failing its check,
+// from the interrupt handler.
+func (m *Monitor) SetErrorHandler(handler func(error)) {
+	m.sm.ErrorHandler = handler
+}
+
+// HandleTimePair implements irtrx.RxStateMachine.
+func (m *Monitor) HandleTimePair(pair irtrx.TimePair) {
+	m.sm.HandleTimePair(pair)
+}
+
+func (m *Monitor) received(f Field) {
+	m.values[f.ID].Store(uint32(f.Value))
+	m.seen[f.ID].Store(m.clock.Now().UnixNano())
+	if m.FieldHandler != nil {
+		m.FieldHandler(f)
+	}
+}
+
+// Value returns the latest value of field id and when it was received.
+func (m *Monitor) Value(id FieldID) (value uint16, at time.Time, ok bool) {
+	if id >= NumFields {
+		return 0, time.Time{}, false
+	}
+	ns := m.seen[id].Load()
+	if ns == 0 {
+		return 0, time.Time{}, false
+	}
+	return uint16(m.values[id].Load()), time.Unix(0, ns), true
+}
+
+// Report returns the latest value of every field.
+func (m *Monitor) Report() Report {
+	var r Report
+	var last int64
+	for id := range m.values {
+		ns := m.seen[id].Load()
+		if ns == 0 {
+			continue
+		}
+		r.Received |= 1 << id
+		last = max(last, ns)
+		v := uint16(m.values[id].Load())
+		switch {
+		case FieldID(id) == Battery:
+			r.Battery = v
+		case FieldID(id) == LinkQuality:
+			r.LinkQuality = v
+		case FieldID(id) == Errors:
+			r.Errors = v
+		case FieldID(id) >= User:
+			r.User[FieldID(id)-User] = v
+		}
+	}
+	if last != 0 {
+		r.LastSeen = time.Unix(0, last)
+	}
+	return r
+}
+
+var _ irtrx.RxStateMachine = (*Monitor)(nil)
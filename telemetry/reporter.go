@@ -0,0 +1,201 @@
+package telemetry
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/sparques/irtrx"
+)
+
+// PollInterval is how often the Reporter's goroutine checks for a gap.
+const PollInterval = time.Millisecond
+
+// Muter is implemented by irtrx.RxDevice.
+type Muter interface {
+	Mute()
+	Unmute(settle time.Duration)
+}
+
+// set marks a value as set in Reporter.values.
+const set = 1 << 16
+
+// Reporter sends telemetry from a robot, one field per gap between the
+// control frames it receives, cycling through the fields that are set. Have
+// the control decoder's handler call ControlReceived, most easily with
+// Control:
+//
+//	rep := telemetry.NewReporter(irtrx.NewTxDevice(txPin))
+//	hb := hexbug.NewStateMachine(telemetry.Control(rep, onCmd))
+//	rx := irtrx.NewRxDevice(rxPin, hb)
+//	rx.Start()
+//	rep.Muter = rx
+//	rep.Start()
+//
+//	rep.Set(telemetry.Battery, readBatteryMV())
+type Reporter struct {
+	// Turnaround is how long after the end of a control frame a field is
+	// sent, giving the base station's receiver time to recover from its
+	// own transmission.
+	Turnaround time.Duration
+	// Slot is how long after the end of one control frame the next may
+	// start. A field is only sent if it will be done by then. The default
+	// suits hexbug remotes, which send every 50ms.
+	Slot time.Duration
+	// Idle is how often fields are sent while no control frames arrive,
+	// so the base station still hears from a parked robot. Zero sends only
+	// in gaps.
+	Idle time.Duration
+	// Muter, if set, is muted while sending, so the robot doesn't hear
+	// itself.
+	Muter Muter
+	// Settle is how long reception stays muted after sending.
+	Settle time.Duration
+
+	tx irtrx.Transmitter
+	// values with the set bit, so the interrupt handler can set them too
+	values [NumFields]atomic.Uint32
+	// end of the last control frame, in Unix nanoseconds
+	control atomic.Int64
+	running atomic.Bool
+	clock   irtrx.Clock
+	// only used by the goroutine
+	next     int
+	handled  int64
+	lastSent time.Time
+}
+
+// NewReporter returns a Reporter sending with tx.
+func NewReporter(tx irtrx.Transmitter) *Reporter {
+	return &Reporter{
+		Turnaround: 2 * time.Millisecond,
+		Slot:       35 * time.Millisecond,
+		Idle:       250 * time.Millisecond,
+		Settle:     time.Millisecond,
+		tx:         tx,
+		clock:      irtrx.RealClock,
+	}
+}
+
+// SetClock replaces the clock used to find gaps.
+func (r *Reporter) SetClock(c irtrx.Clock) {
+	r.clock = c
+}
+
+// Set sets field id, which is then sent in turn with the others set. It may
+// be called from interrupt context.
+func (r *Reporter) Set(id FieldID, value uint16) {
+	if id < NumFields {
+		r.values[id].Store(set | uint32(value))
+	}
+}
+
+// Clear stops field id being sent.
+func (r *Reporter) Clear(id FieldID) {
+	if id < NumFields {
+		r.values[id].Store(0)
+	}
+}
+
+// ControlReceived tells the Reporter a control frame has just ended. Call it
+// from the control decoder's handler; it is safe in interrupt context.
+func (r *Reporter) ControlReceived() {
+	r.control.Store(r.clock.Now().UnixNano())
+}
+
+// Control returns a handler for a control decoder that calls
+// r.ControlReceived and then handler.
+func Control[T any](r *Reporter, handler func(T)) func(T) {
+	return func(v T) {
+		r.ControlReceived()
+		if handler != nil {
+			handler(v)
+		}
+	}
+}
+
+// Start starts sending from a goroutine.
+func (r *Reporter) Start() {
+	if r.running.Swap(true) {
+		return
+	}
+	go func() {
+		for r.running.Load() {
+			r.poll()
+			r.clock.Sleep(PollInterval)
+		}
+	}()
+}
+
+// Stop stops the goroutine started by Start.
+func (r *Reporter) Stop() {
+	r.running.Store(false)
+}
+
+// poll sends the next field if there is a gap for it.
+func (r *Reporter) poll() {
+	control := r.control.Load()
+	now := r.clock.Now()
+	if control != r.handled {
+		r.handled = control
+		end := time.Unix(0, control)
+		if wait := end.Add(r.Turnaround).Sub(now); wait > 0 {
+			r.clock.Sleep(wait)
+		}
+		// a newer frame means this gap has been missed
+		if r.control.Load() != control {
+			return
+		}
+		f, ok := r.pick()
+		if !ok || r.clock.Now().Add(duration(&f)).After(end.Add(r.Slot)) {
+			return
+		}
+		r.send(&f)
+		return
+	}
+	if r.Idle == 0 || now.Sub(time.Unix(0, control)) < r.Idle || now.Sub(r.lastSent) < r.Idle {
+		return
+	}
+	if f, ok := r.pick(); ok {
+		r.send(&f)
+	}
+}
+
+// pick returns the next field set after the one last sent.
+func (r *Reporter) pick() (Field, bool) {
+	for i := 0; i < NumFields; i++ {
+		id := (r.next + i) % NumFields
+		if v := r.values[id].Load(); v&set != 0 {
+			r.next = id + 1
+			return Field{ID: FieldID(id), Value: uint16(v)}, true
+		}
+	}
+	return Field{}, false
+}
+
+func (r *Reporter) send(f *Field) {
+	if r.Muter != nil {
+		r.Muter.Mute()
+		defer r.Muter.Unmute(r.Settle)
+	}
+	r.tx.SendFrame(f)
+	r.lastSent = r.clock.Now()
+}
+
+// duration returns how long f takes to send.
+func duration(f *Field) time.Duration {
+	var d time.Duration
+	for _, p := range f.MarshalFrame() {
+		d += p[0] + p[1]
+	}
+	return d
+}
+
+// Quality returns the percentage of good frames among good and bad, for the
+// LinkQuality field; 0 if there were none. Feed it the counts since the
+// last report, e.g. from a decoder's Errors.
+func Quality(good, bad uint32) uint16 {
+	if good+bad == 0 {
+		return 0
+	}
+	return uint16(uint64(good) * 100 / uint64(good+bad))
+}
@@ -0,0 +1,211 @@
+// telemetry is a return channel from a robot to its base station: battery
+// voltage, link quality, error counters and a few fields of the
+// application's own. A Reporter on the robot sends them in the gaps between
+// the control frames it receives, so driving isn't delayed; a Monitor on the
+// base station decodes them.
+//
+// Each frame carries a single field, so it fits in the gap after a hexbug
+// control frame: a 2.8ms mark and 1ms space followed by 24 bits, LSB first,
+// of the field's ID (4 bits), its value (16 bits) and a check nibble, the
+// XOR of the other five nibbles and 0xA; see Spec. This requires
+// StartInverted() and not Start().
+package telemetry
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/codec"
+)
+
+// FrameBits is the number of bits in a frame.
+const FrameBits = 24
+
+var (
+	HeaderPair = irtrx.TimePair{2800 * time.Microsecond, 1000 * time.Microsecond}
+	ZeroPair   = irtrx.TimePair{300 * time.Microsecond, 300 * time.Microsecond}
+	OnePair    = irtrx.TimePair{300 * time.Microsecond, 800 * time.Microsecond}
+	// StopPair ends the last bit's space.
+	StopPair = irtrx.TimePair{300 * time.Microsecond, 300 * time.Microsecond}
+)
+
+// Spec describes a frame's 24 bits as sent. Its Tolerance is as wide as it
+// can be without a zero's space passing for a one's, as the short marks
+// jitter by more than codec allows by default.
+var Spec = codec.Spec{
+	Name:      "telemetry",
+	Header:    HeaderPair,
+	One:       OnePair,
+	Zero:      ZeroPair,
+	Trailer:   StopPair[0],
+	Gap:       StopPair[1],
+	Bits:      FrameBits,
+	Tolerance: 45,
+}
+
+// FieldID identifies a telemetry field.
+type FieldID uint8
+
+const (
+	// Battery is the battery voltage in mV.
+	Battery FieldID = iota
+	// LinkQuality is the percentage of control frames decoded without
+	// error, standing in for signal strength; see Quality.
+	LinkQuality
+	// Errors counts errors of the application's choosing, e.g. decode
+	// errors or brownouts, wrapping at 65535.
+	Errors
+	// IDs 3 to 7 are reserved.
+
+	// User is the first of the NumUser fields left to the application;
+	// field i is User+i.
+	User FieldID = 8
+	// NumFields is the number of field IDs.
+	NumFields = 16
+)
+
+// NumUser is the number of user fields.
+const NumUser = NumFields - int(User)
+
+func (id FieldID) String() string {
+	switch {
+	case id == Battery:
+		return "battery"
+	case id == LinkQuality:
+		return "link"
+	case id == Errors:
+		return "errors"
+	case id >= User && id < NumFields:
+		return fmt.Sprintf("user%d", id-User)
+	}
+	return fmt.Sprintf("field%d", uint8(id))
+}
+
+var (
+	// ErrNoFrame is returned when unmarshalling TimePairs that hold no
+	// complete frame.
+	ErrNoFrame = errors.New("telemetry: no frame found")
+	// ErrCheck is returned when a frame's check nibble doesn't match.
+	ErrCheck = errors.New("telemetry: bad check nibble")
+)
+
+// Field is a single telemetry frame.
+type Field struct {
+	ID    FieldID
+	Value uint16
+}
+
+func init() {
+	irtrx.RegisterProtocol(irtrx.Protocol{
+		Name:  "telemetry",
+		Usage: "field value",
+		Encode: func(args ...uint64) (irtrx.FrameMarshaller, error) {
+			if len(args) != 2 || args[0] >= NumFields || args[1] > 0xFFFF {
+				return nil, irtrx.ErrArgs
+			}
+			return &Field{ID: FieldID(args[0]), Value: uint16(args[1])}, nil
+		},
+	})
+}
+
+// check returns the check nibble of the ID and value in raw.
+func check(raw uint32) uint32 {
+	c := uint32(0xA)
+	for i := 0; i < 20; i += 4 {
+		c ^= raw >> i & 0xF
+	}
+	return c
+}
+
+// Raw returns the frame's 24 bits as sent.
+func (f Field) Raw() uint32 {
+	raw := uint32(f.ID&0xF) | uint32(f.Value)<<4
+	return raw | check(raw)<<20
+}
+
+// UnmarshalFrame sets f from 24 raw bits, checking the check nibble.
+func (f *Field) UnmarshalFrame(raw uint32) error {
+	if raw>>20&0xF != check(raw&0xFFFFF) {
+		return ErrCheck
+	}
+	f.ID, f.Value = FieldID(raw&0xF), uint16(raw>>4)
+	return nil
+}
+
+// MarshalFrame implements irtrx.FrameMarshaller.
+func (f *Field) MarshalFrame() []irtrx.TimePair {
+	return Spec.Encode(uint64(f.Raw()))
+}
+
+// UnmarshalTimePairs implements irtrx.FrameUnmarshaller.
+func (f *Field) UnmarshalTimePairs(pairs []irtrx.TimePair) error {
+	err := ErrNoFrame
+	var got bool
+	sm := NewStateMachine(func(fd Field) {
+		*f, got = fd, true
+	})
+	sm.ErrorHandler = func(e error) {
+		err = e
+	}
+	for _, p := range pairs {
+		sm.HandleTimePair(p)
+		if got {
+			return nil
+		}
+	}
+	return err
+}
+
+// Protocol implements irtrx.Frame.
+func (f Field) Protocol() string { return "telemetry" }
+
+// Bits implements irtrx.Frame.
+func (f Field) Bits() []byte {
+	raw := f.Raw()
+	return []byte{byte(raw), byte(raw >> 8), byte(raw >> 16)}
+}
+
+func (f Field) String() string {
+	return fmt.Sprintf("%v %d", f.ID, f.Value)
+}
+
+// StateMachine implements irtrx.RxStateMachine, decoding telemetry frames.
+type StateMachine struct {
+	FieldHandler func(Field)
+	// ErrorHandler, if set, is called for every frame failing its check.
+	ErrorHandler func(error)
+
+	dec *codec.Decoder
+}
+
+func NewStateMachine(fieldHandler func(Field)) *StateMachine {
+	sm := &StateMachine{FieldHandler: fieldHandler}
+	sm.dec = codec.NewDecoder(&Spec, sm.raw)
+	return sm
+}
+
+func (sm *StateMachine) HandleTimePair(pair irtrx.TimePair) {
+	sm.dec.HandleTimePair(pair)
+}
+
+// raw checks and delivers the bits of a frame.
+func (sm *StateMachine) raw(v uint64) {
+	var f Field
+	if err := f.UnmarshalFrame(uint32(v)); err != nil {
+		if sm.ErrorHandler != nil {
+			sm.ErrorHandler(err)
+		}
+		return
+	}
+	if sm.FieldHandler != nil {
+		sm.FieldHandler(f)
+	}
+}
+
+var (
+	_ irtrx.Frame             = Field{}
+	_ irtrx.FrameUnmarshaller = (*Field)(nil)
+	_ irtrx.RxStateMachine    = (*StateMachine)(nil)
+)
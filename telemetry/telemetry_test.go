@@ -0,0 +1,146 @@
+package telemetry_test
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/irtest"
+	"github.com/sparques/irtrx/telemetry"
+)
+
+// protocol round trips telemetry.Fields of every ID.
+var protocol = irtest.Protocol[telemetry.Field]{
+	Name: "telemetry",
+	Random: func(r *rand.Rand) telemetry.Field {
+		return telemetry.Field{ID: telemetry.FieldID(r.Intn(telemetry.NumFields)), Value: uint16(r.Uint32())}
+	},
+	Marshal: func(f telemetry.Field) irtrx.FrameMarshaller { return &f },
+	NewDecoder: func(h func(telemetry.Field)) irtrx.RxStateMachine {
+		return telemetry.NewStateMachine(h)
+	},
+	Inverted: true,
+}
+
+// pairs returns the header, a pair for each of bits, '0' or '1', and the
+// stop pair.
+func pairs(bits string) []irtrx.TimePair {
+	out := []irtrx.TimePair{telemetry.HeaderPair}
+	for _, b := range bits {
+		if b == '1' {
+			out = append(out, telemetry.OnePair)
+		} else {
+			out = append(out, telemetry.ZeroPair)
+		}
+	}
+	return append(out, telemetry.StopPair)
+}
+
+// known is a battery reading of 3300mV: ID 0 and value 0xCE4 make 0x0CE40,
+// whose nibbles XORed with 0xA give the check nibble 0xC; LSB first.
+var known = telemetry.Field{ID: telemetry.Battery, Value: 3300}
+
+const knownBits = "00000010" + "01110011" + "00000011"
+
+func TestRoundTrip(t *testing.T) {
+	irtest.RoundTrip(t, protocol, 1000, 1)
+}
+
+func TestMarshalFrame(t *testing.T) {
+	if raw := known.Raw(); raw != 0xC0CE40 {
+		t.Errorf("Raw() = %#x, want 0xc0ce40", raw)
+	}
+	if got, want := known.MarshalFrame(), pairs(knownBits); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := known.Bits(), []byte{0x40, 0xCE, 0xC0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Bits() = %x, want %x", got, want)
+	}
+	if s := known.String(); s != "battery 3300" {
+		t.Errorf("String() = %q", s)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	// what every decoder must do, on random frames
+	irtest.Conform(t, protocol)
+
+	for _, want := range []telemetry.Field{
+		known,
+		{ID: telemetry.Battery},
+		{ID: telemetry.NumFields - 1, Value: 0xFFFF},
+		{ID: telemetry.User, Value: 1},
+	} {
+		var got []telemetry.Field
+		w := irtest.NewWire(telemetry.NewStateMachine(func(f telemetry.Field) { got = append(got, f) }), true)
+		w.SendFrame(&want)
+		if len(got) != 1 || got[0] != want {
+			t.Errorf("sent %v, decoded %v", want, got)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	// a value bit flipped, so the check nibble no longer matches
+	bad := knownBits[:4] + "1" + knownBits[5:]
+	var got []telemetry.Field
+	var errs []error
+	sm := telemetry.NewStateMachine(func(f telemetry.Field) { got = append(got, f) })
+	sm.ErrorHandler = func(err error) { errs = append(errs, err) }
+	irtest.NewWire(sm, true).SendPairs(pairs(bad)...)
+	if len(got) != 0 || len(errs) != 1 || errs[0] != telemetry.ErrCheck {
+		t.Errorf("decoded %v, errors %v; want [%v]", got, errs, telemetry.ErrCheck)
+	}
+
+	var f telemetry.Field
+	if err := f.UnmarshalFrame(0xC0CE41); err != telemetry.ErrCheck {
+		t.Errorf("UnmarshalFrame: got %v, want %v", err, telemetry.ErrCheck)
+	}
+	if err := f.UnmarshalTimePairs(pairs(bad)); err != telemetry.ErrCheck {
+		t.Errorf("UnmarshalTimePairs: got %v, want %v", err, telemetry.ErrCheck)
+	}
+}
+
+func TestUnmarshalTimePairs(t *testing.T) {
+	var f telemetry.Field
+	noise := []irtrx.TimePair{telemetry.OnePair, telemetry.ZeroPair}
+	if err := f.UnmarshalTimePairs(append(noise, pairs(knownBits)...)); err != nil || f != known {
+		t.Errorf("got %v, %v; want %v", f, err, known)
+	}
+	for name, p := range map[string][]irtrx.TimePair{
+		"Empty":    nil,
+		"NoHeader": pairs(knownBits)[1:],
+		"Short":    pairs(knownBits)[:20],
+	} {
+		if err := f.UnmarshalTimePairs(p); err != telemetry.ErrNoFrame {
+			t.Errorf("%s: got %v, want %v", name, err, telemetry.ErrNoFrame)
+		}
+	}
+}
+
+func TestFieldID(t *testing.T) {
+	for id, want := range map[telemetry.FieldID]string{
+		telemetry.Battery:       "battery",
+		telemetry.LinkQuality:   "link",
+		telemetry.Errors:        "errors",
+		3:                       "field3",
+		telemetry.User:          "user0",
+		telemetry.NumFields - 1: "user7",
+		telemetry.NumFields:     "field16",
+	} {
+		if got := id.String(); got != want {
+			t.Errorf("FieldID(%d).String() = %q, want %q", id, got, want)
+		}
+	}
+}
+
+// TestCorpus replays the captures in testdata, each of which holds the
+// value it should decode to.
+func TestCorpus(t *testing.T) {
+	caps, err := irtest.LoadCorpus("testdata/captures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	irtest.ReplayCorpus(t, caps, "telemetry", protocol.NewDecoder)
+}
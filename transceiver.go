@@ -0,0 +1,87 @@
+package irtrx
+
+import "time"
+
+// Transceiver combines an RxDevice and a TxDevice on the same board into a
+// half-duplex link. Reception is muted while transmitting (the receiver would
+// otherwise happily decode our own LED) and transmissions are held off while
+// a frame is being received.
+//
+// Transceiver implements Transmitter.
+type Transceiver struct {
+	Rx *RxDevice
+	Tx *TxDevice
+
+	// Settle is how long reception stays muted after a transmission ends,
+	// giving the receiver's AGC time to recover and reflections time to die.
+	Settle time.Duration
+	// Idle is how long the receiver must have been quiet before we consider
+	// the channel clear to transmit.
+	Idle time.Duration
+	// MaxWait bounds how long a transmission is delayed waiting for the
+	// channel to clear. Zero means wait indefinitely.
+	MaxWait time.Duration
+}
+
+// NewTransceiver returns a Transceiver using rx and tx with reasonable
+// defaults for 38kHz remotes.
+func NewTransceiver(rx *RxDevice, tx *TxDevice) *Transceiver {
+	return &Transceiver{
+		Rx:      rx,
+		Tx:      tx,
+		Settle:  2 * time.Millisecond,
+		Idle:    20 * time.Millisecond,
+		MaxWait: 500 * time.Millisecond,
+	}
+}
+
+// Busy reports whether a frame appears to be in the middle of being received.
+// Edges seen while muted, i.e. our own transmissions, aren't counted.
+func (t *Transceiver) Busy() bool {
+	return t.Rx.clock.Now().Sub(t.Rx.LastActivity()) < t.Idle
+}
+
+// waitClear blocks until the channel is clear or MaxWait has passed.
+func (t *Transceiver) waitClear() {
+	clock := t.Rx.clock
+	start := clock.Now()
+	for t.Busy() {
+		if t.MaxWait != 0 && clock.Now().Sub(start) > t.MaxWait {
+			return
+		}
+		clock.Sleep(t.Idle / 4)
+	}
+}
+
+func (t *Transceiver) begin() {
+	t.waitClear()
+	t.Rx.Mute()
+}
+
+func (t *Transceiver) end() {
+	t.Rx.Unmute(t.Settle)
+}
+
+// SendPair implements Transmitter.
+func (t *Transceiver) SendPair(pair TimePair) {
+	t.begin()
+	t.Tx.SendPair(pair)
+	t.end()
+}
+
+// SendPairs implements Transmitter.
+func (t *Transceiver) SendPairs(pairs ...TimePair) {
+	t.begin()
+	t.Tx.SendPairs(pairs...)
+	t.end()
+}
+
+// SendFrame implements Transmitter. As with TxDevice.SendFrame, a frame
+// implementing CarrierHinter is sent on its own carrier.
+func (t *Transceiver) SendFrame(fm FrameMarshaller) {
+	t.begin()
+	t.Tx.SendFrame(fm)
+	t.end()
+}
+
+var _ Transmitter = (*Transceiver)(nil)
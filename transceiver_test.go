@@ -0,0 +1,39 @@
+//go:build !tinygo
+
+package irtrx_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/internal/hal"
+)
+
+func TestTransceiverCarrierHint(t *testing.T) {
+	defer hal.Reset()
+	const txPin, rxPin hal.Pin = 2, 5
+	rx := irtrx.NewRxDevice(rxPin, discard{})
+	tx := irtrx.NewTxDevice(txPin)
+	idle := hal.GetPWM(txPin).Top()
+
+	var tops []uint32
+	hal.Watch(txPin, func(_ hal.Pin, high bool) {
+		if high {
+			tops = append(tops, hal.GetPWM(txPin).Top())
+		}
+	})
+	tr := irtrx.NewTransceiver(rx, tx)
+	tr.SendFrame(irtrx.Recording{
+		Freq:  56000,
+		Pairs: []irtrx.TimePair{{500 * time.Microsecond, 500 * time.Microsecond}},
+	})
+
+	// 56kHz is 17857ns, 2232 counts at 125MHz
+	if len(tops) != 1 || tops[0] != 2231 {
+		t.Errorf("marks sent with top %v, want [2231]", tops)
+	}
+	if top := hal.GetPWM(txPin).Top(); top != idle {
+		t.Errorf("carrier not restored: top %d, want %d", top, idle)
+	}
+}
@@ -0,0 +1,155 @@
+// tuya encodes and decodes the IR codes used by Tuya WiFi IR blasters (and
+// rebadges such as the YS-IRB / UFO-R11), as found in Tuya's cloud, Zigbee2MQTT
+// and Home Assistant's tuya integrations.
+//
+// A code is base64 of a compressed blob. Uncompressed, it is a list of little
+// endian uint16 durations in microseconds, alternating mark and space. The
+// compression is the FastLZ level 1 block format: a header byte whose top 3
+// bits are a length and bottom 5 bits either a literal run length or the
+// high bits of a back reference distance.
+package tuya
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/sparques/irtrx"
+)
+
+// TrailingSpace is the space given to a final mark without one.
+const TrailingSpace = 40 * time.Millisecond
+
+const (
+	maxLiteral  = 32
+	minMatch    = 3
+	maxMatch    = 7 + 255 + 2
+	maxDistance = 8192
+)
+
+var (
+	// ErrCorrupt is returned for codes that don't decompress.
+	ErrCorrupt = errors.New("tuya: corrupt code")
+)
+
+// decompress expands a FastLZ level 1 block.
+func decompress(in []byte) ([]byte, error) {
+	var out []byte
+	for i := 0; i < len(in); {
+		h := in[i]
+		i++
+		l, d := int(h>>5), int(h&0x1F)
+		if l == 0 {
+			// literal run
+			l = d + 1
+			if i+l > len(in) {
+				return nil, ErrCorrupt
+			}
+			out = append(out, in[i:i+l]...)
+			i += l
+			continue
+		}
+		// back reference
+		if l == 7 {
+			if i >= len(in) {
+				return nil, ErrCorrupt
+			}
+			l += int(in[i])
+			i++
+		}
+		l += 2
+		if i >= len(in) {
+			return nil, ErrCorrupt
+		}
+		d = (d<<8 | int(in[i])) + 1
+		i++
+		if d > len(out) {
+			return nil, ErrCorrupt
+		}
+		// byte by byte, as the reference may overlap what it produces
+		start := len(out) - d
+		for j := 0; j < l; j++ {
+			out = append(out, out[start+j])
+		}
+	}
+	return out, nil
+}
+
+// compress is a simple greedy FastLZ level 1 compressor.
+func compress(in []byte) []byte {
+	var out []byte
+	lit := 0 // start of pending literals
+	flush := func(end int) {
+		for lit < end {
+			n := min(end-lit, maxLiteral)
+			out = append(out, byte(n-1))
+			out = append(out, in[lit:lit+n]...)
+			lit += n
+		}
+	}
+	for i := 0; i < len(in); {
+		bestLen, bestDist := 0, 0
+		for j := max(0, i-maxDistance); j < i; j++ {
+			n := 0
+			for i+n < len(in) && n < maxMatch && in[j+n] == in[i+n] {
+				n++
+			}
+			if n > bestLen {
+				bestLen, bestDist = n, i-j
+			}
+		}
+		if bestLen < minMatch {
+			i++
+			continue
+		}
+		flush(i)
+		l, d := bestLen-2, bestDist-1
+		if l < 7 {
+			out = append(out, byte(l<<5|d>>8), byte(d))
+		} else {
+			out = append(out, byte(7<<5|d>>8), byte(l-7), byte(d))
+		}
+		i += bestLen
+		lit = i
+	}
+	flush(len(in))
+	return out
+}
+
+// Decode decodes a Tuya IR code. Tuya blasters don't store the carrier, so
+// the Recording uses 38kHz.
+func Decode(code string) (irtrx.Recording, error) {
+	b, err := base64.StdEncoding.DecodeString(code)
+	if err != nil {
+		return irtrx.Recording{}, err
+	}
+	raw, err := decompress(b)
+	if err != nil {
+		return irtrx.Recording{}, err
+	}
+	if len(raw)%2 != 0 {
+		return irtrx.Recording{}, ErrCorrupt
+	}
+	r := irtrx.Recording{Freq: irtrx.Freq38Khz}
+	for i := 0; i < len(raw); i += 4 {
+		p := irtrx.TimePair{time.Duration(binary.LittleEndian.Uint16(raw[i:])) * time.Microsecond, TrailingSpace}
+		if i+2 < len(raw) {
+			p[1] = time.Duration(binary.LittleEndian.Uint16(raw[i+2:])) * time.Microsecond
+		}
+		r.Pairs = append(r.Pairs, p)
+	}
+	return r, nil
+}
+
+// Encode returns pairs as a Tuya IR code. Durations are clamped to 65535µs.
+func Encode(pairs []irtrx.TimePair) string {
+	raw := make([]byte, 0, 4*len(pairs))
+	for _, p := range pairs {
+		for _, d := range p {
+			us := min(d.Microseconds(), 0xFFFF)
+			raw = binary.LittleEndian.AppendUint16(raw, uint16(us))
+		}
+	}
+	return base64.StdEncoding.EncodeToString(compress(raw))
+}
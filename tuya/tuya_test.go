@@ -0,0 +1,146 @@
+package tuya_test
+
+import (
+	"encoding/base64"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/tuya"
+)
+
+func us(n int) time.Duration { return time.Duration(n) * time.Microsecond }
+
+// known is the start of an NEC frame: a 9000µs and 4500µs header, four
+// zeros and a final mark, compressed as a 6 byte literal run, an 8 byte
+// back reference overlapping its own output (distance 2) and a 4 byte
+// literal run:
+//
+//	05 28 23 94 11 30 02  C0 01  03 9A 06 30 02
+const known = "BSgjlBEwAsABA5oGMAI="
+
+var knownPairs = []irtrx.TimePair{
+	{us(9000), us(4500)},
+	{us(560), us(560)},
+	{us(560), us(560)},
+	{us(560), us(1690)},
+	// the final mark has no space of its own
+	{us(560), tuya.TrailingSpace},
+}
+
+func TestDecode(t *testing.T) {
+	r, err := tuya.Decode(known)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Freq != irtrx.Freq38Khz {
+		t.Errorf("Freq = %d, want %d", r.Freq, irtrx.Freq38Khz)
+	}
+	if !reflect.DeepEqual(r.Pairs, knownPairs) {
+		t.Errorf("got %v, want %v", r.Pairs, knownPairs)
+	}
+}
+
+func TestDecodeLongMatch(t *testing.T) {
+	// a 2 byte literal then a 38 byte back reference, whose length needs
+	// the extra byte: 01 30 02  E0 1D 01
+	r, err := tuya.Decode("ATAC4B0B")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := make([]irtrx.TimePair, 10)
+	for i := range want {
+		want[i] = irtrx.TimePair{us(560), us(560)}
+	}
+	if !reflect.DeepEqual(r.Pairs, want) {
+		t.Errorf("got %v, want %v", r.Pairs, want)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	pairs := []irtrx.TimePair{
+		{us(9000), us(4500)},
+		{us(560), us(560)},
+		{us(560), us(560)},
+		{us(560), us(1690)},
+		{us(560), us(560)},
+	}
+	// 05 28 23 94 11 30 02  C0 01  01 9A 06  40 0B: the last four bytes
+	// refer back 12 bytes
+	if got, want := tuya.Encode(pairs), "BSgjlBEwAsABAZoGQAs="; got != want {
+		t.Errorf("Encode() = %s, want %s", got, want)
+	}
+	// durations are clamped rather than wrapped
+	long := []irtrx.TimePair{{us(9000), 100 * time.Millisecond}}
+	r, err := tuya.Decode(tuya.Encode(long))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []irtrx.TimePair{{us(9000), us(0xFFFF)}}; !reflect.DeepEqual(r.Pairs, want) {
+		t.Errorf("got %v, want %v", r.Pairs, want)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		// a few distinct durations, as in a real code, so there is
+		// something to compress, up to past the longest back reference
+		durations := []time.Duration{us(560), us(1690), us(9000), us(4500), us(r.Intn(0x10000))}
+		pairs := make([]irtrx.TimePair, 1+r.Intn(300))
+		for j := range pairs {
+			pairs[j] = irtrx.TimePair{durations[r.Intn(len(durations))], durations[r.Intn(len(durations))]}
+		}
+		code := tuya.Encode(pairs)
+		got, err := tuya.Decode(code)
+		if err != nil {
+			t.Fatalf("Decode(%s): %v", code, err)
+		}
+		if !reflect.DeepEqual(got.Pairs, pairs) {
+			t.Fatalf("round trip %d: got %v, want %v", i, got.Pairs, pairs)
+		}
+	}
+}
+
+func TestCorrupt(t *testing.T) {
+	for _, tc := range []struct {
+		name, code string
+	}{
+		// 05 28 23: the literal run is cut short
+		{"Literal", "BSgj"},
+		// C0 05: a back reference before there is any output
+		{"Distance", "wAU="},
+		// 03 AA BB CC DD 20: a back reference without its distance byte
+		{"NoDistance", "A6q7zN0g"},
+		// 03 AA BB CC DD E0: a long back reference without its length byte
+		{"NoLength", "A6q7zN3g"},
+		// 01 30 02 20 05: a back reference further than the output
+		{"Far", "ATACIAU="},
+		// 02 AA BB CC: an odd number of bytes
+		{"Odd", "Aqq7zA=="},
+	} {
+		if _, err := tuya.Decode(tc.code); err != tuya.ErrCorrupt {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tuya.ErrCorrupt)
+		}
+	}
+	if _, err := tuya.Decode("not base64!"); err == nil {
+		t.Error("bad base64: no error")
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	// every prefix of a real code either decodes or fails, without
+	// panicking
+	b, _ := base64.StdEncoding.DecodeString(tuya.Encode(knownPairs))
+	for n := range b {
+		tuya.Decode(base64.StdEncoding.EncodeToString(b[:n]))
+	}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		junk := make([]byte, r.Intn(64))
+		r.Read(junk)
+		tuya.Decode(base64.StdEncoding.EncodeToString(junk))
+	}
+}
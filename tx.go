@@ -1,50 +1,201 @@
 package irtrx
 
 import (
-	. "machine"
 	"time"
 
-	"github.com/sparques/pwm"
+	. "github.com/sparques/irtrx/internal/hal"
 )
 
-type TxDevice struct {
+// EmitMode determines how a TxDevice with more than one emitter uses them.
+type EmitMode uint8
+
+const (
+	// EmitAll drives every emitter at once.
+	EmitAll EmitMode = iota
+	// EmitRoundRobin drives a single emitter per frame, moving on to the
+	// next emitter for the next frame.
+	EmitRoundRobin
+)
+
+// emitter is a single IR LED on a PWM capable pin.
+type emitter struct {
 	pin    Pin
-	pgroup pwm.Group
+	pgroup PWMGroup
 	ch     uint8
 	duty   uint32
-	freq   uint64
 }
 
-func NewTxDevice(pin Pin) *TxDevice {
+func newEmitter(pin Pin, freq uint64) emitter {
 	pin.Configure(PinConfig{Mode: PinPWM})
-	pgroup := pwm.Get(pin)
-	pgroup.Configure(PWMConfig{Period: uint64(1e9) / uint64(Freq38Khz)})
+	pgroup := GetPWM(pin)
+	pgroup.Configure(PWMConfig{Period: uint64(1e9) / freq})
 	ch, _ := pgroup.Channel(pin)
 	pgroup.Set(ch, 0)
-	return &TxDevice{
+	return emitter{
 		pin:    pin,
 		pgroup: pgroup,
 		ch:     ch,
 		duty:   pgroup.Top() / 2,
-		freq:   Freq38Khz,
+	}
+}
+
+type TxDevice struct {
+	emitters []emitter
+	mode     EmitMode
+	// index of the emitter to use for the next frame in EmitRoundRobin mode
+	next   int
+	freq   uint64
+	closed bool
+	// in baseband mode the mark/space envelope is output without a carrier
+	baseband bool
+	// power is the transmit power in percent; see SetPower
+	power uint8
+	// durations shorter than busyWait are timed by spinning
+	busyWait time.Duration
+
+	// alarm driven transmission; see alarm.go
+	alarm alarmState
+}
+
+// TxConfig holds optional TxDevice settings.
+type TxConfig struct {
+	// BusyWait trades CPU time for timing accuracy. The last BusyWait of
+	// every mark and space is timed by spinning on the clock instead of
+	// sleeping, so marks and spaces shorter than BusyWait are entirely
+	// busy-waited. Some receivers reject frames when TinyGo's sleep
+	// granularity stretches short marks; a BusyWait of 2ms fixes this for
+	// most protocols. Zero, the default, always sleeps.
+	BusyWait time.Duration
+}
+
+// Configure applies cfg to the TxDevice.
+func (tx *TxDevice) Configure(cfg TxConfig) error {
+	tx.busyWait = cfg.BusyWait
+	return nil
+}
+
+func NewTxDevice(pin Pin) *TxDevice {
+	return NewMultiTxDevice(EmitAll, pin)
+}
+
+// NewMultiTxDevice returns a TxDevice that drives an IR LED on each of pins,
+// e.g. to cover 360 degrees around a robot. The pins should be on PWM
+// channels that are not otherwise in use. mode determines whether all
+// emitters are driven together or in turn.
+func NewMultiTxDevice(mode EmitMode, pins ...Pin) *TxDevice {
+	tx := &TxDevice{
+		emitters: make([]emitter, len(pins)),
+		mode:     mode,
+		freq:     Freq38Khz,
+		power:    100,
+	}
+	for i, pin := range pins {
+		tx.emitters[i] = newEmitter(pin, tx.freq)
+	}
+	return tx
+}
+
+// active returns the emitters to drive for the current frame.
+func (tx *TxDevice) active() []emitter {
+	if tx.mode == EmitRoundRobin && len(tx.emitters) > 1 {
+		return tx.emitters[tx.next : tx.next+1]
+	}
+	return tx.emitters
+}
+
+func (tx *TxDevice) carrier(on bool) {
+	for _, e := range tx.active() {
+		if on {
+			e.pgroup.Set(e.ch, e.duty)
+		} else {
+			e.pgroup.Set(e.ch, 0)
+		}
 	}
 }
 
 func (tx *TxDevice) SendPair(pair TimePair) {
-	tx.pgroup.Set(tx.ch, tx.duty)
-	time.Sleep(pair[0])
-	tx.pgroup.Set(tx.ch, 0)
-	time.Sleep(pair[1])
+	tx.sendPair(time.Now(), pair)
+}
+
+// sendPair emits pair beginning at start and returns the time at which the
+// pair ends. Sleeping until absolute deadlines, rather than for relative
+// durations, means the time spent in pgroup.Set and waking up from sleep is
+// absorbed by the following mark or space instead of being added to it, so
+// long frames don't accumulate drift.
+func (tx *TxDevice) sendPair(start time.Time, pair TimePair) time.Time {
+	if tx.closed {
+		return start
+	}
+	mark := start.Add(pair[0])
+	end := mark.Add(pair[1])
+	tx.carrier(true)
+	tx.waitUntil(mark)
+	tx.carrier(false)
+	tx.waitUntil(end)
+	return end
 }
 
 func (tx *TxDevice) SendPairs(pairs ...TimePair) {
+	if tx.alarm.alarm != nil {
+		tx.sendPairsAlarm(pairs)
+		return
+	}
+	t := time.Now()
 	for _, p := range pairs {
-		tx.SendPair(p)
+		t = tx.sendPair(t, p)
+	}
+	tx.frameDone()
+}
+
+// SendStream sends a frame pair by pair as fs produces them.
+func (tx *TxDevice) SendStream(fs FrameStreamer) {
+	t := time.Now()
+	for {
+		p, ok := fs.NextPair()
+		if !ok {
+			break
+		}
+		t = tx.sendPair(t, p)
+	}
+	tx.frameDone()
+}
+
+// frameDone is called at the end of every frame.
+func (tx *TxDevice) frameDone() {
+	if tx.mode == EmitRoundRobin && len(tx.emitters) > 1 {
+		tx.next = (tx.next + 1) % len(tx.emitters)
+	}
+}
+
+func sleepUntil(t time.Time) {
+	if d := time.Until(t); d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// waitUntil returns at t, busy-waiting for the final tx.busyWait of it.
+func (tx *TxDevice) waitUntil(t time.Time) {
+	if tx.busyWait == 0 {
+		sleepUntil(t)
+		return
+	}
+	sleepUntil(t.Add(-tx.busyWait))
+	for time.Now().Before(t) {
 	}
 }
 
+// SendFrame sends the frame produced by fm. If fm implements CarrierHinter
+// the carrier is switched for the duration of the frame and then restored.
 func (tx *TxDevice) SendFrame(fm FrameMarshaller) {
+	ch, ok := fm.(CarrierHinter)
+	if !ok || ch.Carrier() == 0 || uint64(ch.Carrier()) == tx.freq {
+		tx.SendPairs(fm.MarshalFrame()...)
+		return
+	}
+	prev := tx.freq
+	tx.SetCarrier(uint64(ch.Carrier()))
 	tx.SendPairs(fm.MarshalFrame()...)
+	tx.SetCarrier(prev)
 }
 
 func (tx *TxDevice) SendFrames(fms ...FrameMarshaller) {
@@ -52,3 +203,155 @@ func (tx *TxDevice) SendFrames(fms ...FrameMarshaller) {
 		tx.SendFrame(fm)
 	}
 }
+
+// DefaultHoldGap is the space Hold leaves between retransmissions of frames
+// that don't implement RepeatMarshaller.
+const DefaultHoldGap = 40 * time.Millisecond
+
+// Hold emulates a button being held down for d: the frame is sent, followed
+// by repeats until d has passed. If fm implements RepeatMarshaller, its
+// repeat frames and period are used; otherwise the whole frame is resent with
+// DefaultHoldGap between each.
+func (tx *TxDevice) Hold(fm FrameMarshaller, d time.Duration) {
+	start := time.Now()
+	pairs := fm.MarshalFrame()
+	repeat := pairs
+	var period time.Duration
+	if rm, ok := fm.(RepeatMarshaller); ok {
+		repeat = rm.MarshalRepeat()
+		period = rm.RepeatPeriod()
+	} else {
+		for _, p := range pairs {
+			period += p[0] + p[1]
+		}
+		period += DefaultHoldGap
+	}
+
+	next := start
+	for {
+		tx.SendPairs(pairs...)
+		next = next.Add(period)
+		if next.Sub(start) >= d {
+			return
+		}
+		sleepUntil(next)
+		pairs = repeat
+	}
+}
+
+// SetEmitMode changes how multiple emitters are driven.
+func (tx *TxDevice) SetEmitMode(mode EmitMode) {
+	tx.mode = mode
+	tx.next = 0
+}
+
+// SetCarrier changes the carrier frequency, in Hz, used for all subsequent
+// transmissions. It has no effect on a closed TxDevice, and a frequency of
+// zero is ignored.
+func (tx *TxDevice) SetCarrier(freq uint64) {
+	if tx.closed || freq == 0 {
+		return
+	}
+	for i := range tx.emitters {
+		e := &tx.emitters[i]
+		e.pgroup.SetPeriod(uint64(1e9) / freq)
+	}
+	tx.freq = freq
+	tx.updateDuty()
+}
+
+// SetBaseband enables or disables baseband mode. In baseband mode, marks are
+// output as a steady high level rather than a modulated carrier. This is for
+// wired or opto-isolated links between boards (pair with
+// RxDevice.SetBaseband) and for transmitter modules that do their own
+// modulation.
+func (tx *TxDevice) SetBaseband(baseband bool) {
+	tx.baseband = baseband
+	tx.updateDuty()
+}
+
+// SetPower sets the transmit power as a percentage of full power by scaling
+// the duty cycle of the carrier. Lower power means less range, which is
+// useful for close range pairing, simulating damage falloff in laser tag, or
+// avoiding blinding receivers on the same board. Values above 100 are
+// treated as 100. Power has no effect in baseband mode.
+func (tx *TxDevice) SetPower(percent uint8) {
+	if percent > 100 {
+		percent = 100
+	}
+	tx.power = percent
+	tx.updateDuty()
+}
+
+// Power returns the transmit power set by SetPower.
+func (tx *TxDevice) Power() uint8 {
+	return tx.power
+}
+
+func (tx *TxDevice) updateDuty() {
+	if tx.closed {
+		return
+	}
+	for i := range tx.emitters {
+		e := &tx.emitters[i]
+		if tx.baseband {
+			e.duty = e.pgroup.Top()
+		} else {
+			e.duty = uint32(uint64(e.pgroup.Top()/2) * uint64(tx.power) / 100)
+		}
+	}
+}
+
+// SendRecording replays a Recording. If the Recording specifies a carrier
+// frequency different from the current one, the carrier is switched for the
+// duration of the send and then restored.
+func (tx *TxDevice) SendRecording(r Recording) {
+	tx.SendFrame(r)
+}
+
+// SendRaw sends pairs using the carrier freq. It is shorthand for
+// SendRecording(Recording{Freq: freq, Pairs: pairs}).
+func (tx *TxDevice) SendRaw(freq uint32, pairs ...TimePair) {
+	tx.SendRecording(Recording{Freq: freq, Pairs: pairs})
+}
+
+// Disable forces the carrier off and drives the pins low as plain outputs, so
+// the IR LEDs are guaranteed dark regardless of what else happens to the PWM
+// slices. Pairs sent while disabled are timed as usual but nothing is
+// emitted. Use Enable to resume transmitting.
+func (tx *TxDevice) Disable() {
+	for _, e := range tx.emitters {
+		if !tx.closed {
+			e.pgroup.Set(e.ch, 0)
+		}
+		e.pin.Configure(PinConfig{Mode: PinOutput})
+		e.pin.Low()
+	}
+}
+
+// Enable hands the pins back to the PWM peripheral after Disable.
+func (tx *TxDevice) Enable() {
+	if tx.closed {
+		return
+	}
+	for _, e := range tx.emitters {
+		e.pin.Configure(PinConfig{Mode: PinPWM})
+		e.pgroup.Set(e.ch, 0)
+	}
+}
+
+// Close disables the TxDevice and releases the PWM channels so the slices can
+// be reconfigured for other peripherals. The pins are left as inputs. A
+// closed TxDevice silently drops anything sent to it and ignores changes to
+// its carrier; create a new one with NewTxDevice to transmit again.
+func (tx *TxDevice) Close() error {
+	tx.Disable()
+	for i := range tx.emitters {
+		tx.emitters[i].pin.Configure(PinConfig{Mode: PinInput})
+		tx.emitters[i].pgroup = nil
+	}
+	tx.closed = true
+	return nil
+}
+
+var _ Transmitter = (*TxDevice)(nil)
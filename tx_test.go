@@ -0,0 +1,44 @@
+//go:build !tinygo
+
+package irtrx_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/internal/hal"
+)
+
+func TestRoundRobin(t *testing.T) {
+	defer hal.Reset()
+	pins := []hal.Pin{2, 4, 6}
+	var marks []hal.Pin
+	for _, pin := range pins {
+		hal.Watch(pin, func(p hal.Pin, high bool) {
+			if high {
+				marks = append(marks, p)
+			}
+		})
+	}
+	tx := irtrx.NewMultiTxDevice(irtrx.EmitRoundRobin, pins...)
+	frame := irtrx.Recording{Pairs: []irtrx.TimePair{{100 * time.Microsecond, 100 * time.Microsecond}}}
+	for i := 0; i < 4; i++ {
+		tx.SendFrame(frame)
+	}
+	want := []hal.Pin{2, 4, 6, 2}
+	if len(marks) != len(want) {
+		t.Fatalf("marks on %v, want %v", marks, want)
+	}
+	for i := range want {
+		if marks[i] != want[i] {
+			t.Fatalf("marks on %v, want %v", marks, want)
+		}
+	}
+}
+
+func TestRoundRobinNoPins(t *testing.T) {
+	tx := irtrx.NewMultiTxDevice(irtrx.EmitRoundRobin)
+	tx.SendFrame(irtrx.Recording{Pairs: []irtrx.TimePair{{100 * time.Microsecond, 100 * time.Microsecond}}})
+	tx.SendFrame(irtrx.Recording{Pairs: []irtrx.TimePair{{100 * time.Microsecond, 100 * time.Microsecond}}})
+}
@@ -0,0 +1,77 @@
+package universal
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// ErrNotFound is returned by a Store for a name it doesn't hold.
+var ErrNotFound = errors.New("universal: no code of that name")
+
+// Store persists learned codes, each as the binary form of its Recording.
+// Implement it over flash, a file system or an EEPROM to keep codes across
+// power cycles.
+type Store interface {
+	// Save stores data as name, replacing anything already there.
+	Save(name string, data []byte) error
+	// Load returns the data saved as name, or ErrNotFound.
+	Load(name string) ([]byte, error)
+	// Delete removes name. Deleting a name that isn't there is not an
+	// error.
+	Delete(name string) error
+	// Names returns every stored name, sorted.
+	Names() ([]string, error)
+}
+
+// MemStore is a Store in RAM; codes are lost on reset.
+type MemStore struct {
+	mu    sync.Mutex
+	codes map[string][]byte
+}
+
+// NewMemStore returns an empty MemStore.
+func NewMemStore() *MemStore {
+	return &MemStore{codes: make(map[string][]byte)}
+}
+
+// Save implements Store.
+func (s *MemStore) Save(name string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.codes[name] = append([]byte(nil), data...)
+	return nil
+}
+
+// Load implements Store.
+func (s *MemStore) Load(name string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.codes[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return data, nil
+}
+
+// Delete implements Store.
+func (s *MemStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.codes, name)
+	return nil
+}
+
+// Names implements Store.
+func (s *MemStore) Names() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.codes))
+	for name := range s.codes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+var _ Store = (*MemStore)(nil)
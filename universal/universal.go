@@ -0,0 +1,327 @@
+// universal is the universal remote use case in one package: learn a code
+// from any remote, identify its protocol, keep it in a Store, and replay it
+// by name.
+//
+//	r := universal.NewRemote(irtrx.NewTxDevice(txPin), universal.NewMemStore())
+//	rx := irtrx.NewRxDevice(rxPin, r)
+//	rx.StartInverted()
+//	r.Muter = rx
+//	r.Flusher = rx
+//
+//	code, err := r.Learn("power") // press power on the remote
+//	err = r.Send("power")
+//
+// Codes of a known protocol are replayed with its encoder, which has exact
+// timings; anything else is replayed from the raw capture.
+package universal
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/sparques/irtrx"
+	"github.com/sparques/irtrx/cheapo"
+	"github.com/sparques/irtrx/codec"
+	"github.com/sparques/irtrx/hexbug"
+	"github.com/sparques/irtrx/samsung"
+	"github.com/sparques/irtrx/samsungac"
+)
+
+// MaxPairs is the longest code that can be learned; Samsung AC frames are
+// the longest supported at 117 pairs.
+const MaxPairs = 160
+
+// ErrTimeout is returned by Learn when nothing is received.
+var ErrTimeout = errors.New("universal: nothing received")
+
+// Frame is a decoded frame that can also be sent.
+type Frame interface {
+	irtrx.Frame
+	irtrx.FrameMarshaller
+	irtrx.FrameUnmarshaller
+}
+
+// Detectors are tried in order by Identify. Protocols with stricter headers
+// come first, as samsung accepts any long header. Append your own to have
+// them recognised.
+var Detectors = []func() Frame{
+	func() Frame { return new(hexbug.Cmd) },
+	func() Frame { return new(samsungac.State) },
+	func() Frame { return new(cheapo.Frame) },
+	func() Frame { return &codec.Code{Spec: &codec.NEC} },
+	func() Frame { return new(samsung.ExtFrame) },
+	func() Frame { return new(samsung.Frame) },
+}
+
+// Tolerance is how far, in percent, each captured mark and space may be from
+// those of the frame it decodes to for Identify to accept the frame.
+const Tolerance = 30
+
+// Identify returns the frame pairs decode to, or nil if they aren't of any
+// known protocol. Decoders are lenient, and some accept parts of other
+// protocols' frames, so a frame is only accepted if marshalling it gives
+// back pairs, mark for mark and space for space but for the final gap.
+func Identify(pairs []irtrx.TimePair) Frame {
+	for _, d := range Detectors {
+		f := d()
+		if f.UnmarshalTimePairs(pairs) == nil && Matches(f, pairs) {
+			return f
+		}
+	}
+	return nil
+}
+
+// Matches reports whether fm marshals to pairs, within Tolerance, ignoring
+// the final space, which is the gap before whatever comes next.
+func Matches(fm irtrx.FrameMarshaller, pairs []irtrx.TimePair) bool {
+	want := fm.MarshalFrame()
+	if len(want) != len(pairs) {
+		return false
+	}
+	for i := range want {
+		if !codec.Within(pairs[i][0], want[i][0], Tolerance) {
+			return false
+		}
+		if i < len(want)-1 && !codec.Within(pairs[i][1], want[i][1], Tolerance) {
+			return false
+		}
+	}
+	return true
+}
+
+// Code is a learned code.
+type Code struct {
+	Name      string
+	Recording irtrx.Recording
+	// Frame is the decoded Recording, or nil if its protocol is unknown.
+	Frame Frame
+}
+
+// NewCode returns the Code for rec, identifying its protocol.
+func NewCode(name string, rec irtrx.Recording) *Code {
+	return &Code{Name: name, Recording: rec, Frame: Identify(rec.Pairs)}
+}
+
+// Marshaller returns what to send for c: the decoded frame if there is one,
+// otherwise the raw Recording.
+func (c *Code) Marshaller() irtrx.FrameMarshaller {
+	if c.Frame != nil {
+		return c.Frame
+	}
+	return c.Recording
+}
+
+func (c *Code) String() string {
+	if c.Frame == nil {
+		return fmt.Sprintf("unknown protocol, %d pairs", len(c.Recording.Pairs))
+	}
+	return fmt.Sprintf("%s %v, bits %x", c.Frame.Protocol(), c.Frame, c.Frame.Bits())
+}
+
+// Muter is implemented by irtrx.RxDevice.
+type Muter interface {
+	Mute()
+	Unmute(settle time.Duration)
+}
+
+// capture is a copy of a Recording, so it can be passed out of the interrupt
+// handler without allocating.
+type capture struct {
+	n     int
+	pairs [MaxPairs]irtrx.TimePair
+}
+
+// Remote learns, stores and replays codes. It implements
+// irtrx.RxStateMachine; its receiver must be started with StartInverted.
+type Remote struct {
+	// Store holds the learned codes.
+	Store Store
+	// LearnTimeout is how long Learn waits for the remote.
+	LearnTimeout time.Duration
+	// Muter, if set, is muted while sending, so the board doesn't hear
+	// itself.
+	Muter Muter
+	// Settle is how long reception stays muted after sending.
+	Settle time.Duration
+	// Flusher is flushed once the remote goes quiet while learning, to get
+	// the last pair of the code, which the receiver only passes on at the
+	// next edge. Set it to the RxDevice; without it, codes are learned
+	// without their final mark.
+	Flusher irtrx.Flusher
+
+	tx       irtrx.Transmitter
+	rec      *irtrx.Recorder
+	captures chan capture
+	learning atomic.Bool
+	// when the last pair arrived, in Unix nanoseconds
+	last atomic.Int64
+	// decoded codes, so Send doesn't identify them every time
+	cache map[string]*Code
+}
+
+// NewRemote returns a Remote sending with tx and keeping codes in store.
+func NewRemote(tx irtrx.Transmitter, store Store) *Remote {
+	r := &Remote{
+		Store:        store,
+		LearnTimeout: 10 * time.Second,
+		Settle:       10 * time.Millisecond,
+		tx:           tx,
+		captures:     make(chan capture, 1),
+		cache:        make(map[string]*Code),
+	}
+	r.rec = irtrx.NewRecorder(MaxPairs, r.captured)
+	return r
+}
+
+// HandleTimePair implements irtrx.RxStateMachine. Pairs are only recorded
+// while learning.
+func (r *Remote) HandleTimePair(pair irtrx.TimePair) {
+	r.last.Store(time.Now().UnixNano())
+	if r.learning.Load() {
+		r.rec.HandleTimePair(pair)
+	}
+}
+
+// Flush implements irtrx.Flusher, ending the capture in progress while
+// learning. The RxDevice calls it from its own Flush.
+func (r *Remote) Flush() {
+	if r.learning.Load() {
+		r.rec.Flush()
+	}
+}
+
+// flush ends the capture in progress, through Flusher if there is one.
+func (r *Remote) flush() {
+	if r.Flusher != nil {
+		r.Flusher.Flush()
+		return
+	}
+	// keep the interrupt handler off the Recorder while flushing it
+	r.learning.Store(false)
+	r.rec.Flush()
+	r.learning.Store(true)
+}
+
+// captured is the Recorder's handler, called from the interrupt handler.
+func (r *Remote) captured(rec irtrx.Recording) {
+	var c capture
+	c.n = copy(c.pairs[:], rec.Pairs)
+	select {
+	case r.captures <- c:
+	default:
+	}
+}
+
+// Learn captures the next code received, identifies it, and saves it to
+// Store as name, replacing any code of that name.
+func (r *Remote) Learn(name string) (*Code, error) {
+	// drop anything captured before now
+	select {
+	case <-r.captures:
+	default:
+	}
+	start := time.Now()
+	r.learning.Store(true)
+	var c capture
+	for c.n == 0 {
+		select {
+		case c = <-r.captures:
+			continue
+		case <-time.After(50 * time.Millisecond):
+		}
+		// the final pair of a code is only seen at the next edge, so
+		// once the remote goes quiet, take what has been captured
+		last := time.Unix(0, r.last.Load())
+		if last.After(start) && time.Since(last) > 2*r.rec.Gap {
+			r.learning.Store(false)
+			r.rec.Flush()
+			r.learning.Store(true)
+		}
+		if time.Since(start) > r.LearnTimeout {
+			r.learning.Store(false)
+			return nil, ErrTimeout
+		}
+	}
+	r.learning.Store(false)
+
+	code := NewCode(name, irtrx.Recording{
+		Freq:  r.rec.Freq,
+		Pairs: append([]irtrx.TimePair(nil), c.pairs[:c.n]...),
+	})
+	if err := r.save(code); err != nil {
+		return nil, err
+	}
+	return code, nil
+}
+
+// Add saves rec as name, e.g. a code from a database or pasted from an
+// earlier Learn, and returns it identified.
+func (r *Remote) Add(name string, rec irtrx.Recording) (*Code, error) {
+	code := NewCode(name, rec)
+	if err := r.save(code); err != nil {
+		return nil, err
+	}
+	return code, nil
+}
+
+func (r *Remote) save(code *Code) error {
+	b, err := code.Recording.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if err := r.Store.Save(code.Name, b); err != nil {
+		return err
+	}
+	r.cache[code.Name] = code
+	return nil
+}
+
+// Code returns the code called name.
+func (r *Remote) Code(name string) (*Code, error) {
+	if code, ok := r.cache[name]; ok {
+		return code, nil
+	}
+	b, err := r.Store.Load(name)
+	if err != nil {
+		return nil, err
+	}
+	var rec irtrx.Recording
+	if err := rec.UnmarshalBinary(b); err != nil {
+		return nil, err
+	}
+	code := NewCode(name, rec)
+	r.cache[name] = code
+	return code, nil
+}
+
+// Send transmits the code called name.
+func (r *Remote) Send(name string) error {
+	code, err := r.Code(name)
+	if err != nil {
+		return err
+	}
+	if r.Muter != nil {
+		r.Muter.Mute()
+		defer r.Muter.Unmute(r.Settle)
+	}
+	r.tx.SendFrame(code.Marshaller())
+	return nil
+}
+
+// Delete removes the code called name.
+func (r *Remote) Delete(name string) error {
+	delete(r.cache, name)
+	return r.Store.Delete(name)
+}
+
+// Names returns the names of the stored codes, sorted.
+func (r *Remote) Names() ([]string, error) {
+	return r.Store.Names()
+}
+
+var (
+	_ irtrx.RxStateMachine = (*Remote)(nil)
+	_ irtrx.Flusher        = (*Remote)(nil)
+)